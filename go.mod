@@ -1,5 +1,10 @@
 module github.com/pzentenoe/graphql-client
 
-go 1.15
+go 1.21
 
-require github.com/pkg/errors v0.9.1
+require (
+	github.com/pkg/errors v0.9.1
+	golang.org/x/oauth2 v0.21.0
+)
+
+require github.com/google/go-cmp v0.6.0 // indirect
@@ -0,0 +1,59 @@
+package graphql
+
+import (
+	"bytes"
+	"encoding/json"
+	"time"
+)
+
+// ApolloTracing is the Apollo tracing extension of a response, as returned
+// by servers with tracing enabled.
+type ApolloTracing struct {
+	Version    int                    `json:"version"`
+	StartTime  time.Time              `json:"startTime"`
+	EndTime    time.Time              `json:"endTime"`
+	Duration   time.Duration          `json:"duration"`
+	Parsing    ApolloTracingPhase     `json:"parsing"`
+	Validation ApolloTracingPhase     `json:"validation"`
+	Execution  ApolloTracingExecution `json:"execution"`
+}
+
+// ApolloTracingPhase is the timing of a phase of the request. StartOffset
+// is relative to ApolloTracing.StartTime.
+type ApolloTracingPhase struct {
+	StartOffset time.Duration `json:"startOffset"`
+	Duration    time.Duration `json:"duration"`
+}
+
+// ApolloTracingExecution holds the timings of the resolvers.
+type ApolloTracingExecution struct {
+	Resolvers []ApolloResolverTiming `json:"resolvers"`
+}
+
+// ApolloResolverTiming is the timing of a single resolver. Path holds
+// field names and list indexes.
+type ApolloResolverTiming struct {
+	Path        []interface{} `json:"path"`
+	ParentType  string        `json:"parentType"`
+	FieldName   string        `json:"fieldName"`
+	ReturnType  string        `json:"returnType"`
+	StartOffset time.Duration `json:"startOffset"`
+	Duration    time.Duration `json:"duration"`
+}
+
+// parseApolloTracing returns the tracing extension of body, or nil if it
+// has none.
+func parseApolloTracing(body []byte) *ApolloTracing {
+	if !bytes.Contains(body, []byte(`"tracing"`)) {
+		return nil
+	}
+	var payload struct {
+		Extensions struct {
+			Tracing *ApolloTracing `json:"tracing"`
+		} `json:"extensions"`
+	}
+	if err := json.Unmarshal(body, &payload); err != nil {
+		return nil
+	}
+	return payload.Extensions.Tracing
+}
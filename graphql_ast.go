@@ -0,0 +1,301 @@
+package graphql
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+)
+
+// QueryDocument is the syntax tree of a query, for tools and hooks that
+// need to inspect it, e.g. to route requests by operation type.
+type QueryDocument struct {
+	Operations []*QueryOperation
+	Fragments  []*QueryFragment
+}
+
+// QueryOperation is an operation definition.
+type QueryOperation struct {
+	// Type is query, mutation or subscription.
+	Type string
+	// Name is empty for anonymous operations.
+	Name       string
+	Variables  []*QueryVariable
+	Directives []*QueryDirective
+	Selections []*QuerySelection
+	Location   Location
+}
+
+// QueryFragment is a fragment definition.
+type QueryFragment struct {
+	Name          string
+	TypeCondition string
+	Directives    []*QueryDirective
+	Selections    []*QuerySelection
+	Location      Location
+}
+
+// QueryVariable is a variable definition.
+type QueryVariable struct {
+	Name string
+	// Type is the type of the variable as written, e.g. "[ID!]!".
+	Type string
+	// DefaultValue is nil without a default value, or if it is null.
+	DefaultValue interface{}
+	Directives   []*QueryDirective
+	Location     Location
+}
+
+// SelectionKind is the kind of a QuerySelection.
+type SelectionKind int
+
+const (
+	// FieldSelection selects a field.
+	FieldSelection SelectionKind = iota
+	// FragmentSpreadSelection spreads a named fragment.
+	FragmentSpreadSelection
+	// InlineFragmentSelection is an inline fragment.
+	InlineFragmentSelection
+)
+
+// QuerySelection is a field, a fragment spread or an inline fragment.
+type QuerySelection struct {
+	Kind SelectionKind
+	// Name is the name of a field or of a spread fragment.
+	Name  string
+	Alias string
+	// TypeCondition is the type of an inline fragment, if any.
+	TypeCondition string
+	Arguments     []*QueryArgument
+	Directives    []*QueryDirective
+	Selections    []*QuerySelection
+	Location      Location
+}
+
+// QueryDirective is a directive, such as @include(if: $flag).
+type QueryDirective struct {
+	Name      string
+	Arguments []*QueryArgument
+	Location  Location
+}
+
+// QueryArgument is an argument of a field or directive.
+type QueryArgument struct {
+	Name string
+	// Value is a Variable, an EnumValue, a json.Number, a string, a bool,
+	// nil, or a []interface{} or map[string]interface{} of those.
+	Value    interface{}
+	Location Location
+}
+
+// ParseQuery parses query, an executable GraphQL document. It checks the
+// syntax only: use a Schema to validate the document.
+func ParseQuery(query string) (*QueryDocument, error) {
+	doc, err := parseQuery(query)
+	if err != nil {
+		return nil, err
+	}
+	c := astConverter{src: query}
+	result := &QueryDocument{}
+	for _, operation := range doc.operations {
+		result.Operations = append(result.Operations, &QueryOperation{
+			Type:       operation.typ,
+			Name:       operation.name,
+			Variables:  c.variables(operation.variables),
+			Directives: c.directives(operation.directives),
+			Selections: c.selections(operation.selections),
+			Location:   locate(query, operation.pos),
+		})
+	}
+	for _, fragment := range doc.fragments {
+		result.Fragments = append(result.Fragments, &QueryFragment{
+			Name:          fragment.name,
+			TypeCondition: fragment.typeCondition,
+			Directives:    c.directives(fragment.directives),
+			Selections:    c.selections(fragment.selections),
+			Location:      locate(query, fragment.pos),
+		})
+	}
+	return result, nil
+}
+
+// Parse parses the query of req.
+func (req *GraphRequest) Parse() (*QueryDocument, error) {
+	return ParseQuery(req.query)
+}
+
+// Operation returns the operation named name, or the only operation of the
+// document if name is empty.
+func (d *QueryDocument) Operation(name string) (*QueryOperation, error) {
+	if name == "" {
+		if len(d.Operations) != 1 {
+			return nil, fmt.Errorf("graphql: document has %d operations, expected a name", len(d.Operations))
+		}
+		return d.Operations[0], nil
+	}
+	for _, operation := range d.Operations {
+		if operation.Name == name {
+			return operation, nil
+		}
+	}
+	return nil, fmt.Errorf("graphql: unknown operation %q", name)
+}
+
+// Fragment returns the fragment named name, or nil.
+func (d *QueryDocument) Fragment(name string) *QueryFragment {
+	for _, fragment := range d.Fragments {
+		if fragment.Name == name {
+			return fragment
+		}
+	}
+	return nil
+}
+
+// ReferencedVariables returns the names of the variables operation uses,
+// directly or through the fragments it spreads, in order of first use.
+// Fields of object values are visited in alphabetical order.
+func (d *QueryDocument) ReferencedVariables(operation *QueryOperation) []string {
+	var names []string
+	seen := make(map[string]bool)
+	spread := make(map[string]bool)
+	var walkValue func(value interface{})
+	walkValue = func(value interface{}) {
+		switch value := value.(type) {
+		case Variable:
+			if !seen[string(value)] {
+				seen[string(value)] = true
+				names = append(names, string(value))
+			}
+		case []interface{}:
+			for _, item := range value {
+				walkValue(item)
+			}
+		case map[string]interface{}:
+			keys := make([]string, 0, len(value))
+			for key := range value {
+				keys = append(keys, key)
+			}
+			sort.Strings(keys)
+			for _, key := range keys {
+				walkValue(value[key])
+			}
+		}
+	}
+	walkDirectives := func(directives []*QueryDirective) {
+		for _, directive := range directives {
+			for _, argument := range directive.Arguments {
+				walkValue(argument.Value)
+			}
+		}
+	}
+	var walkSelections func(selections []*QuerySelection)
+	walkSelections = func(selections []*QuerySelection) {
+		for _, selection := range selections {
+			for _, argument := range selection.Arguments {
+				walkValue(argument.Value)
+			}
+			walkDirectives(selection.Directives)
+			walkSelections(selection.Selections)
+			if selection.Kind == FragmentSpreadSelection && !spread[selection.Name] {
+				spread[selection.Name] = true
+				if fragment := d.Fragment(selection.Name); fragment != nil {
+					walkDirectives(fragment.Directives)
+					walkSelections(fragment.Selections)
+				}
+			}
+		}
+	}
+	walkDirectives(operation.Directives)
+	walkSelections(operation.Selections)
+	return names
+}
+
+// astConverter converts the parser's tree to the exported one.
+type astConverter struct {
+	src string
+}
+
+func (c astConverter) variables(variables []*variableNode) []*QueryVariable {
+	var result []*QueryVariable
+	for _, variable := range variables {
+		converted := &QueryVariable{
+			Name:       variable.name,
+			Type:       variable.typ.String(),
+			Directives: c.directives(variable.directives),
+			Location:   locate(c.src, variable.pos),
+		}
+		if variable.defaultValue != nil {
+			converted.DefaultValue = c.value(variable.defaultValue)
+		}
+		result = append(result, converted)
+	}
+	return result
+}
+
+func (c astConverter) selections(selections []*selectionNode) []*QuerySelection {
+	var result []*QuerySelection
+	for _, selection := range selections {
+		result = append(result, &QuerySelection{
+			Kind:          SelectionKind(selection.kind),
+			Name:          selection.name,
+			Alias:         selection.alias,
+			TypeCondition: selection.typeCondition,
+			Arguments:     c.arguments(selection.arguments),
+			Directives:    c.directives(selection.directives),
+			Selections:    c.selections(selection.selections),
+			Location:      locate(c.src, selection.pos),
+		})
+	}
+	return result
+}
+
+func (c astConverter) directives(directives []*directiveNode) []*QueryDirective {
+	var result []*QueryDirective
+	for _, directive := range directives {
+		result = append(result, &QueryDirective{
+			Name:      directive.name,
+			Arguments: c.arguments(directive.arguments),
+			Location:  locate(c.src, directive.pos),
+		})
+	}
+	return result
+}
+
+func (c astConverter) arguments(arguments []*argumentNode) []*QueryArgument {
+	var result []*QueryArgument
+	for _, argument := range arguments {
+		result = append(result, &QueryArgument{
+			Name:     argument.name,
+			Value:    c.value(argument.value),
+			Location: locate(c.src, argument.pos),
+		})
+	}
+	return result
+}
+
+func (c astConverter) value(value *valueNode) interface{} {
+	switch value.kind {
+	case valueVariable:
+		return Variable(value.text)
+	case valueInt, valueFloat:
+		return json.Number(value.text)
+	case valueString:
+		return value.text
+	case valueBoolean:
+		return value.text == "true"
+	case valueEnum:
+		return EnumValue(value.text)
+	case valueList:
+		list := make([]interface{}, len(value.list))
+		for i, item := range value.list {
+			list[i] = c.value(item)
+		}
+		return list
+	case valueObject:
+		object := make(map[string]interface{}, len(value.fields))
+		for _, field := range value.fields {
+			object[field.name] = c.value(field.value)
+		}
+		return object
+	}
+	return nil
+}
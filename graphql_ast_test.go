@@ -0,0 +1,319 @@
+package graphql
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// clearLocations zeroes the locations of the nodes of doc, for trees to be
+// compared without them.
+func clearLocations(doc *QueryDocument) {
+	var directives func([]*QueryDirective)
+	arguments := func(arguments []*QueryArgument) {
+		for _, argument := range arguments {
+			argument.Location = Location{}
+		}
+	}
+	directives = func(ds []*QueryDirective) {
+		for _, directive := range ds {
+			directive.Location = Location{}
+			arguments(directive.Arguments)
+		}
+	}
+	var selections func([]*QuerySelection)
+	selections = func(ss []*QuerySelection) {
+		for _, selection := range ss {
+			selection.Location = Location{}
+			arguments(selection.Arguments)
+			directives(selection.Directives)
+			selections(selection.Selections)
+		}
+	}
+	for _, operation := range doc.Operations {
+		operation.Location = Location{}
+		for _, variable := range operation.Variables {
+			variable.Location = Location{}
+			directives(variable.Directives)
+		}
+		directives(operation.Directives)
+		selections(operation.Selections)
+	}
+	for _, fragment := range doc.Fragments {
+		fragment.Location = Location{}
+		directives(fragment.Directives)
+		selections(fragment.Selections)
+	}
+}
+
+func fieldSelection(name string, selections ...*QuerySelection) *QuerySelection {
+	return &QuerySelection{Kind: FieldSelection, Name: name, Selections: selections}
+}
+
+func TestParseQuery(t *testing.T) {
+	tests := []struct {
+		name  string
+		query string
+		want  *QueryDocument
+	}{
+		{
+			name:  "shorthand query",
+			query: `{ viewer { login } }`,
+			want: &QueryDocument{Operations: []*QueryOperation{{
+				Type:       "query",
+				Selections: []*QuerySelection{fieldSelection("viewer", fieldSelection("login"))},
+			}}},
+		},
+		{
+			name:  "variables and directives",
+			query: `query GetUser($id: ID!, $first: Int = 10, $tags: [String!] = ["a"], $after: String = null) @cached(ttl: 60) { user(id: $id) { friends(first: $first, tags: $tags, after: $after) { id } } }`,
+			want: &QueryDocument{Operations: []*QueryOperation{{
+				Type: "query",
+				Name: "GetUser",
+				Variables: []*QueryVariable{
+					{Name: "id", Type: "ID!"},
+					{Name: "first", Type: "Int", DefaultValue: json.Number("10")},
+					{Name: "tags", Type: "[String!]", DefaultValue: []interface{}{"a"}},
+					{Name: "after", Type: "String"},
+				},
+				Directives: []*QueryDirective{{Name: "cached", Arguments: []*QueryArgument{{Name: "ttl", Value: json.Number("60")}}}},
+				Selections: []*QuerySelection{{
+					Kind:      FieldSelection,
+					Name:      "user",
+					Arguments: []*QueryArgument{{Name: "id", Value: Variable("id")}},
+					Selections: []*QuerySelection{{
+						Kind: FieldSelection,
+						Name: "friends",
+						Arguments: []*QueryArgument{
+							{Name: "first", Value: Variable("first")},
+							{Name: "tags", Value: Variable("tags")},
+							{Name: "after", Value: Variable("after")},
+						},
+						Selections: []*QuerySelection{fieldSelection("id")},
+					}},
+				}},
+			}}},
+		},
+		{
+			name:  "aliases and fragments",
+			query: `{ me: viewer { ...UserFields ... on Admin { level } ... @include(if: true) { email } } } fragment UserFields on User @private { id }`,
+			want: &QueryDocument{
+				Operations: []*QueryOperation{{
+					Type: "query",
+					Selections: []*QuerySelection{{
+						Kind:  FieldSelection,
+						Name:  "viewer",
+						Alias: "me",
+						Selections: []*QuerySelection{
+							{Kind: FragmentSpreadSelection, Name: "UserFields"},
+							{Kind: InlineFragmentSelection, TypeCondition: "Admin", Selections: []*QuerySelection{fieldSelection("level")}},
+							{
+								Kind:       InlineFragmentSelection,
+								Directives: []*QueryDirective{{Name: "include", Arguments: []*QueryArgument{{Name: "if", Value: true}}}},
+								Selections: []*QuerySelection{fieldSelection("email")},
+							},
+						},
+					}},
+				}},
+				Fragments: []*QueryFragment{{
+					Name:          "UserFields",
+					TypeCondition: "User",
+					Directives:    []*QueryDirective{{Name: "private"}},
+					Selections:    []*QuerySelection{fieldSelection("id")},
+				}},
+			},
+		},
+		{
+			name:  "values",
+			query: `{ search(filter: {role: ADMIN, name: "a\nb", scores: [1, 2.5e3, null, false], raw: """block"""}) { id } }`,
+			want: &QueryDocument{Operations: []*QueryOperation{{
+				Type: "query",
+				Selections: []*QuerySelection{{
+					Kind: FieldSelection,
+					Name: "search",
+					Arguments: []*QueryArgument{{Name: "filter", Value: map[string]interface{}{
+						"role":   EnumValue("ADMIN"),
+						"name":   "a\nb",
+						"scores": []interface{}{json.Number("1"), json.Number("2.5e3"), nil, false},
+						"raw":    "block",
+					}}},
+					Selections: []*QuerySelection{fieldSelection("id")},
+				}},
+			}}},
+		},
+		{
+			name:  "several operations",
+			query: `mutation Rename { rename(id: 1, name: "a") { id } } subscription OnRename { renamed { id } }`,
+			want: &QueryDocument{Operations: []*QueryOperation{
+				{
+					Type: "mutation",
+					Name: "Rename",
+					Selections: []*QuerySelection{{
+						Kind: FieldSelection,
+						Name: "rename",
+						Arguments: []*QueryArgument{
+							{Name: "id", Value: json.Number("1")},
+							{Name: "name", Value: "a"},
+						},
+						Selections: []*QuerySelection{fieldSelection("id")},
+					}},
+				},
+				{Type: "subscription", Name: "OnRename", Selections: []*QuerySelection{fieldSelection("renamed", fieldSelection("id"))}},
+			}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			doc, err := ParseQuery(tt.query)
+			if err != nil {
+				t.Fatal(err)
+			}
+			clearLocations(doc)
+			if !reflect.DeepEqual(doc, tt.want) {
+				got, _ := json.MarshalIndent(doc, "", "  ")
+				want, _ := json.MarshalIndent(tt.want, "", "  ")
+				t.Fatalf("got\n%s\nwant\n%s", got, want)
+			}
+		})
+	}
+}
+
+func TestParseQueryErrors(t *testing.T) {
+	tests := []struct {
+		name  string
+		query string
+		want  string
+	}{
+		{name: "unclosed selection set", query: "{ user { id }", want: "syntax error at 1:14"},
+		{name: "missing selection set", query: "query Q", want: "syntax error at 1:8"},
+		{name: "bad variable", query: "query ($id ID) { user }", want: "syntax error at 1:12"},
+		{name: "unterminated string", query: "{ user(name: \"ann) { id } }", want: "syntax error"},
+		{name: "position on later lines", query: "{\n  user(id: 1) {\n    id ]\n  }\n}", want: "syntax error at 3:8"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ParseQuery(tt.query)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Fatalf("got %v, want an error containing %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseQueryLocations(t *testing.T) {
+	doc, err := ParseQuery("query Q($id: ID!) {\n  user(id: $id) {\n    ...F\n  }\n}\n\nfragment F on User { id }")
+	if err != nil {
+		t.Fatal(err)
+	}
+	operation := doc.Operations[0]
+	user := operation.Selections[0]
+	tests := []struct {
+		name string
+		got  Location
+		want Location
+	}{
+		{"operation", operation.Location, Location{Line: 1, Column: 1}},
+		{"variable", operation.Variables[0].Location, Location{Line: 1, Column: 9}},
+		{"field", user.Location, Location{Line: 2, Column: 3}},
+		{"argument", user.Arguments[0].Location, Location{Line: 2, Column: 8}},
+		{"spread", user.Selections[0].Location, Location{Line: 3, Column: 5}},
+		{"fragment", doc.Fragments[0].Location, Location{Line: 7, Column: 1}},
+	}
+	for _, tt := range tests {
+		if tt.got != tt.want {
+			t.Errorf("%s is at %+v, want %+v", tt.name, tt.got, tt.want)
+		}
+	}
+}
+
+func TestQueryDocumentOperation(t *testing.T) {
+	single, err := ParseQuery(`query A { a }`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	several, err := ParseQuery(`query A { a } query B { b }`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name          string
+		doc           *QueryDocument
+		operationName string
+		want          string
+		wantErr       string
+	}{
+		{name: "only operation", doc: single, want: "A"},
+		{name: "named operation", doc: several, operationName: "B", want: "B"},
+		{name: "no name", doc: several, wantErr: "document has 2 operations, expected a name"},
+		{name: "unknown name", doc: single, operationName: "C", wantErr: `unknown operation "C"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			operation, err := tt.doc.Operation(tt.operationName)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("got %v, want an error containing %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if operation.Name != tt.want {
+				t.Fatalf("got operation %q, want %q", operation.Name, tt.want)
+			}
+		})
+	}
+	if fragment := single.Fragment("F"); fragment != nil {
+		t.Fatalf("got fragment %+v from a document without fragments", fragment)
+	}
+}
+
+func TestReferencedVariables(t *testing.T) {
+	tests := []struct {
+		name  string
+		query string
+		want  []string
+	}{
+		{
+			name:  "arguments in order",
+			query: `query($b: Int, $a: Int, $unused: Int) { x(n: $b) { y(n: $a) z(n: $b) } }`,
+			want:  []string{"b", "a"},
+		},
+		{
+			name:  "directives",
+			query: `query($full: Boolean!, $skip: Boolean!) @live(if: $skip) { x @include(if: $full) }`,
+			want:  []string{"skip", "full"},
+		},
+		{
+			name:  "lists and objects",
+			query: `query($z: Int, $a: Int, $l: Int) { x(filter: {z: $z, a: $a}, ids: [1, $l]) }`,
+			want:  []string{"a", "z", "l"},
+		},
+		{
+			name:  "fragments",
+			query: `query($a: Int, $b: Int) { ...F ...F x { ...G } } fragment F on Query { f(n: $a) } fragment G on X { ...F g(n: $b) }`,
+			want:  []string{"a", "b"},
+		},
+		{
+			name:  "unknown fragment",
+			query: `{ ...Missing }`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			doc, err := NewGraphqlRequest(tt.query).Parse()
+			if err != nil {
+				t.Fatal(err)
+			}
+			operation, err := doc.Operation("")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := doc.ReferencedVariables(operation); !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
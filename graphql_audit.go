@@ -0,0 +1,119 @@
+package graphql
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// AuditOutcome is the outcome of an audited operation.
+type AuditOutcome string
+
+const (
+	// AuditSuccess means the operation returned data without errors.
+	AuditSuccess AuditOutcome = "success"
+	// AuditGraphQLErrors means the response contained GraphQL errors.
+	AuditGraphQLErrors AuditOutcome = "graphql_errors"
+	// AuditFailure means Run returned an error.
+	AuditFailure AuditOutcome = "failure"
+)
+
+// AuditRecord is the record of an executed operation passed to an
+// AuditSink. Variables are only recorded as a hash.
+type AuditRecord struct {
+	Time          time.Time
+	OperationName string
+	OperationType string
+	Endpoint      string
+	RequestID     string
+	Label         string
+	// VariablesHash is the hex encoded SHA-256 of the JSON encoded
+	// variables, or empty if the operation had none.
+	VariablesHash string
+	// Caller is the identity set with ContextWithCaller.
+	Caller     string
+	Outcome    AuditOutcome
+	StatusCode int
+	// Error is the message of the error Run returned, if any.
+	Error string
+}
+
+// AuditSink receives a record of every operation run by the Client, for
+// compliance logging separate from debug logging.
+type AuditSink interface {
+	Audit(ctx context.Context, record AuditRecord)
+}
+
+// AuditSinkFunc is an adapter to use an ordinary function as an AuditSink.
+type AuditSinkFunc func(ctx context.Context, record AuditRecord)
+
+// Audit calls f(ctx, record).
+func (f AuditSinkFunc) Audit(ctx context.Context, record AuditRecord) {
+	f(ctx, record)
+}
+
+// WithAuditSink sends a record of every operation to sink.
+func WithAuditSink(sink AuditSink) ClientOption {
+	return func(client *Client) {
+		client.audit = sink
+	}
+}
+
+type callerKey struct{}
+
+// ContextWithCaller returns a copy of ctx carrying the identity of the
+// caller recorded in AuditRecord.Caller.
+func ContextWithCaller(ctx context.Context, caller string) context.Context {
+	return context.WithValue(ctx, callerKey{}, caller)
+}
+
+// CallerFromContext returns the caller identity carried by ctx.
+func CallerFromContext(ctx context.Context) string {
+	caller, _ := ctx.Value(callerKey{}).(string)
+	return caller
+}
+
+func (c *Client) auditOperation(ctx context.Context, op *operation, graphResponse *GraphResponse, err error) {
+	if c.audit == nil {
+		return
+	}
+	info := c.operationInfo(op)
+	record := AuditRecord{
+		Time:          op.start,
+		OperationName: info.Name,
+		OperationType: info.Type,
+		Endpoint:      info.Endpoint,
+		RequestID:     info.RequestID,
+		Label:         info.Label,
+		VariablesHash: variablesHash(op.req.vars),
+		Caller:        CallerFromContext(ctx),
+		Outcome:       AuditSuccess,
+		StatusCode:    op.statusCode,
+	}
+	var graphErrs GraphErrors
+	switch {
+	case errors.As(err, &graphErrs):
+		record.Outcome, record.Error = AuditGraphQLErrors, err.Error()
+	case err != nil:
+		record.Outcome, record.Error = AuditFailure, err.Error()
+	case graphResponse != nil && len(graphResponse.Errors) > 0:
+		record.Outcome = AuditGraphQLErrors
+	}
+	c.audit.Audit(ctx, record)
+}
+
+func variablesHash(vars map[string]interface{}) string {
+	if len(vars) == 0 {
+		return ""
+	}
+	encoded, err := json.Marshal(vars)
+	if err != nil {
+		return ""
+	}
+	sum := sha256.Sum256(encoded)
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,152 @@
+package graphql
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+
+	"github.com/pkg/errors"
+	"golang.org/x/oauth2"
+)
+
+// AuthProvider adds credentials to outgoing requests.
+type AuthProvider interface {
+	Apply(ctx context.Context, r *http.Request) error
+}
+
+// AuthProviderFunc is an adapter to use an ordinary function as an
+// AuthProvider.
+type AuthProviderFunc func(ctx context.Context, r *http.Request) error
+
+// Apply calls f(ctx, r).
+func (f AuthProviderFunc) Apply(ctx context.Context, r *http.Request) error {
+	return f(ctx, r)
+}
+
+// WithAuth authenticates every request with provider. It replaces any
+// credentials set by a previous auth option.
+func WithAuth(provider AuthProvider) ClientOption {
+	return func(client *Client) {
+		client.auth = provider
+	}
+}
+
+// WithTokenSource authenticates every request with an OAuth2 token taken
+// from ts. Tokens are cached and transparently refreshed once they expire.
+//
+//	NewClient(url, WithTokenSource(config.TokenSource(ctx, token)))
+func WithTokenSource(ts oauth2.TokenSource) ClientOption {
+	return WithAuth(TokenSourceAuth(ts))
+}
+
+// WithBearerToken authenticates every request with a static bearer token.
+func WithBearerToken(token string) ClientOption {
+	return WithAuth(BearerAuth(token))
+}
+
+// WithAuthorizationHeader sets the Authorization header of every request
+// to value, e.g. "Token abc123".
+func WithAuthorizationHeader(value string) ClientOption {
+	return WithAuth(APIKeyAuth("Authorization", value))
+}
+
+// BasicAuth authenticates requests with HTTP basic authentication.
+func BasicAuth(username, password string) AuthProvider {
+	return AuthProviderFunc(func(ctx context.Context, r *http.Request) error {
+		r.SetBasicAuth(username, password)
+		return nil
+	})
+}
+
+// BearerAuth authenticates requests with a static bearer token.
+func BearerAuth(token string) AuthProvider {
+	return APIKeyAuth("Authorization", "Bearer "+token)
+}
+
+// APIKeyAuth authenticates requests by setting header to key.
+func APIKeyAuth(header, key string) AuthProvider {
+	return AuthProviderFunc(func(ctx context.Context, r *http.Request) error {
+		r.Header.Set(header, key)
+		return nil
+	})
+}
+
+// TokenSourceAuth authenticates requests with OAuth2 tokens taken from ts.
+// Tokens are cached and transparently refreshed once they expire.
+func TokenSourceAuth(ts oauth2.TokenSource) AuthProvider {
+	ts = oauth2.ReuseTokenSource(nil, ts)
+	return AuthProviderFunc(func(ctx context.Context, r *http.Request) error {
+		token, err := ts.Token()
+		if err != nil {
+			return errors.Wrap(err, "get token")
+		}
+		token.SetAuthHeader(r)
+		return nil
+	})
+}
+
+// ComposeAuth applies every provider in order, e.g. to send an API key
+// along with a bearer token. Responses are passed on to the providers that
+// are ResponseObservers.
+func ComposeAuth(providers ...AuthProvider) AuthProvider {
+	return composedAuth(providers)
+}
+
+type composedAuth []AuthProvider
+
+func (a composedAuth) Apply(ctx context.Context, r *http.Request) error {
+	for _, provider := range a {
+		if err := provider.Apply(ctx, r); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (a composedAuth) ObserveResponse(r *http.Request, res *http.Response, graphErrors []GraphErr) {
+	for _, provider := range a {
+		if observer, ok := provider.(ResponseObserver); ok {
+			observer.ObserveResponse(r, res, graphErrors)
+		}
+	}
+}
+
+// ResponseObserver is implemented by AuthProviders that need to see the
+// outcome of the requests they authenticated, e.g. to drop rejected
+// credentials.
+type ResponseObserver interface {
+	ObserveResponse(r *http.Request, res *http.Response, graphErrors []GraphErr)
+}
+
+// authProvider returns the credentials of req, or the client-level ones if
+// req has none.
+func (c *Client) authProvider(req *GraphRequest) AuthProvider {
+	if req.auth != nil {
+		return req.auth
+	}
+	return c.auth
+}
+
+// authorize adds the credentials of req to r.
+func (c *Client) authorize(ctx context.Context, req *GraphRequest, r *http.Request) error {
+	provider := c.authProvider(req)
+	if provider == nil {
+		return nil
+	}
+	return provider.Apply(ctx, r)
+}
+
+// observeResponse passes the response to r to the auth provider of req if
+// it is a ResponseObserver.
+func (c *Client) observeResponse(req *GraphRequest, r *http.Request, res *http.Response, body []byte) {
+	observer, ok := c.authProvider(req).(ResponseObserver)
+	if !ok {
+		return
+	}
+	var payload struct {
+		Errors []GraphErr `json:"errors"`
+	}
+	// the body may not be a GraphQL response at all, e.g. on a 401
+	_ = json.Unmarshal(body, &payload)
+	observer.ObserveResponse(r, res, payload.Errors)
+}
@@ -0,0 +1,116 @@
+package graphql
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"golang.org/x/oauth2"
+	"golang.org/x/oauth2/jwt"
+)
+
+const (
+	googleTokenURL          = "https://oauth2.googleapis.com/token"
+	googleMetadataIdentity  = "http://metadata.google.internal/computeMetadata/v1/instance/service-accounts/default/identity"
+	googleIDTokenRefreshGap = time.Minute
+)
+
+// GoogleIDTokenSource returns a TokenSource of Google-signed ID tokens for
+// audience, as expected by endpoints behind Identity-Aware Proxy or Cloud
+// Run. Tokens are signed with the service account key in credentialsJSON,
+// or fetched from the metadata server when credentialsJSON is nil. Pass
+// the source to WithTokenSource so tokens are refreshed before they expire.
+//
+//	ts, err := GoogleIDTokenSource(ctx, "https://my-service.run.app", nil)
+//	client := NewClient(url, WithTokenSource(ts))
+func GoogleIDTokenSource(ctx context.Context, audience string, credentialsJSON []byte) (oauth2.TokenSource, error) {
+	if credentialsJSON == nil {
+		return &metadataIDTokenSource{ctx: ctx, audience: audience}, nil
+	}
+	var credentials struct {
+		Type         string `json:"type"`
+		ClientEmail  string `json:"client_email"`
+		PrivateKey   string `json:"private_key"`
+		PrivateKeyID string `json:"private_key_id"`
+		TokenURI     string `json:"token_uri"`
+	}
+	if err := json.Unmarshal(credentialsJSON, &credentials); err != nil {
+		return nil, errors.Wrap(err, "decode credentials")
+	}
+	if credentials.Type != "service_account" {
+		return nil, fmt.Errorf("graphql: unsupported credentials type %q", credentials.Type)
+	}
+	config := &jwt.Config{
+		Email:         credentials.ClientEmail,
+		PrivateKey:    []byte(credentials.PrivateKey),
+		PrivateKeyID:  credentials.PrivateKeyID,
+		TokenURL:      credentials.TokenURI,
+		PrivateClaims: map[string]interface{}{"target_audience": audience},
+		UseIDToken:    true,
+	}
+	if config.TokenURL == "" {
+		config.TokenURL = googleTokenURL
+	}
+	return earlyExpiryTokenSource{source: config.TokenSource(ctx), margin: googleIDTokenRefreshGap}, nil
+}
+
+// metadataIDTokenSource fetches ID tokens from the GCE metadata server.
+type metadataIDTokenSource struct {
+	ctx      context.Context
+	audience string
+}
+
+func (s *metadataIDTokenSource) Token() (*oauth2.Token, error) {
+	endpoint := googleMetadataIdentity + "?format=full&audience=" + url.QueryEscape(s.audience)
+	r, err := http.NewRequest(http.MethodGet, endpoint, nil)
+	if err != nil {
+		return nil, err
+	}
+	r.Header.Set("Metadata-Flavor", "Google")
+	res, err := http.DefaultClient.Do(r.WithContext(s.ctx))
+	if err != nil {
+		return nil, errors.Wrap(err, "fetch identity token")
+	}
+	defer res.Body.Close()
+	body, err := ioutil.ReadAll(res.Body)
+	if err != nil {
+		return nil, errors.Wrap(err, "reading identity token")
+	}
+	if res.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("graphql: metadata server returned a non-200 status code: %v", res.StatusCode)
+	}
+	idToken := strings.TrimSpace(string(body))
+	expiry, err := jwtExpiry(idToken)
+	if err != nil {
+		return nil, err
+	}
+	return &oauth2.Token{
+		AccessToken: idToken,
+		TokenType:   "Bearer",
+		Expiry:      expiry.Add(-googleIDTokenRefreshGap),
+	}, nil
+}
+
+// earlyExpiryTokenSource makes tokens expire margin before they really do,
+// so they are refreshed ahead of time.
+type earlyExpiryTokenSource struct {
+	source oauth2.TokenSource
+	margin time.Duration
+}
+
+func (s earlyExpiryTokenSource) Token() (*oauth2.Token, error) {
+	token, err := s.source.Token()
+	if err != nil {
+		return nil, err
+	}
+	if !token.Expiry.IsZero() {
+		token.Expiry = token.Expiry.Add(-s.margin)
+	}
+	return token, nil
+}
@@ -0,0 +1,85 @@
+package graphql
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// TokenFetcher returns a fresh JWT.
+type TokenFetcher func(ctx context.Context) (string, error)
+
+// JWTAuth returns an AuthProvider that sends the JWT returned by fetch as a
+// bearer token. The token is fetched again margin before the time of its
+// exp claim, so in-flight requests never carry an expired token, or as
+// soon as the server answers with a 401.
+func JWTAuth(fetch TokenFetcher, margin time.Duration) AuthProvider {
+	return &jwtAuth{fetch: fetch, margin: margin}
+}
+
+type jwtAuth struct {
+	fetch  TokenFetcher
+	margin time.Duration
+
+	mu     sync.Mutex
+	token  string
+	expiry time.Time
+}
+
+func (a *jwtAuth) Apply(ctx context.Context, r *http.Request) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.token == "" || !time.Now().Before(a.expiry.Add(-a.margin)) {
+		token, err := a.fetch(ctx)
+		if err != nil {
+			return errors.Wrap(err, "fetch token")
+		}
+		expiry, err := jwtExpiry(token)
+		if err != nil {
+			return err
+		}
+		a.token, a.expiry = token, expiry
+	}
+	r.Header.Set("Authorization", "Bearer "+a.token)
+	return nil
+}
+
+func (a *jwtAuth) ObserveResponse(r *http.Request, res *http.Response, graphErrors []GraphErr) {
+	if res.StatusCode != http.StatusUnauthorized {
+		return
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if r.Header.Get("Authorization") == "Bearer "+a.token {
+		a.token = ""
+	}
+}
+
+// jwtExpiry returns the time of the exp claim of a JWT, without verifying
+// its signature.
+func jwtExpiry(token string) (time.Time, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return time.Time{}, errors.New("graphql: malformed JWT")
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(parts[1], "="))
+	if err != nil {
+		return time.Time{}, errors.Wrap(err, "decode JWT payload")
+	}
+	var claims struct {
+		Exp int64 `json:"exp"`
+	}
+	if err := json.Unmarshal(payload, &claims); err != nil {
+		return time.Time{}, errors.Wrap(err, "decode JWT claims")
+	}
+	if claims.Exp == 0 {
+		return time.Time{}, errors.New("graphql: JWT has no exp claim")
+	}
+	return time.Unix(claims.Exp, 0), nil
+}
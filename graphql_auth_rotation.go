@@ -0,0 +1,94 @@
+package graphql
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sync"
+
+	"github.com/pkg/errors"
+)
+
+// ErrNoAPIKeys is returned when every key of an API key rotation has been
+// disabled.
+var ErrNoAPIKeys = errors.New("graphql: all API keys have been disabled")
+
+// APIKeyRotation configures RotateAPIKeys.
+type APIKeyRotation struct {
+	// Header is the request header the key is sent in.
+	Header string
+	// Keys are used in order, moving to the next one when the current
+	// key is rejected.
+	Keys []string
+	// QuotaCodes are the extensions.code values of GraphQL errors that
+	// mean the key's quota is exhausted. 401 and 403 responses always
+	// disable the key.
+	QuotaCodes []string
+	// OnKeyDisabled, if set, is called with every key that gets disabled
+	// and the reason why.
+	OnKeyDisabled func(key, reason string)
+}
+
+// RotateAPIKeys returns an AuthProvider that sends one of several API keys
+// and rotates to the next one when the server rejects the current key.
+func RotateAPIKeys(config APIKeyRotation) AuthProvider {
+	return &apiKeyRotator{
+		config:   config,
+		disabled: make(map[string]bool),
+	}
+}
+
+type apiKeyRotator struct {
+	config APIKeyRotation
+
+	mu       sync.Mutex
+	current  int
+	disabled map[string]bool
+}
+
+func (a *apiKeyRotator) Apply(ctx context.Context, r *http.Request) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	for ; a.current < len(a.config.Keys); a.current++ {
+		key := a.config.Keys[a.current]
+		if !a.disabled[key] {
+			r.Header.Set(a.config.Header, key)
+			return nil
+		}
+	}
+	return ErrNoAPIKeys
+}
+
+func (a *apiKeyRotator) ObserveResponse(r *http.Request, res *http.Response, graphErrors []GraphErr) {
+	reason := a.rejection(res, graphErrors)
+	if reason == "" {
+		return
+	}
+	key := r.Header.Get(a.config.Header)
+	a.mu.Lock()
+	if a.disabled[key] {
+		a.mu.Unlock()
+		return
+	}
+	a.disabled[key] = true
+	a.mu.Unlock()
+	if a.config.OnKeyDisabled != nil {
+		a.config.OnKeyDisabled(key, reason)
+	}
+}
+
+// rejection returns why the response rejected the key, or an empty string.
+func (a *apiKeyRotator) rejection(res *http.Response, graphErrors []GraphErr) string {
+	if res.StatusCode == http.StatusUnauthorized || res.StatusCode == http.StatusForbidden {
+		return fmt.Sprintf("server returned status code %d", res.StatusCode)
+	}
+	for i := range graphErrors {
+		code, _ := graphErrors[i].ErrorExtensions["code"].(string)
+		for _, quotaCode := range a.config.QuotaCodes {
+			if code != "" && code == quotaCode {
+				return fmt.Sprintf("server returned error code %s", code)
+			}
+		}
+	}
+	return ""
+}
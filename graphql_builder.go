@@ -0,0 +1,529 @@
+package graphql
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// QueryBuilder builds an operation document programmatically, as an
+// alternative to assembling query strings by hand.
+//
+//	query := NewQueryBuilder("GetUser").
+//		Var("id", "ID!").
+//		Select(NewField("user").Arg("id", Variable("id")).Select(
+//			NewField("name"),
+//			NewField("avatar").Alias("thumbnail").Arg("size", 64),
+//		))
+//	req, err := query.Request()
+type QueryBuilder struct {
+	operationType string
+	name          string
+	variables     []variableDefinition
+	selections    []Selector
+	// flags are the values of the variables declared with Flag.
+	flags map[string]bool
+}
+
+type variableDefinition struct {
+	name         string
+	typ          string
+	defaultValue interface{}
+	hasDefault   bool
+}
+
+// NewQueryBuilder starts a query named name. The name may be empty.
+func NewQueryBuilder(name string) *QueryBuilder {
+	return &QueryBuilder{operationType: "query", name: name}
+}
+
+// NewMutationBuilder starts a mutation named name. The name may be empty.
+func NewMutationBuilder(name string) *QueryBuilder {
+	return &QueryBuilder{operationType: "mutation", name: name}
+}
+
+// NewSubscriptionBuilder starts a subscription named name. The name may
+// be empty.
+func NewSubscriptionBuilder(name string) *QueryBuilder {
+	return &QueryBuilder{operationType: "subscription", name: name}
+}
+
+// Var declares the variable name of type typ, e.g. "ID!" or "[String!]".
+func (b *QueryBuilder) Var(name, typ string) *QueryBuilder {
+	b.variables = append(b.variables, variableDefinition{name: name, typ: typ})
+	return b
+}
+
+// VarDefault declares the variable name of type typ with a default value.
+func (b *QueryBuilder) VarDefault(name, typ string, defaultValue interface{}) *QueryBuilder {
+	b.variables = append(b.variables, variableDefinition{
+		name: name, typ: typ, defaultValue: defaultValue, hasDefault: true,
+	})
+	return b
+}
+
+// Flag declares the Boolean! variable name, for use with Include, Skip,
+// IncludeIf and SkipIf, and sets it to value in the requests built by
+// Request.
+func (b *QueryBuilder) Flag(name string, value bool) *QueryBuilder {
+	if b.flags == nil {
+		b.flags = make(map[string]bool)
+	}
+	if _, ok := b.flags[name]; !ok {
+		b.Var(name, "Boolean!")
+	}
+	b.flags[name] = value
+	return b
+}
+
+// Select adds selections to the operation.
+func (b *QueryBuilder) Select(selections ...Selector) *QueryBuilder {
+	b.selections = append(b.selections, selections...)
+	return b
+}
+
+// Build renders the document, followed by the definitions of the fragments
+// it spreads. Variables of Include, Skip, IncludeIf and SkipIf directives
+// that aren't declared are declared as Boolean! = false.
+func (b *QueryBuilder) Build() (string, error) {
+	if b.name != "" && !validName(b.name) {
+		return "", fmt.Errorf("graphql: invalid operation name %q", b.name)
+	}
+	if len(b.selections) == 0 {
+		return "", errors.New("graphql: operation has no selections")
+	}
+	body := &documentWriter{fragments: make(map[string]*FragmentBuilder)}
+	if err := body.writeSelections(b.selections); err != nil {
+		return "", err
+	}
+	if err := body.writeFragments(); err != nil {
+		return "", err
+	}
+	variables := b.variables
+	declared := make(map[string]bool, len(variables))
+	for _, variable := range variables {
+		declared[variable.name] = true
+	}
+	for _, name := range body.conditions {
+		if !declared[name] {
+			declared[name] = true
+			variables = append(variables, variableDefinition{
+				name: name, typ: "Boolean!", defaultValue: false, hasDefault: true,
+			})
+		}
+	}
+	w := &documentWriter{}
+	w.WriteString(b.operationType)
+	if b.name != "" {
+		w.WriteString(" " + b.name)
+	}
+	if len(variables) > 0 {
+		w.WriteString("(")
+		for i, variable := range variables {
+			if !validName(variable.name) {
+				return "", fmt.Errorf("graphql: invalid variable name %q", variable.name)
+			}
+			if strings.TrimSpace(variable.typ) == "" {
+				return "", fmt.Errorf("graphql: variable %q has no type", variable.name)
+			}
+			if i > 0 {
+				w.WriteString(", ")
+			}
+			w.WriteString("$" + variable.name + ": " + variable.typ)
+			if variable.hasDefault {
+				w.WriteString(" = ")
+				if err := w.writeValue(variable.defaultValue); err != nil {
+					return "", errors.Wrapf(err, "default value of variable %q", variable.name)
+				}
+			}
+		}
+		w.WriteString(")")
+	}
+	return w.String() + body.String(), nil
+}
+
+// String renders the document, or returns an empty string if it is
+// invalid.
+func (b *QueryBuilder) String() string {
+	document, _ := b.Build()
+	return document
+}
+
+// Request renders the document into a new GraphRequest, with its operation
+// name and the variables declared with Flag set.
+func (b *QueryBuilder) Request() (*GraphRequest, error) {
+	document, err := b.Build()
+	if err != nil {
+		return nil, err
+	}
+	req := NewGraphqlRequest(document)
+	req.SetOperationName(b.name)
+	for name, value := range b.flags {
+		req.Var(name, value)
+	}
+	return req, nil
+}
+
+// Selector is a selection built by NewField, Spread, On, IncludeIf or
+// SkipIf.
+type Selector interface {
+	writeSelection(w *documentWriter) error
+}
+
+// FieldBuilder builds a field selection.
+type FieldBuilder struct {
+	name       string
+	alias      string
+	arguments  []argument
+	conditions []condition
+	selections []Selector
+}
+
+type argument struct {
+	name  string
+	value interface{}
+}
+
+// NewField starts a selection of the field name.
+func NewField(name string) *FieldBuilder {
+	return &FieldBuilder{name: name}
+}
+
+// Alias sets the alias of the field in the response.
+func (f *FieldBuilder) Alias(alias string) *FieldBuilder {
+	f.alias = alias
+	return f
+}
+
+// Arg adds an argument to the field. The value may be a Variable, an
+// EnumValue, a scalar, a slice, a map or a struct, which are rendered as
+// GraphQL literals following their JSON encoding.
+func (f *FieldBuilder) Arg(name string, value interface{}) *FieldBuilder {
+	f.arguments = append(f.arguments, argument{name: name, value: value})
+	return f
+}
+
+// Include selects the field only when the Boolean variable is true, with
+// an @include directive.
+func (f *FieldBuilder) Include(variable string) *FieldBuilder {
+	f.conditions = append(f.conditions, condition{directive: "include", variable: variable})
+	return f
+}
+
+// Skip leaves the field out when the Boolean variable is true, with a
+// @skip directive.
+func (f *FieldBuilder) Skip(variable string) *FieldBuilder {
+	f.conditions = append(f.conditions, condition{directive: "skip", variable: variable})
+	return f
+}
+
+// Select adds sub-selections to the field.
+func (f *FieldBuilder) Select(selections ...Selector) *FieldBuilder {
+	f.selections = append(f.selections, selections...)
+	return f
+}
+
+func (f *FieldBuilder) writeSelection(w *documentWriter) error {
+	if f.alias != "" {
+		if !validName(f.alias) {
+			return fmt.Errorf("graphql: invalid alias %q", f.alias)
+		}
+		w.WriteString(f.alias + ": ")
+	}
+	if !validName(f.name) {
+		return fmt.Errorf("graphql: invalid field name %q", f.name)
+	}
+	w.WriteString(f.name)
+	if len(f.arguments) > 0 {
+		w.WriteString("(")
+		for i, arg := range f.arguments {
+			if !validName(arg.name) {
+				return fmt.Errorf("graphql: invalid argument name %q", arg.name)
+			}
+			if i > 0 {
+				w.WriteString(", ")
+			}
+			w.WriteString(arg.name + ": ")
+			if err := w.writeValue(arg.value); err != nil {
+				return errors.Wrapf(err, "argument %q of field %q", arg.name, f.name)
+			}
+		}
+		w.WriteString(")")
+	}
+	if err := w.writeConditions(f.conditions); err != nil {
+		return err
+	}
+	if len(f.selections) == 0 {
+		return nil
+	}
+	return w.writeSelections(f.selections)
+}
+
+// FragmentBuilder builds a named fragment. Documents that spread it
+// include its definition.
+type FragmentBuilder struct {
+	name          string
+	typeCondition string
+	selections    []Selector
+}
+
+// NewFragment starts the fragment name on the type typeCondition.
+func NewFragment(name, typeCondition string) *FragmentBuilder {
+	return &FragmentBuilder{name: name, typeCondition: typeCondition}
+}
+
+// Select adds selections to the fragment.
+func (f *FragmentBuilder) Select(selections ...Selector) *FragmentBuilder {
+	f.selections = append(f.selections, selections...)
+	return f
+}
+
+// Spread returns a spread of the fragment f.
+func Spread(f *FragmentBuilder) Selector {
+	return fragmentSpread{fragment: f}
+}
+
+type fragmentSpread struct {
+	fragment *FragmentBuilder
+}
+
+func (s fragmentSpread) writeSelection(w *documentWriter) error {
+	if !validName(s.fragment.name) || s.fragment.name == "on" {
+		return fmt.Errorf("graphql: invalid fragment name %q", s.fragment.name)
+	}
+	if other, ok := w.fragments[s.fragment.name]; ok && other != s.fragment {
+		return fmt.Errorf("graphql: fragment %q is defined twice", s.fragment.name)
+	}
+	if _, ok := w.fragments[s.fragment.name]; !ok {
+		w.fragments[s.fragment.name] = s.fragment
+		w.fragmentOrder = append(w.fragmentOrder, s.fragment.name)
+	}
+	w.WriteString("..." + s.fragment.name)
+	return nil
+}
+
+// On returns an inline fragment selecting selections when the object is
+// of type typeCondition.
+func On(typeCondition string, selections ...Selector) Selector {
+	return inlineFragment{typeCondition: typeCondition, selections: selections}
+}
+
+// IncludeIf returns selections, selected only when the Boolean variable is
+// true, grouped in an inline fragment with an @include directive.
+func IncludeIf(variable string, selections ...Selector) Selector {
+	return inlineFragment{
+		conditions: []condition{{directive: "include", variable: variable}},
+		selections: selections,
+	}
+}
+
+// SkipIf returns selections, left out when the Boolean variable is true,
+// grouped in an inline fragment with a @skip directive.
+func SkipIf(variable string, selections ...Selector) Selector {
+	return inlineFragment{
+		conditions: []condition{{directive: "skip", variable: variable}},
+		selections: selections,
+	}
+}
+
+type inlineFragment struct {
+	// typeCondition is empty for the fragments of IncludeIf and SkipIf.
+	typeCondition string
+	conditions    []condition
+	selections    []Selector
+}
+
+func (f inlineFragment) writeSelection(w *documentWriter) error {
+	w.WriteString("...")
+	if f.typeCondition != "" || len(f.conditions) == 0 {
+		if !validName(f.typeCondition) {
+			return fmt.Errorf("graphql: invalid type condition %q", f.typeCondition)
+		}
+		w.WriteString(" on " + f.typeCondition)
+	}
+	if err := w.writeConditions(f.conditions); err != nil {
+		return err
+	}
+	return w.writeSelections(f.selections)
+}
+
+// condition is an @include or @skip directive on a variable.
+type condition struct {
+	directive string
+	variable  string
+}
+
+// Variable is a reference to a variable of the operation, rendered as
+// $name when used as an argument value.
+type Variable string
+
+// EnumValue is an enum value, rendered without quotes when used as an
+// argument value.
+type EnumValue string
+
+// documentWriter renders a document, collecting the fragments it spreads.
+type documentWriter struct {
+	strings.Builder
+	fragments     map[string]*FragmentBuilder
+	fragmentOrder []string
+	// conditions are the variables of the conditions written, in order.
+	conditions []string
+}
+
+func (w *documentWriter) writeConditions(conditions []condition) error {
+	for _, c := range conditions {
+		if !validName(c.variable) {
+			return fmt.Errorf("graphql: invalid variable name %q", c.variable)
+		}
+		w.WriteString(" @" + c.directive + "(if: $" + c.variable + ")")
+		seen := false
+		for _, name := range w.conditions {
+			seen = seen || name == c.variable
+		}
+		if !seen {
+			w.conditions = append(w.conditions, c.variable)
+		}
+	}
+	return nil
+}
+
+func (w *documentWriter) writeSelections(selections []Selector) error {
+	if len(selections) == 0 {
+		return errors.New("graphql: empty selection set")
+	}
+	w.WriteString(" {")
+	for _, selection := range selections {
+		w.WriteString(" ")
+		if err := selection.writeSelection(w); err != nil {
+			return err
+		}
+	}
+	w.WriteString(" }")
+	return nil
+}
+
+// writeFragments renders the fragments spread so far, including the ones
+// spread by fragments.
+func (w *documentWriter) writeFragments() error {
+	for i := 0; i < len(w.fragmentOrder); i++ {
+		fragment := w.fragments[w.fragmentOrder[i]]
+		if !validName(fragment.typeCondition) {
+			return fmt.Errorf("graphql: invalid type condition %q", fragment.typeCondition)
+		}
+		w.WriteString(" fragment " + fragment.name + " on " + fragment.typeCondition)
+		if err := w.writeSelections(fragment.selections); err != nil {
+			return errors.Wrapf(err, "fragment %q", fragment.name)
+		}
+	}
+	return nil
+}
+
+func (w *documentWriter) writeValue(value interface{}) error {
+	switch v := value.(type) {
+	case nil:
+		w.WriteString("null")
+		return nil
+	case Variable:
+		if !validName(string(v)) {
+			return fmt.Errorf("graphql: invalid variable name %q", string(v))
+		}
+		w.WriteString("$" + string(v))
+		return nil
+	case EnumValue:
+		if !validName(string(v)) || v == "true" || v == "false" || v == "null" {
+			return fmt.Errorf("graphql: invalid enum value %q", string(v))
+		}
+		w.WriteString(string(v))
+		return nil
+	case json.Marshaler:
+		return w.writeJSONValue(v)
+	}
+	rv := reflect.ValueOf(value)
+	switch rv.Kind() {
+	case reflect.Ptr, reflect.Interface:
+		if rv.IsNil() {
+			w.WriteString("null")
+			return nil
+		}
+		return w.writeValue(rv.Elem().Interface())
+	case reflect.Slice, reflect.Array:
+		if rv.Kind() == reflect.Slice && rv.Type().Elem().Kind() == reflect.Uint8 {
+			return w.writeJSONValue(value)
+		}
+		w.WriteString("[")
+		for i := 0; i < rv.Len(); i++ {
+			if i > 0 {
+				w.WriteString(", ")
+			}
+			if err := w.writeValue(rv.Index(i).Interface()); err != nil {
+				return err
+			}
+		}
+		w.WriteString("]")
+		return nil
+	case reflect.Map:
+		if rv.Type().Key().Kind() != reflect.String {
+			return fmt.Errorf("graphql: unsupported map key type %s", rv.Type().Key())
+		}
+		keys := make([]string, 0, rv.Len())
+		for _, key := range rv.MapKeys() {
+			keys = append(keys, key.String())
+		}
+		sort.Strings(keys)
+		w.WriteString("{")
+		for i, key := range keys {
+			if !validName(key) {
+				return fmt.Errorf("graphql: invalid input field name %q", key)
+			}
+			if i > 0 {
+				w.WriteString(", ")
+			}
+			w.WriteString(key + ": ")
+			item := rv.MapIndex(reflect.ValueOf(key).Convert(rv.Type().Key()))
+			if err := w.writeValue(item.Interface()); err != nil {
+				return err
+			}
+		}
+		w.WriteString("}")
+		return nil
+	}
+	return w.writeJSONValue(value)
+}
+
+// writeJSONValue renders value following its JSON encoding. Objects and
+// lists are decoded again so their keys are rendered as names.
+func (w *documentWriter) writeJSONValue(value interface{}) error {
+	encoded, err := json.Marshal(value)
+	if err != nil {
+		return errors.Wrap(err, "encode value")
+	}
+	if len(encoded) > 0 && (encoded[0] == '{' || encoded[0] == '[') {
+		decoder := json.NewDecoder(bytes.NewReader(encoded))
+		decoder.UseNumber()
+		var decoded interface{}
+		if err := decoder.Decode(&decoded); err != nil {
+			return errors.Wrap(err, "decode value")
+		}
+		return w.writeValue(decoded)
+	}
+	w.Write(encoded)
+	return nil
+}
+
+// validName reports whether s is a GraphQL name.
+func validName(s string) bool {
+	if s == "" || !isNameStart(s[0]) {
+		return false
+	}
+	for i := 1; i < len(s); i++ {
+		if !isNameContinue(s[i]) {
+			return false
+		}
+	}
+	return true
+}
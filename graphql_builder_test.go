@@ -0,0 +1,171 @@
+package graphql
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// payloadServer answers every request with data, and records the JSON
+// payloads it receives.
+type payloadServer struct {
+	*httptest.Server
+
+	mu       sync.Mutex
+	payloads []graphqlModel
+}
+
+func newPayloadServer(t *testing.T, data string) *payloadServer {
+	s := &payloadServer{}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var payload graphqlModel
+		if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		s.mu.Lock()
+		s.payloads = append(s.payloads, payload)
+		s.mu.Unlock()
+		fmt.Fprintf(w, `{"data":%s}`, data)
+	}))
+	t.Cleanup(s.Close)
+	return s
+}
+
+func (s *payloadServer) last() graphqlModel {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(s.payloads) == 0 {
+		return graphqlModel{}
+	}
+	return s.payloads[len(s.payloads)-1]
+}
+
+func TestQueryBuilderBuild(t *testing.T) {
+	userFields := NewFragment("UserFields", "User").Select(NewField("id"), NewField("name"))
+	tests := []struct {
+		name    string
+		builder *QueryBuilder
+		want    string
+	}{
+		{
+			name: "variables and arguments",
+			builder: NewQueryBuilder("GetUser").Var("id", "ID!").Select(
+				NewField("user").Arg("id", Variable("id")).Select(
+					NewField("name"),
+					NewField("avatar").Alias("thumbnail").Arg("size", 64),
+				)),
+			want: `query GetUser($id: ID!) { user(id: $id) { name thumbnail: avatar(size: 64) } }`,
+		},
+		{
+			name:    "anonymous mutation",
+			builder: NewMutationBuilder("").Select(NewField("logout")),
+			want:    `mutation { logout }`,
+		},
+		{
+			name:    "default value",
+			builder: NewQueryBuilder("List").VarDefault("first", "Int", 10).Select(NewField("users").Arg("first", Variable("first")).Select(NewField("id"))),
+			want:    `query List($first: Int = 10) { users(first: $first) { id } }`,
+		},
+		{
+			name: "literal values",
+			builder: NewQueryBuilder("").Select(NewField("search").
+				Arg("role", EnumValue("ADMIN")).
+				Arg("names", []string{"a", `b"c`}).
+				Arg("filter", map[string]interface{}{"active": true, "age": nil}).
+				Select(NewField("id"))),
+			want: `query { search(role: ADMIN, names: ["a", "b\"c"], filter: {active: true, age: null}) { id } }`,
+		},
+		{
+			name:    "struct argument",
+			builder: NewQueryBuilder("").Select(NewField("create").Arg("input", struct{ Name string }{"a"}).Select(NewField("id"))),
+			want:    `query { create(input: {Name: "a"}) { id } }`,
+		},
+		{
+			name: "fragments",
+			builder: NewQueryBuilder("").Select(
+				NewField("me").Select(Spread(userFields)),
+				NewField("node").Arg("id", 1).Select(On("User", NewField("role")), Spread(userFields)),
+			),
+			want: `query { me { ...UserFields } node(id: 1) { ... on User { role } ...UserFields } } fragment UserFields on User { id name }`,
+		},
+		{
+			name: "conditions",
+			builder: NewQueryBuilder("").Flag("full", true).Select(
+				NewField("me").Select(
+					NewField("id"),
+					NewField("email").Include("full"),
+					SkipIf("brief", NewField("bio")),
+				)),
+			want: `query($full: Boolean!, $brief: Boolean! = false) { me { id email @include(if: $full) ... @skip(if: $brief) { bio } } }`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.builder.Build()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Fatalf("got  %s\nwant %s", got, tt.want)
+			}
+			if _, err := ParseQuery(got); err != nil {
+				t.Fatalf("built an invalid document: %v", err)
+			}
+		})
+	}
+}
+
+func TestQueryBuilderErrors(t *testing.T) {
+	tests := map[string]struct {
+		builder *QueryBuilder
+		err     string
+	}{
+		"no selections":   {NewQueryBuilder("Q"), "operation has no selections"},
+		"operation name":  {NewQueryBuilder("get-user").Select(NewField("a")), `invalid operation name "get-user"`},
+		"field name":      {NewQueryBuilder("").Select(NewField("a b")), `invalid field name "a b"`},
+		"alias":           {NewQueryBuilder("").Select(NewField("a").Alias("1a")), `invalid alias "1a"`},
+		"variable type":   {NewQueryBuilder("").Var("id", " ").Select(NewField("a")), `variable "id" has no type`},
+		"enum value":      {NewQueryBuilder("").Select(NewField("a").Arg("x", EnumValue("true"))), `invalid enum value "true"`},
+		"empty selection": {NewQueryBuilder("").Select(NewField("a").Select(On("User"))), "empty selection set"},
+		"duplicate fragment": {
+			NewQueryBuilder("").Select(Spread(NewFragment("F", "User").Select(NewField("id"))), Spread(NewFragment("F", "User").Select(NewField("name")))),
+			`fragment "F" is defined twice`,
+		},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			_, err := tt.builder.Build()
+			if err == nil || !strings.Contains(err.Error(), tt.err) {
+				t.Fatalf("got %v, want an error containing %q", err, tt.err)
+			}
+			if tt.builder.String() != "" {
+				t.Fatalf("String() = %q, want an empty string", tt.builder.String())
+			}
+		})
+	}
+}
+
+func TestQueryBuilderRequest(t *testing.T) {
+	srv := newPayloadServer(t, `{"me":{"id":"1"}}`)
+	req, err := NewQueryBuilder("Me").Flag("full", false).Select(NewField("me").Select(NewField("id"), NewField("email").Include("full"))).Request()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var data struct{ Me struct{ ID string } }
+	if _, err := NewClient(srv.URL).Run(context.Background(), req, &data); err != nil {
+		t.Fatal(err)
+	}
+	payload := srv.last()
+	if payload.OperationName != "Me" || payload.Variables["full"] != false || !strings.HasPrefix(payload.Query, "query Me($full: Boolean!)") {
+		t.Fatalf("got payload %+v", payload)
+	}
+	if data.Me.ID != "1" {
+		t.Fatalf("got %+v", data)
+	}
+}
@@ -0,0 +1,432 @@
+package graphql
+
+import (
+	"container/list"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// Cache stores the responses cached with WithResponseCache, e.g. in Redis
+// or memcached so instances of a service share them. Values are opaque.
+type Cache interface {
+	// Get returns the value of key, and false if there is none.
+	Get(ctx context.Context, key string) ([]byte, bool, error)
+	// Set stores value under key for ttl.
+	Set(ctx context.Context, key string, value []byte, ttl time.Duration) error
+	Delete(ctx context.Context, key string) error
+}
+
+// CacheConfig configures the response cache enabled with WithResponseCache.
+type CacheConfig struct {
+	// TTL is how long responses are reused.
+	TTL time.Duration
+	// MaxEntries bounds the number of responses of the default in-memory
+	// backend, evicting the least recently used ones. Zero means no limit.
+	MaxEntries int
+	// Headers are the request headers, set with WithDefaultHeaders or on
+	// requests, whose values are part of the cache key, e.g. Authorization
+	// when responses depend on the caller. Headers set by an AuthProvider
+	// aren't known when the key is computed: requests using one are never
+	// cached.
+	Headers []string
+	// IgnoreVariables are the variables left out of the cache key, e.g.
+	// volatile ones that don't change the response.
+	IgnoreVariables []string
+	// Key, if set, returns the key of the cached response to req from its
+	// default key, e.g. to add a tenant ID from ctx, or false for it not to
+	// be cached.
+	Key CacheKeyFunc
+	// Backend stores the responses. It defaults to NewMemoryCache.
+	Backend Cache
+	// OperationStats breaks the lookups counted in Stats.Cache down by
+	// operation name in Stats.CacheByOperation.
+	OperationStats bool
+	// Revalidate, if set, keeps responses with an ETag header for that
+	// long after their TTL expired. Queries then send the ETag in an
+	// If-None-Match header, and reuse the cached response when the server
+	// answers 304 Not Modified.
+	Revalidate time.Duration
+	// StaleWhileRevalidate, if set, keeps responses for that long after
+	// their TTL expired. Queries then return the stale response right away,
+	// with GraphResponse.Stale set, while it is refreshed in the
+	// background.
+	StaleWhileRevalidate time.Duration
+	// OnRefresh, if set, is called with the fresh responses of background
+	// refreshes, their data left undecoded in RawData, or the error that
+	// failed them.
+	OnRefresh RefreshFunc
+}
+
+// CacheKeyFunc returns the key of the cached response to req, given its
+// default key, and false for the response not to be cached.
+type CacheKeyFunc func(ctx context.Context, req *GraphRequest, key string) (string, bool)
+
+// CacheControl controls how the caches of the client serve a request.
+type CacheControl struct {
+	// NoCache sends the request without caching its response.
+	NoCache bool
+	// RefreshCache sends the request and caches its response, replacing
+	// the cached one.
+	RefreshCache bool
+	// MaxStale, if set, accepts a cached response up to that long after
+	// its TTL expired, with GraphResponse.Stale set. Responses are only
+	// kept past their TTL with CacheConfig.StaleWhileRevalidate or
+	// Revalidate.
+	MaxStale time.Duration
+}
+
+// SetCacheControl sets how the caches of the client serve this request.
+func (req *GraphRequest) SetCacheControl(control CacheControl) {
+	req.cacheControl = control
+}
+
+// CacheControl returns the CacheControl set with SetCacheControl.
+func (req *GraphRequest) CacheControl() CacheControl {
+	return req.cacheControl
+}
+
+// RefreshFunc receives the response to req fetched to replace a stale
+// cached response.
+type RefreshFunc func(ctx context.Context, req *GraphRequest, res *GraphResponse, err error)
+
+// responseCache is the response cache of a client.
+type responseCache struct {
+	CacheConfig
+
+	mu sync.Mutex
+	// refreshing are the keys of the responses being refreshed.
+	refreshing map[string]bool
+}
+
+// WithResponseCache caches the responses of queries without errors, so
+// identical queries skip the network within the TTL. Queries are identical
+// when their canonical documents (see CanonicalQuery), operation names,
+// variables and the headers listed in config match. Mutations,
+// subscriptions, uploads, streamed responses and requests authenticated by
+// an AuthProvider, set with WithAuth or SetAuth, are never cached. Cached
+// responses have GraphResponse.FromCache set.
+func WithResponseCache(config CacheConfig) ClientOption {
+	return func(client *Client) {
+		if config.Backend == nil {
+			config.Backend = NewMemoryCache(config.MaxEntries)
+		}
+		client.cache = &responseCache{CacheConfig: config, refreshing: make(map[string]bool)}
+	}
+}
+
+// EvictionCounter is implemented by the Cache backends that count the
+// values they evicted to make room, such as the one of NewMemoryCache, for
+// Client.Stats to report them.
+type EvictionCounter interface {
+	Evictions() int64
+}
+
+// memoryEntry is a value kept by a memory cache.
+type memoryEntry struct {
+	key     string
+	value   []byte
+	expires time.Time
+}
+
+// memoryCache is an LRU Cache held in memory.
+type memoryCache struct {
+	maxEntries int
+
+	mu        sync.Mutex
+	entries   map[string]*list.Element
+	lru       *list.List
+	evictions int64
+}
+
+// NewMemoryCache returns a Cache held in memory, bounded to maxEntries
+// values by evicting the least recently used ones. Zero means no limit.
+func NewMemoryCache(maxEntries int) Cache {
+	return &memoryCache{
+		maxEntries: maxEntries,
+		entries:    make(map[string]*list.Element),
+		lru:        list.New(),
+	}
+}
+
+func (c *memoryCache) Get(_ context.Context, key string) ([]byte, bool, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	element, ok := c.entries[key]
+	if !ok {
+		return nil, false, nil
+	}
+	entry := element.Value.(*memoryEntry)
+	if time.Now().After(entry.expires) {
+		c.lru.Remove(element)
+		delete(c.entries, key)
+		return nil, false, nil
+	}
+	c.lru.MoveToFront(element)
+	return entry.value, true, nil
+}
+
+func (c *memoryCache) Set(_ context.Context, key string, value []byte, ttl time.Duration) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	entry := &memoryEntry{key: key, value: value, expires: time.Now().Add(ttl)}
+	if element, ok := c.entries[key]; ok {
+		element.Value = entry
+		c.lru.MoveToFront(element)
+		return nil
+	}
+	c.entries[key] = c.lru.PushFront(entry)
+	for c.maxEntries > 0 && c.lru.Len() > c.maxEntries {
+		oldest := c.lru.Back()
+		c.lru.Remove(oldest)
+		delete(c.entries, oldest.Value.(*memoryEntry).key)
+		c.evictions++
+	}
+	return nil
+}
+
+func (c *memoryCache) Evictions() int64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.evictions
+}
+
+func (c *memoryCache) Delete(_ context.Context, key string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if element, ok := c.entries[key]; ok {
+		c.lru.Remove(element)
+		delete(c.entries, key)
+	}
+	return nil
+}
+
+// cacheEntry is a response stored in a Cache.
+type cacheEntry struct {
+	Header  http.Header     `json:"header"`
+	Body    json.RawMessage `json:"body"`
+	Expires time.Time       `json:"expires"`
+}
+
+// etag returns the ETag of the response if it can be revalidated.
+func (c *Client) etag(entry *cacheEntry) string {
+	if c.cache.Revalidate <= 0 {
+		return ""
+	}
+	return entry.Header.Get("ETag")
+}
+
+// InvalidateCache removes the cached response to req, if any, e.g. after a
+// mutation changed its data.
+func (c *Client) InvalidateCache(ctx context.Context, req *GraphRequest) error {
+	if c.cache == nil {
+		return nil
+	}
+	req, err := c.withFragments(c.withDefaultVars(req))
+	if err != nil {
+		return err
+	}
+	key := c.cacheKey(ctx, newOperation(c.withMinifiedQuery(req)))
+	if key == "" {
+		return nil
+	}
+	return c.cache.Backend.Delete(ctx, key)
+}
+
+// cacheKey returns the key of the response to op, or "" if it must not be
+// cached.
+func (c *Client) cacheKey(ctx context.Context, op *operation) string {
+	req := op.req
+	if c.cache == nil || op.hasSideEffects() || len(req.files) > 0 || op.stream != nil || req.cacheControl.NoCache {
+		return ""
+	}
+	// the credentials an AuthProvider sets may depend on the caller, e.g.
+	// on ctx, and aren't known until the request is built
+	if c.authProvider(req) != nil {
+		return ""
+	}
+	query, err := CanonicalQuery(req.query)
+	if err != nil {
+		query = req.query
+	}
+	keyVars := req.vars
+	if len(c.cache.IgnoreVariables) > 0 {
+		keyVars = make(map[string]interface{}, len(req.vars))
+		for name, value := range req.vars {
+			keyVars[name] = value
+		}
+		for _, name := range c.cache.IgnoreVariables {
+			delete(keyVars, name)
+		}
+	}
+	vars, err := json.Marshal(keyVars)
+	if err != nil {
+		return ""
+	}
+	h := sha256.New()
+	for _, part := range []string{query, req.operationName, string(vars)} {
+		h.Write([]byte(part))
+		h.Write([]byte{0})
+	}
+	headers := append([]string(nil), c.cache.Headers...)
+	sort.Strings(headers)
+	for _, name := range headers {
+		values := req.Header.Values(name)
+		if len(values) == 0 {
+			values = c.headers.Values(name)
+		}
+		h.Write([]byte(http.CanonicalHeaderKey(name)))
+		for _, value := range values {
+			h.Write([]byte{0})
+			h.Write([]byte(value))
+		}
+		h.Write([]byte{0})
+	}
+	key := hex.EncodeToString(h.Sum(nil))
+	if c.cache.Key != nil {
+		var ok bool
+		if key, ok = c.cache.Key(ctx, req, key); !ok {
+			return ""
+		}
+	}
+	return key
+}
+
+// cachedResponse returns the cached response to op, decoded into
+// graphqlResponse, if any.
+func (c *Client) cachedResponse(ctx context.Context, op *operation, graphqlResponse interface{}) (*GraphResponse, bool) {
+	if op.cacheKey == "" {
+		return nil, false
+	}
+	graphResponse, ok := c.lookupCache(ctx, op, graphqlResponse)
+	c.stats.cacheLookup(op.name, ok, c.cache.OperationStats)
+	return graphResponse, ok
+}
+
+// lookupCache reads the cached response to op.
+func (c *Client) lookupCache(ctx context.Context, op *operation, graphqlResponse interface{}) (*GraphResponse, bool) {
+	value, ok, err := c.cache.Backend.Get(ctx, op.cacheKey)
+	if err != nil {
+		c.logf(op, LogLevelInfo, LogWire, "<< cache: %v", err)
+	}
+	if !ok || err != nil {
+		return nil, false
+	}
+	entry := &cacheEntry{}
+	if err := json.Unmarshal(value, entry); err != nil {
+		return nil, false
+	}
+	now := time.Now()
+	stale := now.After(entry.Expires)
+	revalidate := stale && now.Before(entry.Expires.Add(c.cache.StaleWhileRevalidate))
+	if stale && !revalidate && !now.Before(entry.Expires.Add(op.req.cacheControl.MaxStale)) {
+		if c.etag(entry) != "" {
+			op.revalidate = entry
+		}
+		return nil, false
+	}
+	graphResponse, err := c.decodeCacheEntry(op, entry, graphqlResponse)
+	if err != nil {
+		return nil, false
+	}
+	c.logf(op, LogLevelDebug, LogWire, "<< cached response")
+	graphResponse.Stale = stale
+	if revalidate {
+		c.refreshCache(ctx, op, entry)
+	}
+	return graphResponse, true
+}
+
+// refreshCache refreshes the stale cached response to op in the
+// background, unless it is already being refreshed.
+func (c *Client) refreshCache(ctx context.Context, op *operation, entry *cacheEntry) {
+	c.cache.mu.Lock()
+	if c.cache.refreshing[op.cacheKey] {
+		c.cache.mu.Unlock()
+		return
+	}
+	c.cache.refreshing[op.cacheKey] = true
+	c.cache.mu.Unlock()
+	refresh := newOperation(op.req)
+	refresh.requestID = op.requestID
+	refresh.refreshCache = true
+	if c.etag(entry) != "" {
+		refresh.revalidate = entry
+	}
+	ctx = context.WithoutCancel(ctx)
+	go func() {
+		defer func() {
+			c.cache.mu.Lock()
+			delete(c.cache.refreshing, op.cacheKey)
+			c.cache.mu.Unlock()
+		}()
+		c.logf(refresh, LogLevelDebug, LogWire, ">> refreshing stale cached response")
+		graphResponse, err := c.run(ctx, refresh, nil)
+		if c.cache.OnRefresh != nil {
+			c.cache.OnRefresh(ctx, refresh.req, graphResponse, err)
+		}
+	}()
+}
+
+// notModified reports whether res is a 304 Not Modified answering the
+// revalidation of the cached response to op.
+func notModified(op *operation, res *http.Response) bool {
+	return res.StatusCode == http.StatusNotModified && op.revalidate != nil
+}
+
+// revalidatedResponse returns the cached response to op the server
+// answered res, a 304 Not Modified, for, and caches it again.
+func (c *Client) revalidatedResponse(ctx context.Context, op *operation, res *http.Response, graphqlResponse interface{}) (*GraphResponse, error) {
+	entry := op.revalidate
+	header := entry.Header.Clone()
+	for key, values := range res.Header {
+		header[key] = values
+	}
+	graphResponse, err := c.decodeCacheEntry(op, &cacheEntry{Header: header, Body: entry.Body}, graphqlResponse)
+	if err != nil {
+		return nil, errors.Wrap(err, "decoding cached response")
+	}
+	c.logf(op, LogLevelDebug, LogWire, "<< not modified")
+	c.cacheResponse(ctx, op, graphResponse, &http.Response{Header: header}, entry.Body)
+	return graphResponse, nil
+}
+
+// decodeCacheEntry decodes entry, a cached response to op, into
+// graphqlResponse.
+func (c *Client) decodeCacheEntry(op *operation, entry *cacheEntry, graphqlResponse interface{}) (*GraphResponse, error) {
+	graphResponse := &GraphResponse{Data: graphqlResponse}
+	if err := c.decodeResponse(entry.Body, graphResponse); err != nil {
+		return nil, err
+	}
+	res := &http.Response{StatusCode: http.StatusOK, Header: entry.Header}
+	c.finishResponse(op, graphResponse, res, entry.Body)
+	graphResponse.FromCache = true
+	return graphResponse, nil
+}
+
+// cacheResponse caches body, the response to op, unless it has errors.
+func (c *Client) cacheResponse(ctx context.Context, op *operation, graphResponse *GraphResponse, res *http.Response, body []byte) {
+	if op.cacheKey == "" || len(graphResponse.Errors) > 0 || graphResponse.NoData {
+		return
+	}
+	entry := &cacheEntry{Header: res.Header, Body: body, Expires: time.Now().Add(c.cache.TTL)}
+	keep := c.cache.StaleWhileRevalidate
+	if c.etag(entry) != "" && c.cache.Revalidate > keep {
+		keep = c.cache.Revalidate
+	}
+	ttl := c.cache.TTL + keep
+	value, err := json.Marshal(entry)
+	if err == nil {
+		err = c.cache.Backend.Set(ctx, op.cacheKey, value, ttl)
+	}
+	if err != nil {
+		c.logf(op, LogLevelInfo, LogWire, "<< cache: %v", err)
+	}
+}
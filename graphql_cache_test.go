@@ -0,0 +1,137 @@
+package graphql
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// userServer answers with the user of the Authorization header of the
+// request, and counts the requests.
+func userServer(t *testing.T, calls *int32) *httptest.Server {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(calls, 1)
+		fmt.Fprintf(w, `{"data":{"viewer":%q}}`, r.Header.Get("Authorization"))
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+type viewerData struct {
+	Viewer string
+}
+
+func TestResponseCacheReusesResponses(t *testing.T) {
+	var calls int32
+	srv := userServer(t, &calls)
+	client := NewClient(srv.URL, WithResponseCache(CacheConfig{TTL: time.Minute}))
+	for i := 0; i < 2; i++ {
+		var data viewerData
+		res, err := client.Run(context.Background(), NewGraphqlRequest("{ viewer }"), &data)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if res.FromCache != (i == 1) {
+			t.Fatalf("run %d: FromCache = %t", i, res.FromCache)
+		}
+	}
+	if calls != 1 {
+		t.Fatalf("server got %d requests, want 1", calls)
+	}
+}
+
+func TestResponseCacheKeysOnHeaders(t *testing.T) {
+	var calls int32
+	srv := userServer(t, &calls)
+	client := NewClient(srv.URL, WithResponseCache(CacheConfig{TTL: time.Minute, Headers: []string{"Authorization"}}))
+	for _, user := range []string{"alice", "bob"} {
+		req := NewGraphqlRequest("{ viewer }")
+		req.Header.Set("Authorization", user)
+		var data viewerData
+		if _, err := client.Run(context.Background(), req, &data); err != nil {
+			t.Fatal(err)
+		}
+		if data.Viewer != user {
+			t.Fatalf("got viewer %q, want %q", data.Viewer, user)
+		}
+	}
+}
+
+func TestResponseCacheSkipsAuthProviders(t *testing.T) {
+	type userKey struct{}
+	var calls int32
+	srv := userServer(t, &calls)
+	auth := AuthProviderFunc(func(ctx context.Context, r *http.Request) error {
+		r.Header.Set("Authorization", ctx.Value(userKey{}).(string))
+		return nil
+	})
+	tests := map[string][]ClientOption{
+		"client":  {WithAuth(auth)},
+		"request": nil,
+	}
+	for name, opts := range tests {
+		t.Run(name, func(t *testing.T) {
+			opts = append(opts, WithResponseCache(CacheConfig{TTL: time.Minute, Headers: []string{"Authorization"}}))
+			client := NewClient(srv.URL, opts...)
+			for _, user := range []string{"alice", "bob"} {
+				req := NewGraphqlRequest("{ viewer }")
+				if name == "request" {
+					req.SetAuth(auth)
+				}
+				ctx := context.WithValue(context.Background(), userKey{}, user)
+				var data viewerData
+				res, err := client.Run(ctx, req, &data)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if data.Viewer != user || res.FromCache {
+					t.Fatalf("got viewer %q from cache %t, want %q from the server", data.Viewer, res.FromCache, user)
+				}
+			}
+		})
+	}
+}
+
+func TestResponseCacheRevalidates(t *testing.T) {
+	for _, multipart := range []bool{false, true} {
+		t.Run(fmt.Sprintf("multipart=%t", multipart), func(t *testing.T) {
+			var calls, notModified int32
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				atomic.AddInt32(&calls, 1)
+				w.Header().Set("ETag", `"v1"`)
+				if r.Header.Get("If-None-Match") == `"v1"` {
+					atomic.AddInt32(&notModified, 1)
+					w.WriteHeader(http.StatusNotModified)
+					return
+				}
+				fmt.Fprint(w, `{"data":{"viewer":"alice"}}`)
+			}))
+			defer srv.Close()
+			opts := []ClientOption{WithResponseCache(CacheConfig{TTL: time.Millisecond, Revalidate: time.Minute})}
+			if multipart {
+				opts = append(opts, UseMultipartForm())
+			}
+			client := NewClient(srv.URL, opts...)
+			for i := 0; i < 2; i++ {
+				if i == 1 {
+					time.Sleep(5 * time.Millisecond)
+				}
+				var data viewerData
+				res, err := client.Run(context.Background(), NewGraphqlRequest("{ viewer }"), &data)
+				if err != nil {
+					t.Fatalf("run %d: %v", i, err)
+				}
+				if data.Viewer != "alice" || res.FromCache != (i == 1) {
+					t.Fatalf("run %d: got viewer %q from cache %t", i, data.Viewer, res.FromCache)
+				}
+			}
+			if calls != 2 || notModified != 1 {
+				t.Fatalf("server got %d requests, %d revalidations, want 2 and 1", calls, notModified)
+			}
+		})
+	}
+}
@@ -0,0 +1,88 @@
+package graphql
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"sync"
+)
+
+// Exchange is the raw content of a single HTTP request sent by the Client
+// and of its response. Nothing in it is redacted.
+type Exchange struct {
+	Method        string
+	URL           string
+	RequestHeader http.Header
+	RequestBody   []byte
+	// StatusCode, ResponseHeader and ResponseBody are empty if no
+	// response was received.
+	StatusCode     int
+	ResponseHeader http.Header
+	ResponseBody   []byte
+	// Err is set if the request failed or its response couldn't be read.
+	Err error
+}
+
+// CaptureFunc receives the raw exchange of an HTTP request.
+type CaptureFunc func(ctx context.Context, info OperationInfo, exchange Exchange)
+
+// WithCapture calls fn with the exact bytes sent and received for every
+// HTTP request, including retries, e.g. to persist or scan GraphQL traffic.
+// Exchanges contain credentials, so handle them with care.
+func WithCapture(fn CaptureFunc) ClientOption {
+	return func(client *Client) {
+		client.capture = fn
+	}
+}
+
+// captureBuffer records the request body as the transport reads it, which
+// may happen on another goroutine.
+type captureBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *captureBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *captureBuffer) bytes() []byte {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return append([]byte(nil), b.buf.Bytes()...)
+}
+
+// captureBody returns body teed into a new captureBuffer, or body and nil
+// if capturing is off.
+func (c *Client) captureBody(body io.Reader) (io.Reader, *captureBuffer) {
+	if c.capture == nil {
+		return body, nil
+	}
+	sent := &captureBuffer{}
+	return io.TeeReader(body, sent), sent
+}
+
+func (c *Client) captureExchange(ctx context.Context, op *operation, r *http.Request, sent *captureBuffer,
+	res *http.Response, buf *bytes.Buffer, err error) {
+	if c.capture == nil {
+		return
+	}
+	exchange := Exchange{
+		Method:        r.Method,
+		URL:           r.URL.String(),
+		RequestHeader: r.Header.Clone(),
+		RequestBody:   sent.bytes(),
+		Err:           err,
+	}
+	if res != nil {
+		exchange.StatusCode = res.StatusCode
+		exchange.ResponseHeader = res.Header.Clone()
+	}
+	if buf != nil {
+		exchange.ResponseBody = append([]byte(nil), buf.Bytes()...)
+	}
+	c.capture(ctx, c.operationInfo(op), exchange)
+}
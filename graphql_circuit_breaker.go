@@ -0,0 +1,148 @@
+package graphql
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// ErrCircuitOpen is returned by Run while the circuit breaker is open and
+// requests are failing fast.
+var ErrCircuitOpen = errors.New("graphql: circuit breaker is open")
+
+// CircuitState is the state of a circuit breaker.
+type CircuitState int
+
+const (
+	// CircuitClosed lets every request through.
+	CircuitClosed CircuitState = iota
+	// CircuitOpen fails every request fast until the cool-down expires.
+	CircuitOpen
+	// CircuitHalfOpen lets a single trial request through to probe the
+	// endpoint.
+	CircuitHalfOpen
+)
+
+func (s CircuitState) String() string {
+	switch s {
+	case CircuitClosed:
+		return "closed"
+	case CircuitOpen:
+		return "open"
+	case CircuitHalfOpen:
+		return "half-open"
+	}
+	return "unknown"
+}
+
+// CircuitBreakerSettings configures the circuit breaker enabled with
+// WithCircuitBreaker.
+type CircuitBreakerSettings struct {
+	// FailureThreshold is the number of consecutive failures that opens
+	// the circuit.
+	FailureThreshold int
+	// CoolDown is how long the circuit stays open before a trial request
+	// is let through.
+	CoolDown time.Duration
+	// OnStateChange, if set, is called every time the circuit changes state.
+	OnStateChange func(from, to CircuitState)
+}
+
+// WithCircuitBreaker enables a circuit breaker that opens after
+// FailureThreshold consecutive failures (network errors, 429 and 5xx
+// responses) and makes Run fail fast with ErrCircuitOpen for the CoolDown
+// period.
+func WithCircuitBreaker(settings CircuitBreakerSettings) ClientOption {
+	return func(client *Client) {
+		client.breaker = &circuitBreaker{settings: settings}
+	}
+}
+
+type circuitBreaker struct {
+	settings CircuitBreakerSettings
+
+	mu       sync.Mutex
+	state    CircuitState
+	failures int
+	openedAt time.Time
+	trial    bool
+}
+
+// allow reports whether a request may be sent, moving an open circuit to
+// half-open once its cool-down has expired.
+func (b *circuitBreaker) allow() error {
+	b.mu.Lock()
+	from := b.state
+	switch b.state {
+	case CircuitOpen:
+		if time.Since(b.openedAt) < b.settings.CoolDown {
+			b.mu.Unlock()
+			return ErrCircuitOpen
+		}
+		b.state = CircuitHalfOpen
+		b.trial = true
+	case CircuitHalfOpen:
+		if b.trial {
+			b.mu.Unlock()
+			return ErrCircuitOpen
+		}
+		b.trial = true
+	case CircuitClosed:
+	}
+	to := b.state
+	b.mu.Unlock()
+	b.notify(from, to)
+	return nil
+}
+
+// done records the outcome of a request let through by allow that was
+// sent: a response, or a network error. Requests that failed before being
+// sent are released instead.
+func (b *circuitBreaker) done(ctx context.Context, res *http.Response, err error) {
+	b.mu.Lock()
+	from := b.state
+	b.trial = false
+	switch {
+	case ctx.Err() != nil:
+		// the caller gave up, which says nothing about the endpoint
+	case isTransientFailure(res, err):
+		b.failures++
+		if b.state == CircuitHalfOpen || b.failures >= b.settings.FailureThreshold {
+			b.state = CircuitOpen
+			b.openedAt = time.Now()
+		}
+	default:
+		b.failures = 0
+		b.state = CircuitClosed
+	}
+	to := b.state
+	b.mu.Unlock()
+	b.notify(from, to)
+}
+
+// release ends a request let through by allow that was never sent, e.g.
+// because its body couldn't be built, without recording an outcome.
+func (b *circuitBreaker) release() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.trial = false
+}
+
+// unsentError is an error raised building a request, before anything was
+// sent to the endpoint.
+type unsentError struct {
+	err error
+}
+
+func (e *unsentError) Error() string {
+	return e.err.Error()
+}
+
+func (b *circuitBreaker) notify(from, to CircuitState) {
+	if from != to && b.settings.OnStateChange != nil {
+		b.settings.OnStateChange(from, to)
+	}
+}
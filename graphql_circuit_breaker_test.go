@@ -0,0 +1,134 @@
+package graphql
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+func TestCircuitOpenMultipartDoesNotHang(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer srv.Close()
+	client := NewClient(srv.URL, UseMultipartForm(), WithCircuitBreaker(CircuitBreakerSettings{
+		FailureThreshold: 1,
+		CoolDown:         time.Minute,
+	}))
+	req := NewGraphqlRequest("mutation { upload }")
+	req.File("file", "a.txt", strings.NewReader("content"))
+	if _, err := client.Run(context.Background(), req, nil); err == nil {
+		t.Fatal("expected the first request to fail")
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		req := NewGraphqlRequest("mutation { upload }")
+		req.File("file", "a.txt", strings.NewReader("content"))
+		_, err := client.Run(context.Background(), req, nil)
+		done <- err
+	}()
+	select {
+	case err := <-done:
+		if !errors.Is(err, ErrCircuitOpen) {
+			t.Fatalf("got %v, want %v", err, ErrCircuitOpen)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Run did not return with an open circuit")
+	}
+}
+
+func TestCircuitOpensAfterFailures(t *testing.T) {
+	calls := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer srv.Close()
+	client := NewClient(srv.URL, WithCircuitBreaker(CircuitBreakerSettings{
+		FailureThreshold: 2,
+		CoolDown:         time.Minute,
+	}))
+	for i := 0; i < 3; i++ {
+		_, err := client.Run(context.Background(), NewGraphqlRequest("{ a }"), nil)
+		if i == 2 && !errors.Is(err, ErrCircuitOpen) {
+			t.Fatalf("run %d: got %v, want %v", i, err, ErrCircuitOpen)
+		}
+	}
+	if calls != 2 {
+		t.Fatalf("server got %d requests, want 2", calls)
+	}
+}
+
+// unrewindable is a file reader that can't be rewound to its start.
+type unrewindable struct {
+	*strings.Reader
+}
+
+func (r unrewindable) Seek(offset int64, whence int) (int64, error) {
+	if whence == io.SeekStart {
+		return 0, errors.New("cannot rewind")
+	}
+	return r.Reader.Seek(offset, whence)
+}
+
+func TestCircuitHalfOpenIgnoresUnsentRequests(t *testing.T) {
+	tests := map[string]func(req *GraphRequest){
+		"body fails": func(req *GraphRequest) {
+			req.File("file", "a.txt", unrewindable{strings.NewReader("content")})
+		},
+		"auth fails": func(req *GraphRequest) {
+			req.SetAuth(AuthProviderFunc(func(ctx context.Context, r *http.Request) error {
+				return errors.New("no credentials")
+			}))
+		},
+	}
+	for name, unsent := range tests {
+		t.Run(name, func(t *testing.T) {
+			var calls int32
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if atomic.AddInt32(&calls, 1) == 1 {
+					w.WriteHeader(http.StatusServiceUnavailable)
+					return
+				}
+				fmt.Fprint(w, `{"data":{"a":1}}`)
+			}))
+			defer srv.Close()
+			var states []CircuitState
+			client := NewClient(srv.URL, UseMultipartForm(), WithCircuitBreaker(CircuitBreakerSettings{
+				FailureThreshold: 1,
+				CoolDown:         10 * time.Millisecond,
+				OnStateChange: func(from, to CircuitState) {
+					states = append(states, to)
+				},
+			}))
+			if _, err := client.Run(context.Background(), NewGraphqlRequest("{ a }"), nil); err == nil {
+				t.Fatal("expected the first request to fail")
+			}
+			time.Sleep(20 * time.Millisecond)
+			req := NewGraphqlRequest("{ a }")
+			unsent(req)
+			if _, err := client.Run(context.Background(), req, nil); err == nil || errors.Is(err, ErrCircuitOpen) {
+				t.Fatalf("got %v, want the error of the unsent request", err)
+			}
+			if want := []CircuitState{CircuitOpen, CircuitHalfOpen}; !reflect.DeepEqual(states, want) {
+				t.Fatalf("got states %v, want %v", states, want)
+			}
+			if _, err := client.Run(context.Background(), NewGraphqlRequest("{ a }"), nil); err != nil {
+				t.Fatalf("trial request: %v", err)
+			}
+			if calls != 2 {
+				t.Fatalf("server got %d requests, want 2", calls)
+			}
+		})
+	}
+}
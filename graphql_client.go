@@ -4,10 +4,12 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
-	"fmt"
 	"io"
+	"io/ioutil"
 	"mime/multipart"
 	"net/http"
+	"net/http/httptrace"
+	"time"
 
 	"github.com/pkg/errors"
 )
@@ -28,20 +30,129 @@ type Client struct {
 
 	useMultipartForm bool
 
+	// retry is nil unless retries were enabled with WithRetry.
+	retry *RetryPolicy
+	// breaker is nil unless enabled with WithCircuitBreaker.
+	breaker *circuitBreaker
+	// limiter is nil unless enabled with WithRateLimit.
+	limiter *tokenBucket
+	// scheduler is nil unless enabled with WithScheduler.
+	scheduler *scheduler
+	// priorityHeader is empty unless set with WithPriorityHeader.
+	priorityHeader string
+	// idempotencyKeyHeader is empty unless set with WithIdempotencyKeys.
+	idempotencyKeyHeader string
+	// errorPolicy is set with WithErrorPolicy.
+	errorPolicy ErrorPolicy
+	// strictDecoding is set with WithStrictDecoding.
+	strictDecoding bool
+	// useNumber is set with WithUseNumber.
+	useNumber bool
+	// codec is nil unless set with WithCodec.
+	codec Codec
+	// scalars is nil unless set with WithScalars.
+	scalars *Scalars
+	// rateLimitExtractor defaults to DefaultRateLimitExtractor.
+	rateLimitExtractor RateLimitExtractor
+	// onRetry is nil unless set with WithRetryHook.
+	onRetry RetryFunc
+	// retryClassifier defaults to DefaultRetryClassifier.
+	retryClassifier RetryClassifier
+	// decodeHooks are added with WithDecodeHooks.
+	decodeHooks []DecodeHook
+	// onShape and shapeErrors are set with WithResponseShapeWarnings and
+	// WithResponseShapeErrors.
+	onShape     ShapeFunc
+	shapeErrors bool
+	// onResponseWarning is nil unless set with WithResponseWarnings.
+	onResponseWarning ResponseWarningFunc
+	// cache is nil unless set with WithResponseCache.
+	cache *responseCache
+	// normalized is nil unless set with WithNormalizedCache.
+	normalized *NormalizedCache
+	// flights is nil unless enabled with WithSingleFlight.
+	flights *flightGroup
+	// hedgeDelay is zero unless hedging was enabled with WithHedging.
+	hedgeDelay time.Duration
+	// maxResponseBytes is zero unless a limit was set with WithMaxResponseBytes.
+	maxResponseBytes int64
+	// auth is nil unless credentials were set with WithAuth or one of the
+	// other auth options.
+	auth AuthProvider
+	// signer is nil unless set with WithRequestSigner.
+	signer RequestSigner
+	// jar is nil unless set with WithCookieJar or UseCookieJar.
+	jar http.CookieJar
+	// csrf is nil unless enabled with WithCSRFToken.
+	csrf *csrfTokens
+	// clientCertificate is nil unless set with WithClientCertificate.
+	clientCertificate CertificateLoader
+	// beforeRequest and afterResponse are the interceptors set with
+	// WithBeforeRequest and WithAfterResponse.
+	beforeRequest []BeforeRequestFunc
+	afterResponse []AfterResponseFunc
+	// onTimings is nil unless set with WithRequestTimings.
+	onTimings RequestTimingsFunc
+	// logCurl is set by WithCurlLogging.
+	logCurl bool
+	// redaction is nil unless set with WithRedaction.
+	redaction *RedactionPolicy
+	// requestIDHeader is empty unless request IDs were enabled with
+	// WithRequestID.
+	requestIDHeader string
+	// middleware wraps httpClient, see WithMiddleware.
+	middleware []Middleware
+	// github is only set by NewGitHubClient.
+	github *gitHub
+
 	// closeReq will close the request body immediately allowing for reuse of client
 	closeReq bool
 
+	stats clientStats
+
+	// logger is nil unless set with WithLogger.
+	logger Logger
+	// tracer is nil unless set with WithTracer.
+	tracer Tracer
+	// metrics is nil unless set with WithMetrics.
+	metrics MetricsCollector
+	// audit is nil unless set with WithAuditSink.
+	audit AuditSink
+
+	// defaultVars are set with WithDefaultVars.
+	defaultVars map[string]interface{}
+	// fragments are registered with WithFragments, by name.
+	fragments    map[string]definedFragment
+	fragmentsErr error
+	// uploadProgress is nil unless set with WithUploadProgress.
+	uploadProgress UploadProgressFunc
+	// minifyQueries is set with WithQueryMinification.
+	minifyQueries bool
+	// schema is nil unless set with WithSchema.
+	schema *Schema
+	// onDeprecation is nil unless set with WithDeprecationWarnings.
+	onDeprecation DeprecationFunc
+	// capture is nil unless set with WithCapture.
+	capture CaptureFunc
+	// debugBuffer is nil unless set with WithDebugBuffer.
+	debugBuffer *DebugBuffer
+	// debugLog is nil unless set with WithDebugLog.
+	debugLog *DebugLogConfig
+
 	// Log is called with various debug information.
 	// To log to standard out, use:
 	//  client.Log = func(s string) { log.Println(s) }
+	// Use WithDebugLog to choose which messages are logged.
+	// For structured logs of every operation, use WithLogger.
 	Log func(s string)
 }
 
 // NewClient makes a new Client capable of making GraphQL requests.
 func NewClient(url string, opts ...ClientOption) *Client {
 	c := &Client{
-		url: url,
-		Log: func(string) {},
+		url:                url,
+		Log:                func(string) {},
+		rateLimitExtractor: DefaultRateLimitExtractor,
 	}
 	for _, optionFunc := range opts {
 		optionFunc(c)
@@ -49,30 +160,143 @@ func NewClient(url string, opts ...ClientOption) *Client {
 	if c.httpClient == nil {
 		c.httpClient = http.DefaultClient
 	}
+	if c.clientCertificate != nil {
+		c.httpClient = withClientCertificate(c.httpClient, c.clientCertificate)
+	}
+	c.httpClient = chain(c.httpClient, c.middleware)
 
 	return c
 }
 
+// OperationLabelHeader is the header carrying the label set with
+// GraphRequest.SetLabel.
+const OperationLabelHeader = "X-GraphQL-Operation"
+
 const messageCodeNotOK = "graphql: server returned a non-200 status code: %v"
 
+// Runner runs GraphQL requests. It is implemented by Client, and by the
+// fake of the graphqltest package for code depending on a Runner to be
+// tested without a server.
+type Runner interface {
+	Run(ctx context.Context, req *GraphRequest, graphqlResponse interface{}) (*GraphResponse, error)
+}
+
+var _ Runner = (*Client)(nil)
+
 func (c *Client) Run(ctx context.Context, req *GraphRequest, graphqlResponse interface{}) (*GraphResponse, error) {
+	if err := checkTarget(graphqlResponse); err != nil {
+		return nil, err
+	}
+	return c.execute(ctx, req, graphqlResponse, nil)
+}
+
+// execute runs req, decoding its data into graphqlResponse, or streaming
+// its body into stream if set.
+func (c *Client) execute(ctx context.Context, req *GraphRequest, graphqlResponse interface{}, stream *responseStream) (*GraphResponse, error) {
+	ctx, requestID := c.requestID(ctx)
+	req, err := c.interceptRequest(ctx, c.withDefaultVars(req))
+	if err != nil {
+		return nil, err
+	}
+	if req, err = c.withFragments(req); err != nil {
+		return nil, err
+	}
+	deprecations, err := c.validate(req)
+	if err != nil {
+		return nil, err
+	}
+	req = c.withMinifiedQuery(req)
+	op := newOperation(req)
+	op.requestID = requestID
+	op.stream = stream
+	op.idempotencyKey = c.idempotencyKey(op)
+	c.reportDeprecations(ctx, op, deprecations)
+	c.stats.begin()
+	ctx, finishTrace := c.startTrace(ctx, op)
+	graphResponse, err := c.runWithTimeout(ctx, op, graphqlResponse)
+	err = c.applyErrorPolicy(graphResponse, err)
+	c.reportResponseWarnings(ctx, op, graphResponse)
+	err = c.interceptResponse(ctx, req, graphResponse, err)
+	finishTrace(graphResponse, err)
+	c.logOperation(ctx, op, err)
+	c.recordMetrics(op, graphResponse, err)
+	c.auditOperation(ctx, op, graphResponse, err)
+	c.stats.end(op, err)
+	return graphResponse, err
+}
+
+// runWithTimeout runs op within the timeout of its request, if any.
+func (c *Client) runWithTimeout(ctx context.Context, op *operation, graphqlResponse interface{}) (*GraphResponse, error) {
+	if op.req.timeout <= 0 {
+		return c.run(ctx, op, graphqlResponse)
+	}
+	runCtx, cancel := context.WithTimeout(ctx, op.req.timeout)
+	defer cancel()
+	graphResponse, err := c.run(runCtx, op, graphqlResponse)
+	if err != nil && ctx.Err() == nil && errors.Is(runCtx.Err(), context.DeadlineExceeded) {
+		return graphResponse, &TimeoutError{Timeout: op.req.timeout}
+	}
+	return graphResponse, err
+}
+
+func (c *Client) run(ctx context.Context, op *operation, graphqlResponse interface{}) (*GraphResponse, error) {
 	select {
 	case <-ctx.Done():
 		return nil, ctx.Err()
 	default:
 	}
-	if len(req.files) > 0 && !c.useMultipartForm {
+	if len(op.req.files) > 0 && !c.useMultipartForm {
 		return nil, errors.New("cannot send files with PostFields option")
 	}
+	op.cacheKey = c.cacheKey(ctx, op)
+	if control := op.req.cacheControl; !op.refreshCache && !control.NoCache && !control.RefreshCache {
+		if graphResponse, ok := c.cachedResponse(ctx, op, graphqlResponse); ok {
+			return graphResponse, nil
+		}
+		if graphResponse, ok := c.normalizedResponse(op, graphqlResponse); ok {
+			return graphResponse, nil
+		}
+	}
+	if c.scheduler != nil {
+		if err := c.scheduler.acquire(ctx, op.req.priority); err != nil {
+			return nil, err
+		}
+		defer c.scheduler.release()
+	}
+	if c.github != nil {
+		if err := c.github.pace(ctx); err != nil {
+			return nil, err
+		}
+	}
+	if c.limiter != nil {
+		if err := c.limiter.take(ctx); err != nil {
+			return nil, err
+		}
+	}
 	if c.useMultipartForm {
-		return c.runWithPostFields(ctx, req, graphqlResponse)
+		return c.runWithPostFields(ctx, op, graphqlResponse)
 	}
-	return c.runWithJSON(ctx, req, graphqlResponse)
+	return c.runWithJSON(ctx, op, graphqlResponse)
 }
 
 type graphqlModel struct {
-	Query     string                 `json:"query"`
-	Variables map[string]interface{} `json:"variables"`
+	Query         string                 `json:"query"`
+	OperationName string                 `json:"operationName,omitempty"`
+	Variables     map[string]interface{} `json:"variables"`
+	Extensions    map[string]interface{} `json:"extensions,omitempty"`
+}
+
+// WithDefaultHeaders adds headers to every request. A header the request
+// sets itself replaces the default one.
+func WithDefaultHeaders(headers http.Header) ClientOption {
+	return func(client *Client) {
+		if client.headers == nil {
+			client.headers = make(http.Header, len(headers))
+		}
+		for key, values := range headers {
+			client.headers[http.CanonicalHeaderKey(key)] = append([]string(nil), values...)
+		}
+	}
 }
 
 // WithHTTPClient specifies the underlying http.Client to use when
@@ -99,6 +323,14 @@ func ImmediatelyCloseReqBody() ClientOption {
 	}
 }
 
+// WithMaxResponseBytes limits the size of the response bodies the client
+// reads. Larger responses are aborted with a *ResponseTooLargeError.
+func WithMaxResponseBytes(n int64) ClientOption {
+	return func(client *Client) {
+		client.maxResponseBytes = n
+	}
+}
+
 // ClientOption are functions that are passed into NewClient to
 // modify the behaviour of the Client.
 type ClientOption func(*Client)
@@ -106,112 +338,430 @@ type ClientOption func(*Client)
 type GraphResponse struct {
 	Data   interface{}
 	Errors []GraphErr
+	// RawData is the undecoded data of the response, kept when Run is
+	// passed nil to decode it into, e.g. to decode it later or forward it.
+	RawData json.RawMessage `json:"-"`
+	// NoData is set when the data of the response is null or missing,
+	// e.g. because of errors, so Data holds zero values rather than an
+	// empty result.
+	NoData bool `json:"-"`
+	// Extensions is the extensions object of the response, such as
+	// tracing, cost or cache hints, and RawExtensions its JSON.
+	Extensions    map[string]interface{} `json:"-"`
+	RawExtensions json.RawMessage        `json:"-"`
+	// Warnings are the warnings sent in the extensions of the response.
+	Warnings []ResponseWarning `json:"-"`
+	// FromCache is set when the response comes from the cache set with
+	// WithResponseCache.
+	FromCache bool `json:"-"`
+	// Stale is set when the response comes from the cache after its TTL
+	// expired, see CacheConfig.StaleWhileRevalidate.
+	Stale bool `json:"-"`
+	// StatusCode and Header are the status and headers of the HTTP
+	// response, e.g. to read rate limit or cache headers.
+	StatusCode int         `json:"-"`
+	Header     http.Header `json:"-"`
+	// ServerTiming holds the metrics of the Server-Timing response headers.
+	ServerTiming []ServerTiming `json:"-"`
+	// RateLimit is the rate limit status reported by the server, if any.
+	RateLimit *RateLimit `json:"-"`
+	// GitHubRateLimit is only set by clients created with NewGitHubClient.
+	GitHubRateLimit *GitHubRateLimit `json:"-"`
+	// RequestID is the ID of the request, when enabled with WithRequestID.
+	RequestID string `json:"-"`
+	// Tracing is set when the server returns the Apollo tracing extension.
+	Tracing *ApolloTracing `json:"-"`
 }
 
-func (c *Client) runWithJSON(ctx context.Context, req *GraphRequest, responseData interface{}) (*GraphResponse, error) {
-	var requestBody bytes.Buffer
-	requestBodyObj := graphqlModel{
-		Query:     req.query,
-		Variables: req.vars,
+func (c *Client) runWithJSON(ctx context.Context, op *operation, responseData interface{}) (*GraphResponse, error) {
+	req := op.req
+	vars, err := c.encodeVariables(req.vars)
+	if err != nil {
+		return nil, errors.Wrap(err, "encode variables")
 	}
-	if err := json.NewEncoder(&requestBody).Encode(requestBodyObj); err != nil {
-		return nil, errors.Wrap(err, "encode body")
+	requestBody, err := c.encodeJSONBody(req, vars)
+	if err != nil {
+		return nil, err
+	}
+	if c.logEnabled(LogLevelDebug, LogVariables) {
+		// encoded on their own so raw JSON values are logged as JSON
+		encodedVars, _ := json.Marshal(vars)
+		c.logf(op, LogLevelDebug, LogVariables, ">> variables: %s", c.redactedJSON(vars, encodedVars))
 	}
-	c.logf(">> variables: %v", req.vars)
-	c.logf(">> query: %s", req.query)
+	c.logf(op, LogLevelTrace, LogBody, ">> query: %s", c.truncateBody(req.query))
 	graphResponse := &GraphResponse{Data: responseData}
 
-	r, err := http.NewRequest(http.MethodPost, c.url, &requestBody)
+	res, buf, err := c.doShared(ctx, op, "application/json; charset=utf-8", requestBody)
 	if err != nil {
 		return nil, err
 	}
+	if notModified(op, res) {
+		return c.revalidatedResponse(ctx, op, res, responseData)
+	}
+	if res.StatusCode != http.StatusOK {
+		return nil, newHTTPError(res, buf.Bytes())
+	}
+	responseBody := buf.Bytes()
+	if err := c.decodeResponse(responseBody, graphResponse); err != nil {
+		return nil, errors.Wrap(err, "decoding response")
+	}
+	c.finishResponse(op, graphResponse, res, responseBody)
+	if err := c.checkResponseShape(ctx, op, responseBody); err != nil {
+		return graphResponse, err
+	}
+	c.cacheResponse(ctx, op, graphResponse, res, responseBody)
+	c.normalizeResponse(op, graphResponse, responseBody)
+	return graphResponse, nil
+}
 
-	r.Close = c.closeReq
-	addHTTPHeaders(r, req, "application/json; charset=utf-8")
-	c.logf(">> headers: %v", r.Header)
-	r = r.WithContext(ctx)
-	res, err := c.httpClient.Do(r)
+func (c *Client) runWithPostFields(ctx context.Context, op *operation, responseData interface{}) (*GraphResponse, error) {
+	req := op.req
+	vars, err := c.encodeVariables(req.vars)
+	if err != nil {
+		return nil, errors.Wrap(err, "encode variables")
+	}
+	variables, err := c.encodeMultipartVariables(vars)
 	if err != nil {
 		return nil, err
 	}
-	defer res.Body.Close()
-	var buf bytes.Buffer
-	if _, err := io.Copy(&buf, res.Body); err != nil {
-		return nil, errors.Wrap(err, "reading body")
+	offsets, rewindable := fileOffsets(req.files)
+	boundary := multipart.NewWriter(ioutil.Discard).Boundary()
+	c.logf(op, LogLevelDebug, LogVariables, ">> variables: %s", c.redactedJSON(vars, variables))
+	c.logf(op, LogLevelDebug, LogWire, ">> files: %d", len(req.files))
+	c.logf(op, LogLevelTrace, LogBody, ">> query: %s", c.truncateBody(req.query))
+	graphResponse := &GraphResponse{Data: responseData}
+
+	// the multipart body is streamed through a pipe so file contents are
+	// never held in memory; writing is closed once the previous body has
+	// been fully written and its files can safely be rewound.
+	var writing chan struct{}
+	defer func() {
+		if writing != nil {
+			<-writing
+		}
+		closeFiles(req.files)
+	}()
+	body := func() (io.Reader, error) {
+		if writing != nil {
+			<-writing
+		}
+		if err := rewindFiles(req.files, offsets); err != nil {
+			return nil, err
+		}
+		progress := c.newUploadTracker(req, boundary, variables)
+		pr, pw := io.Pipe()
+		writer := multipart.NewWriter(progress.body(pw))
+		if err := writer.SetBoundary(boundary); err != nil {
+			return nil, errors.Wrap(err, "set boundary")
+		}
+		writing = make(chan struct{})
+		go func(done chan struct{}) {
+			defer close(done)
+			pw.CloseWithError(writeMultipartBody(writer, req, variables, progress))
+		}(writing)
+		return pr, nil
 	}
-	c.logf("<< %s", buf.String())
-	if res.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf(messageCodeNotOK, res.StatusCode)
+	contentType := "multipart/form-data; boundary=" + boundary
+	res, buf, err := c.do(ctx, op, contentType, body, rewindable)
+	if err != nil {
+		return nil, err
 	}
-	if err := json.NewDecoder(&buf).Decode(&graphResponse); err != nil {
+	if notModified(op, res) {
+		return c.revalidatedResponse(ctx, op, res, responseData)
+	}
+	responseBody := buf.Bytes()
+	if err := c.decodeResponse(responseBody, graphResponse); err != nil {
+		if res.StatusCode != http.StatusOK {
+			return nil, newHTTPError(res, responseBody)
+		}
 		return nil, errors.Wrap(err, "decoding response")
 	}
+	c.finishResponse(op, graphResponse, res, responseBody)
+	if err := c.checkResponseShape(ctx, op, responseBody); err != nil {
+		return graphResponse, err
+	}
+	c.cacheResponse(ctx, op, graphResponse, res, responseBody)
+	c.normalizeResponse(op, graphResponse, responseBody)
 	return graphResponse, nil
 }
 
-func (c *Client) runWithPostFields(ctx context.Context, req *GraphRequest, responseData interface{}) (*GraphResponse, error) {
-	var requestBody bytes.Buffer
-	writer := multipart.NewWriter(&requestBody)
-	if err := writer.WriteField("query", req.query); err != nil {
-		return nil, errors.Wrap(err, "write query field")
+// encodeJSONBody encodes the JSON body sending req with vars, its encoded
+// variables.
+func (c *Client) encodeJSONBody(req *GraphRequest, vars map[string]interface{}) ([]byte, error) {
+	body, err := c.encode(graphqlModel{
+		Query:         req.query,
+		OperationName: req.operationName,
+		Variables:     vars,
+		Extensions:    req.extensions,
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "encode body")
 	}
-	var variablesBuf bytes.Buffer
-	if len(req.vars) > 0 {
-		variablesField, err := writer.CreateFormField("variables")
-		if err != nil {
-			return nil, errors.Wrap(err, "create variables field")
-		}
-		if err := json.NewEncoder(io.MultiWriter(variablesField, &variablesBuf)).Encode(req.vars); err != nil {
-			return nil, errors.Wrap(err, "encode variables")
+	return body, nil
+}
+
+// encodeMultipartVariables encodes vars, the encoded variables of a
+// request, as the variables field of a multipart body.
+func (c *Client) encodeMultipartVariables(vars map[string]interface{}) ([]byte, error) {
+	if len(vars) == 0 {
+		return nil, nil
+	}
+	variables, err := c.encode(vars)
+	if err != nil {
+		return nil, errors.Wrap(err, "encode variables")
+	}
+	return variables, nil
+}
+
+// finishResponse fills in the parts of graphResponse that come from the
+// HTTP response rather than from the decoded body.
+func (c *Client) finishResponse(op *operation, graphResponse *GraphResponse, res *http.Response, body []byte) {
+	graphResponse.StatusCode = res.StatusCode
+	graphResponse.Header = res.Header
+	graphResponse.RequestID = op.requestID
+	graphResponse.ServerTiming = parseServerTiming(res.Header)
+	graphResponse.Tracing = parseApolloTracing(body)
+	graphResponse.RawExtensions, graphResponse.Extensions = c.decodeExtensions(body)
+	graphResponse.Warnings = parseResponseWarnings(graphResponse.Extensions)
+	if c.rateLimitExtractor != nil {
+		graphResponse.RateLimit = c.rateLimitExtractor(graphResponse)
+	}
+	if c.github != nil {
+		graphResponse.GitHubRateLimit = parseGitHubRateLimit(res.Header, body)
+	}
+}
+
+func writeMultipartBody(writer *multipart.Writer, req *GraphRequest, variables []byte, progress *uploadTracker) error {
+	fields, err := multipartFields(req, variables)
+	if err != nil {
+		return err
+	}
+	for _, field := range fields {
+		if err := writer.WriteField(field.name, field.value); err != nil {
+			return errors.Wrapf(err, "write %s field", field.name)
 		}
 	}
 	for i := range req.files {
-		part, err := writer.CreateFormFile(req.files[i].Field, req.files[i].Name)
+		part, err := createFilePart(writer, i, req.files[i])
 		if err != nil {
-			return nil, errors.Wrap(err, "create form file")
+			return errors.Wrap(err, "create form file")
 		}
-		if _, err := io.Copy(part, req.files[i].R); err != nil {
-			return nil, errors.Wrap(err, "preparing file")
+		if _, err := io.Copy(part, progress.file(i, req.files[i])); err != nil {
+			return errors.Wrap(err, "preparing file")
 		}
 	}
 	if err := writer.Close(); err != nil {
-		return nil, errors.Wrap(err, "close writer")
+		return errors.Wrap(err, "close writer")
 	}
-	c.logf(">> variables: %s", variablesBuf.String())
-	c.logf(">> files: %d", len(req.files))
-	c.logf(">> query: %s", req.query)
-	graphResponse := &GraphResponse{Data: responseData}
-	r, err := http.NewRequest(http.MethodPost, c.url, &requestBody)
+	return nil
+}
+
+// do sends the request body to the server, retrying transient failures
+// according to the client's RetryPolicy, and returns the response along
+// with its fully read body.
+func (c *Client) do(ctx context.Context, op *operation, contentType string,
+	body func() (io.Reader, error), rewindable bool) (*http.Response, *bytes.Buffer, error) {
+	maxAttempts := 1
+	if c.retry != nil && rewindable && c.retry.MaxAttempts > 1 {
+		maxAttempts = c.retry.MaxAttempts
+	}
+	for attempt := 1; ; attempt++ {
+		// checked before the body is built, since multipart bodies start
+		// writing to a pipe that only sending the request drains
+		if c.breaker != nil {
+			if err := c.breaker.allow(); err != nil {
+				return nil, nil, err
+			}
+		}
+		reader, err := body()
+		if err != nil {
+			if c.breaker != nil {
+				c.breaker.release()
+			}
+			return nil, nil, err
+		}
+		attemptCtx, cancel := c.retry.attemptContext(ctx)
+		res, buf, err := c.send(attemptCtx, op, contentType, reader, body)
+		cancel()
+		var unsent *unsentError
+		if errors.As(err, &unsent) {
+			err = unsent.err
+		}
+		op.attempts = attempt
+		if res != nil {
+			op.statusCode, op.responseBytes = res.StatusCode, buf.Len()
+		}
+		if c.breaker != nil {
+			if unsent != nil {
+				// nothing reached the endpoint, which says nothing about it
+				c.breaker.release()
+			} else {
+				c.breaker.done(ctx, res, err)
+			}
+		}
+		if attempt >= maxAttempts || op.stream != nil && op.stream.written || !c.shouldRetry(ctx, res, buf, err) {
+			return res, buf, err
+		}
+		delay, retryAfter, ok := c.retry.delay(attempt, res)
+		if !ok {
+			c.logf(op, LogLevelInfo, LogWire, ">> not retrying: Retry-After exceeds %s", c.retry.MaxRetryAfter)
+			return res, buf, err
+		}
+		c.logf(op, LogLevelInfo, LogWire, ">> retrying in %s (attempt %d of %d)", delay, attempt+1, maxAttempts)
+		if c.onRetry != nil {
+			retry := Retry{Attempt: attempt, Delay: delay, RetryAfter: retryAfter, Err: err}
+			if res != nil {
+				retry.StatusCode = res.StatusCode
+			}
+			c.onRetry(ctx, c.operationInfo(op), retry)
+		}
+		if err := sleepContext(ctx, delay); err != nil {
+			return nil, nil, err
+		}
+	}
+}
+
+func (c *Client) attempt(ctx context.Context, op *operation, contentType string, body io.Reader) (*http.Response, *bytes.Buffer, error) {
+	if closer, ok := body.(io.Closer); ok {
+		// unblocks streaming body writers if the request is never sent or
+		// fails before the body was fully read
+		defer closer.Close()
+	}
+	body, sent := c.captureBody(body)
+	r, err := c.newHTTPRequest(ctx, op, contentType, &countingReader{r: body, n: &op.requestBytes})
 	if err != nil {
-		return nil, err
+		return nil, nil, &unsentError{err: err}
+	}
+	c.logf(op, LogLevelDebug, LogHeaders, ">> headers: %v", c.redaction.redactHeaders(r.Header))
+	if c.logCurl {
+		if cmd, err := c.curlCommand(op, r); err == nil {
+			c.logf(op, LogLevelDebug, LogWire, ">> curl: %s", cmd)
+		}
+	}
+	var timings *timingsTracker
+	if c.onTimings != nil {
+		timings = newTimingsTracker()
+		defer c.reportTimings(ctx, op, timings)
+		r = r.WithContext(httptrace.WithClientTrace(ctx, timings.clientTrace()))
+	} else {
+		r = r.WithContext(ctx)
 	}
-	r.Close = c.closeReq
-	addHTTPHeaders(r, req, writer.FormDataContentType())
-	c.logf(">> headers: %v", r.Header)
-	r = r.WithContext(ctx)
 	res, err := c.httpClient.Do(r)
 	if err != nil {
-		return nil, err
+		c.captureExchange(ctx, op, r, sent, nil, nil, err)
+		c.recordDebug(op, r, nil, nil, err)
+		return nil, nil, err
 	}
 	defer res.Body.Close()
+	c.storeCookies(r, res)
+	if c.csrf != nil && res.StatusCode == http.StatusForbidden {
+		c.csrf.invalidate(r)
+	}
+	timings.readingBody()
+	var buf *bytes.Buffer
+	if op.stream != nil && res.StatusCode == http.StatusOK {
+		if buf, err = op.stream.copy(res.Body); err != nil {
+			err = errors.Wrap(err, "streaming body")
+		}
+	} else {
+		buf, err = c.readBody(res)
+	}
+	c.captureExchange(ctx, op, r, sent, res, buf, err)
+	c.recordDebug(op, r, res, buf, err)
+	if err != nil {
+		return nil, nil, err
+	}
+	if c.logEnabled(LogLevelTrace, LogBody) {
+		c.logf(op, LogLevelTrace, LogBody, "<< %s", c.truncateBody(buf.String()))
+	}
+	c.observeResponse(op.req, r, res, buf.Bytes())
+	if c.github != nil {
+		c.github.observe(res, buf.Bytes())
+	}
+	return res, buf, nil
+}
+
+// readBody reads the body of res, up to the client's size limit.
+func (c *Client) readBody(res *http.Response) (*bytes.Buffer, error) {
+	var responseBody io.Reader = res.Body
+	if c.maxResponseBytes > 0 {
+		responseBody = io.LimitReader(res.Body, c.maxResponseBytes+1)
+	}
 	var buf bytes.Buffer
-	if _, err := io.Copy(&buf, res.Body); err != nil {
+	if _, err := io.Copy(&buf, responseBody); err != nil {
 		return nil, errors.Wrap(err, "reading body")
 	}
-	c.logf("<< %s", buf.String())
-	if err := json.NewDecoder(&buf).Decode(&graphResponse); err != nil {
-		if res.StatusCode != http.StatusOK {
-			return nil, fmt.Errorf(messageCodeNotOK, res.StatusCode)
+	if c.maxResponseBytes > 0 && int64(buf.Len()) > c.maxResponseBytes {
+		return nil, &ResponseTooLargeError{Limit: c.maxResponseBytes}
+	}
+	return &buf, nil
+}
+
+// newHTTPRequest builds the HTTP request of a single attempt, with its
+// headers, credentials and cookies.
+func (c *Client) newHTTPRequest(ctx context.Context, op *operation, contentType string, body io.Reader) (*http.Request, error) {
+	req := op.req
+	var payload []byte
+	if c.signer != nil {
+		var err error
+		if payload, err = ioutil.ReadAll(body); err != nil {
+			return nil, errors.Wrap(err, "reading request body")
 		}
-		return nil, errors.Wrap(err, "decoding response")
+		body = bytes.NewReader(payload)
 	}
-	return graphResponse, nil
+	r, err := http.NewRequest(http.MethodPost, c.url, body)
+	if err != nil {
+		return nil, err
+	}
+	r.Close = c.closeReq
+	addHTTPHeaders(r, c.headers, req, contentType)
+	if c.requestIDHeader != "" {
+		r.Header.Set(c.requestIDHeader, op.requestID)
+	}
+	if req.label != "" {
+		r.Header.Set(OperationLabelHeader, req.label)
+	}
+	if c.priorityHeader != "" {
+		r.Header.Set(c.priorityHeader, req.priority.String())
+	}
+	if op.idempotencyKey != "" {
+		r.Header.Set(c.idempotencyHeader(), op.idempotencyKey)
+	}
+	if op.revalidate != nil {
+		r.Header.Set("If-None-Match", op.revalidate.Header.Get("ETag"))
+	}
+	if !op.dryRun {
+		if err := c.authorize(ctx, req, r); err != nil {
+			return nil, err
+		}
+	}
+	c.addCookies(r)
+	if c.tracer != nil {
+		c.tracer.InjectHeaders(ctx, r.Header)
+	}
+	if c.csrf != nil && !op.dryRun {
+		if err := c.csrf.apply(ctx, c, r); err != nil {
+			return nil, err
+		}
+	}
+	if c.signer != nil {
+		if err := c.signer(r, payload); err != nil {
+			return nil, errors.Wrap(err, "sign request")
+		}
+	}
+	return r, nil
 }
 
-func addHTTPHeaders(httpRequest *http.Request, req *GraphRequest, contentType string) {
+func addHTTPHeaders(httpRequest *http.Request, defaults http.Header, req *GraphRequest, contentType string) {
 	httpRequest.Header.Set("Content-Type", contentType)
 	httpRequest.Header.Set("Accept", "application/json; charset=utf-8")
+	for key, values := range defaults {
+		httpRequest.Header[http.CanonicalHeaderKey(key)] = append([]string(nil), values...)
+	}
 	for key, values := range req.Header {
+		if _, ok := defaults[http.CanonicalHeaderKey(key)]; ok {
+			httpRequest.Header.Del(key)
+		}
 		for _, value := range values {
 			httpRequest.Header.Add(key, value)
 		}
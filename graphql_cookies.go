@@ -0,0 +1,42 @@
+package graphql
+
+import (
+	"net/http"
+	"net/http/cookiejar"
+)
+
+// WithCookieJar sends the cookies of jar with every request and stores the
+// cookies set by the server in it, so session-cookie authenticated servers
+// work across Run calls. Don't combine it with an http.Client that has its
+// own Jar.
+func WithCookieJar(jar http.CookieJar) ClientOption {
+	return func(client *Client) {
+		client.jar = jar
+	}
+}
+
+// UseCookieJar is like WithCookieJar with an internal in-memory jar.
+func UseCookieJar() ClientOption {
+	return func(client *Client) {
+		// cookiejar.New never fails without options
+		client.jar, _ = cookiejar.New(nil)
+	}
+}
+
+func (c *Client) addCookies(r *http.Request) {
+	if c.jar == nil {
+		return
+	}
+	for _, cookie := range c.jar.Cookies(r.URL) {
+		r.AddCookie(cookie)
+	}
+}
+
+func (c *Client) storeCookies(r *http.Request, res *http.Response) {
+	if c.jar == nil {
+		return
+	}
+	if cookies := res.Cookies(); len(cookies) > 0 {
+		c.jar.SetCookies(r.URL, cookies)
+	}
+}
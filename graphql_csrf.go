@@ -0,0 +1,132 @@
+package graphql
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/pkg/errors"
+)
+
+// CSRFConfig configures how the client obtains the CSRF token it sends
+// with every request.
+type CSRFConfig struct {
+	// Header is the request header the token is sent in, e.g. X-CSRF-Token.
+	Header string
+	// URL, if set, is fetched with a GET request before the first
+	// GraphQL request to obtain the token. The token is read from the
+	// CookieName cookie of the response or, if CookieName is empty, from
+	// the response body.
+	URL string
+	// CookieName is the name of the cookie holding the token. Without a
+	// URL, the token is read from the client's cookie jar.
+	CookieName string
+	// Fetch, if set, is used to obtain the token instead of URL and
+	// CookieName.
+	Fetch func(ctx context.Context) (string, error)
+}
+
+// WithCSRFToken attaches a CSRF token to every request, fetching it first
+// as described by config, and fetching it again after a 403 response.
+// Combine it with UseCookieJar so the CSRF cookie is sent back too.
+//
+//	NewClient(url, UseCookieJar(), WithCSRFToken(CSRFConfig{
+//		Header:     "X-CSRFToken",
+//		URL:        "https://api.test/csrf",
+//		CookieName: "csrftoken",
+//	}))
+func WithCSRFToken(config CSRFConfig) ClientOption {
+	return func(client *Client) {
+		client.csrf = &csrfTokens{config: config}
+	}
+}
+
+type csrfTokens struct {
+	config CSRFConfig
+
+	mu    sync.Mutex
+	token string
+}
+
+// apply sets the CSRF header of r, fetching a token if there is none yet.
+func (t *csrfTokens) apply(ctx context.Context, c *Client, r *http.Request) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.token == "" {
+		token, err := t.fetch(ctx, c, r)
+		if err != nil {
+			return errors.Wrap(err, "fetch CSRF token")
+		}
+		t.token = token
+		if t.config.URL != "" {
+			// the pre-flight may have set cookies the request must carry
+			r.Header.Del("Cookie")
+			c.addCookies(r)
+		}
+	}
+	r.Header.Set(t.config.Header, t.token)
+	return nil
+}
+
+// invalidate drops the token sent with r so the next request fetches a
+// fresh one.
+func (t *csrfTokens) invalidate(r *http.Request) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if r.Header.Get(t.config.Header) == t.token {
+		t.token = ""
+	}
+}
+
+func (t *csrfTokens) fetch(ctx context.Context, c *Client, r *http.Request) (string, error) {
+	switch {
+	case t.config.Fetch != nil:
+		return t.config.Fetch(ctx)
+	case t.config.URL != "":
+		return t.preflight(ctx, c)
+	case c.jar != nil && t.config.CookieName != "":
+		for _, cookie := range c.jar.Cookies(r.URL) {
+			if cookie.Name == t.config.CookieName {
+				return cookie.Value, nil
+			}
+		}
+		return "", fmt.Errorf("graphql: no %s cookie", t.config.CookieName)
+	}
+	return "", errors.New("graphql: CSRFConfig needs a Fetch function, a URL or a CookieName and a cookie jar")
+}
+
+// preflight fetches config.URL and reads the token from its response.
+func (t *csrfTokens) preflight(ctx context.Context, c *Client) (string, error) {
+	r, err := http.NewRequest(http.MethodGet, t.config.URL, nil)
+	if err != nil {
+		return "", err
+	}
+	c.addCookies(r)
+	res, err := c.httpClient.Do(r.WithContext(ctx))
+	if err != nil {
+		return "", err
+	}
+	defer res.Body.Close()
+	c.storeCookies(r, res)
+	if res.StatusCode != http.StatusOK {
+		body, _ := ioutil.ReadAll(io.LimitReader(res.Body, maxHTTPErrorBody))
+		return "", newHTTPError(res, body)
+	}
+	if t.config.CookieName != "" {
+		for _, cookie := range res.Cookies() {
+			if cookie.Name == t.config.CookieName {
+				return cookie.Value, nil
+			}
+		}
+		return "", fmt.Errorf("graphql: no %s cookie", t.config.CookieName)
+	}
+	body, err := ioutil.ReadAll(res.Body)
+	if err != nil {
+		return "", errors.Wrap(err, "reading body")
+	}
+	return strings.TrimSpace(string(body)), nil
+}
@@ -0,0 +1,124 @@
+package graphql
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// WithCurlLogging passes every outgoing request to Client.Log as an
+// equivalent curl command, redacted according to the RedactionPolicy.
+func WithCurlLogging() ClientOption {
+	return func(client *Client) {
+		client.logCurl = true
+	}
+}
+
+// Curl returns a curl command equivalent to sending req with the client,
+// so a failing call can be reproduced outside the application. Sensitive
+// data is redacted according to the client's RedactionPolicy.
+//
+// Curl sends nothing: the credentials of AuthProviders and CSRF tokens,
+// which may take requests to obtain, are left out of the command. Curl
+// rebuilds multipart bodies with its own boundary, so a RequestSigner
+// only signs JSON bodies.
+func (c *Client) Curl(ctx context.Context, req *GraphRequest) (string, error) {
+	req, err := c.withFragments(c.withDefaultVars(req))
+	if err != nil {
+		return "", err
+	}
+	op := newOperation(c.withMinifiedQuery(req))
+	op.idempotencyKey = req.idempotencyKey
+	op.dryRun = true
+	var body io.Reader = http.NoBody
+	if !c.useMultipartForm {
+		vars, err := c.encodeVariables(req.vars)
+		if err != nil {
+			return "", errors.Wrap(err, "encode variables")
+		}
+		payload, err := c.encodeJSONBody(req, vars)
+		if err != nil {
+			return "", err
+		}
+		body = bytes.NewReader(payload)
+	}
+	r, err := c.newHTTPRequest(ctx, op, c.contentType(), body)
+	if err != nil {
+		return "", err
+	}
+	return c.curlCommand(op, r)
+}
+
+func (c *Client) contentType() string {
+	if c.useMultipartForm {
+		return "multipart/form-data"
+	}
+	return "application/json; charset=utf-8"
+}
+
+// curlCommand renders r, whose body is built from op.req, as a curl command.
+func (c *Client) curlCommand(op *operation, r *http.Request) (string, error) {
+	req := op.req
+	vars, err := c.encodeVariables(req.vars)
+	if err != nil {
+		return "", errors.Wrap(err, "encode variables")
+	}
+	vars = c.redaction.redactVariables(vars)
+	header := c.redaction.redactHeaders(r.Header)
+	var cmd strings.Builder
+	cmd.WriteString("curl -X POST " + shellQuote(r.URL.String()))
+	names := make([]string, 0, len(header))
+	for name := range header {
+		if c.useMultipartForm && name == "Content-Type" {
+			// curl sets it, along with the boundary, for -F and --form-string
+			continue
+		}
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		for _, value := range header[name] {
+			cmd.WriteString(" -H " + shellQuote(name+": "+value))
+		}
+	}
+	if !c.useMultipartForm {
+		body, err := c.encodeJSONBody(req, vars)
+		if err != nil {
+			return "", err
+		}
+		cmd.WriteString(" --data-raw " + shellQuote(strings.TrimSuffix(string(body), "\n")))
+		return c.redaction.redactText(cmd.String()), nil
+	}
+	encoded, err := c.encodeMultipartVariables(vars)
+	if err != nil {
+		return "", err
+	}
+	encoded = bytes.TrimSuffix(encoded, []byte("\n"))
+	fields, err := multipartFields(req, encoded)
+	if err != nil {
+		return "", err
+	}
+	for _, field := range fields {
+		// unlike -F, --form-string never reads values starting with @ or <
+		// from files
+		cmd.WriteString(" --form-string " + shellQuote(field.name+"="+field.value))
+	}
+	for i := range req.files {
+		field := fileFieldName(i, req.files[i]) + "=@" + req.files[i].Name
+		if req.files[i].ContentType != "" {
+			field += ";type=" + req.files[i].ContentType
+		}
+		cmd.WriteString(" -F " + shellQuote(field))
+	}
+	return c.redaction.redactText(cmd.String()), nil
+}
+
+// shellQuote quotes s for a POSIX shell.
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
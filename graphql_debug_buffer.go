@@ -0,0 +1,121 @@
+package graphql
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// DebugRecord is a redacted HTTP request sent by the Client and its
+// response, as kept by a DebugBuffer.
+type DebugRecord struct {
+	Time          time.Time              `json:"time"`
+	OperationName string                 `json:"operationName,omitempty"`
+	RequestID     string                 `json:"requestId,omitempty"`
+	Label         string                 `json:"label,omitempty"`
+	Endpoint      string                 `json:"endpoint"`
+	Query         string                 `json:"query"`
+	Variables     map[string]interface{} `json:"variables,omitempty"`
+	RequestHeader http.Header            `json:"requestHeader"`
+	// Duration is the time elapsed since the operation started, including
+	// previous attempts.
+	Duration       time.Duration `json:"duration"`
+	StatusCode     int           `json:"statusCode,omitempty"`
+	ResponseHeader http.Header   `json:"responseHeader,omitempty"`
+	ResponseBody   string        `json:"responseBody,omitempty"`
+	Error          string        `json:"error,omitempty"`
+}
+
+// DebugBuffer keeps the most recent requests of the clients it is passed
+// to with WithDebugBuffer. It is an http.Handler serving them as JSON,
+// newest first, e.g. for an internal debug page.
+//
+//	buffer := NewDebugBuffer(100)
+//	client := NewClient(url, WithDebugBuffer(buffer), WithRedaction(DefaultRedactionPolicy()))
+//	http.Handle("/debug/graphql", buffer)
+type DebugBuffer struct {
+	mu      sync.Mutex
+	records []DebugRecord
+	next    int
+	full    bool
+}
+
+// NewDebugBuffer returns a DebugBuffer keeping the last size requests.
+func NewDebugBuffer(size int) *DebugBuffer {
+	if size < 1 {
+		size = 1
+	}
+	return &DebugBuffer{records: make([]DebugRecord, size)}
+}
+
+// WithDebugBuffer keeps every HTTP request and response in buffer, with
+// the client's redaction policy applied and bodies truncated to
+// DebugLogConfig.MaxBodyBytes.
+func WithDebugBuffer(buffer *DebugBuffer) ClientOption {
+	return func(client *Client) {
+		client.debugBuffer = buffer
+	}
+}
+
+// Records returns the requests in the buffer, newest first.
+func (b *DebugBuffer) Records() []DebugRecord {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	n := b.next
+	if b.full {
+		n = len(b.records)
+	}
+	records := make([]DebugRecord, 0, n)
+	for i := 1; i <= n; i++ {
+		records = append(records, b.records[(b.next-i+len(b.records))%len(b.records)])
+	}
+	return records
+}
+
+// ServeHTTP writes the records in the buffer as a JSON array.
+func (b *DebugBuffer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	_ = encoder.Encode(b.Records())
+}
+
+func (b *DebugBuffer) add(record DebugRecord) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.records[b.next] = record
+	b.next = (b.next + 1) % len(b.records)
+	if b.next == 0 {
+		b.full = true
+	}
+}
+
+func (c *Client) recordDebug(op *operation, r *http.Request, res *http.Response, buf *bytes.Buffer, err error) {
+	if c.debugBuffer == nil {
+		return
+	}
+	record := DebugRecord{
+		Time:          time.Now(),
+		OperationName: op.name,
+		RequestID:     op.requestID,
+		Label:         op.req.label,
+		Endpoint:      c.url,
+		Query:         c.redaction.redactText(op.req.query),
+		Variables:     c.redaction.redactVariables(op.req.vars),
+		RequestHeader: c.redaction.redactHeaders(r.Header).Clone(),
+		Duration:      time.Since(op.start),
+	}
+	if res != nil {
+		record.StatusCode = res.StatusCode
+		record.ResponseHeader = c.redaction.redactHeaders(res.Header).Clone()
+	}
+	if buf != nil {
+		record.ResponseBody = c.redaction.redactText(c.truncateBody(buf.String()))
+	}
+	if err != nil {
+		record.Error = c.redaction.redactText(err.Error())
+	}
+	c.debugBuffer.add(record)
+}
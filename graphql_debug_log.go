@@ -0,0 +1,101 @@
+package graphql
+
+import (
+	"fmt"
+	"unicode/utf8"
+)
+
+// LogLevel is the verbosity of a message passed to Client.Log.
+type LogLevel int
+
+const (
+	// LogLevelInfo covers the lifecycle of a request, e.g. retries.
+	LogLevelInfo LogLevel = iota
+	// LogLevelDebug covers request metadata such as headers, variables and
+	// timings.
+	LogLevelDebug
+	// LogLevelTrace covers queries and response bodies.
+	LogLevelTrace
+)
+
+// LogCategory is a set of kinds of messages passed to Client.Log.
+type LogCategory uint
+
+const (
+	// LogWire covers retries, hedging, timings and curl commands.
+	LogWire LogCategory = 1 << iota
+	// LogHeaders covers request headers.
+	LogHeaders
+	// LogVariables covers request variables.
+	LogVariables
+	// LogBody covers queries and response bodies.
+	LogBody
+	// LogDeprecations covers deprecated schema elements used by queries,
+	// and warnings sent with responses.
+	LogDeprecations
+	// LogResponseShape covers differences between responses and the schema.
+	LogResponseShape
+
+	// LogAllCategories covers every kind of message.
+	LogAllCategories = LogWire | LogHeaders | LogVariables | LogBody | LogDeprecations | LogResponseShape
+)
+
+// DebugLogConfig selects the messages passed to Client.Log.
+type DebugLogConfig struct {
+	// Level is the most verbose level logged.
+	Level LogLevel
+	// Categories is the set of categories logged. Zero means all of them.
+	Categories LogCategory
+	// MaxBodyBytes truncates queries and response bodies longer than
+	// this. Zero means no limit.
+	MaxBodyBytes int
+}
+
+// WithDebugLog restricts the messages passed to Client.Log. Without it,
+// every message is logged in full.
+//
+//	NewClient(url, WithDebugLog(DebugLogConfig{
+//		Level:        LogLevelTrace,
+//		Categories:   LogWire | LogBody,
+//		MaxBodyBytes: 4 << 10,
+//	}))
+func WithDebugLog(config DebugLogConfig) ClientOption {
+	return func(client *Client) {
+		client.debugLog = &config
+	}
+}
+
+// logEnabled reports whether messages of level and category are logged.
+func (c *Client) logEnabled(level LogLevel, category LogCategory) bool {
+	if c.debugLog == nil {
+		return true
+	}
+	categories := c.debugLog.Categories
+	if categories == 0 {
+		categories = LogAllCategories
+	}
+	return level <= c.debugLog.Level && categories&category != 0
+}
+
+func (c *Client) logf(op *operation, level LogLevel, category LogCategory, format string, args ...interface{}) {
+	if !c.logEnabled(level, category) {
+		return
+	}
+	if op.requestID != "" {
+		format = "[" + op.requestID + "] " + format
+	}
+	c.Log(c.redaction.redactText(fmt.Sprintf(format, args...)))
+}
+
+// truncateBody shortens s to the configured MaxBodyBytes, without splitting
+// a UTF-8 sequence.
+func (c *Client) truncateBody(s string) string {
+	if c.debugLog == nil || c.debugLog.MaxBodyBytes <= 0 || len(s) <= c.debugLog.MaxBodyBytes {
+		return s
+	}
+	n := c.debugLog.MaxBodyBytes
+	for n > 0 && !utf8.RuneStart(s[n]) {
+		n--
+	}
+	return fmt.Sprintf("%s... (%d bytes truncated)", s[:n], len(s)-n)
+}
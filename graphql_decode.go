@@ -0,0 +1,168 @@
+package graphql
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"reflect"
+)
+
+// Codec encodes request bodies and decodes responses, e.g. to use a faster
+// JSON library than encoding/json. It must honor json struct tags and
+// json.Marshaler, json.Unmarshaler and json.RawMessage values.
+type Codec interface {
+	Marshal(v interface{}) ([]byte, error)
+	Unmarshal(data []byte, v interface{}) error
+}
+
+// WithCodec encodes the bodies of requests and decodes responses with
+// codec instead of encoding/json. WithStrictDecoding and WithUseNumber
+// have no effect with a codec: configure the codec itself instead.
+//
+//	NewClient(url, WithCodec(jsoniter.ConfigCompatibleWithStandardLibrary))
+func WithCodec(codec Codec) ClientOption {
+	return func(client *Client) {
+		client.codec = codec
+	}
+}
+
+// encode encodes v with the codec of the client.
+func (c *Client) encode(v interface{}) ([]byte, error) {
+	if c.codec != nil {
+		return c.codec.Marshal(v)
+	}
+	var buf bytes.Buffer
+	err := json.NewEncoder(&buf).Encode(v)
+	return buf.Bytes(), err
+}
+
+// WithStrictDecoding makes Run fail when the data of a response has fields
+// the value passed to Run to decode it into doesn't, so drift between the
+// schema and Go types fails loudly instead of silently dropping fields.
+// With Targets, the fields decoded into a target are checked, and fields
+// without one are still ignored.
+func WithStrictDecoding() ClientOption {
+	return func(client *Client) {
+		client.strictDecoding = true
+	}
+}
+
+// WithUseNumber decodes numbers of responses held in interface{} values,
+// such as map[string]interface{} data or error extensions, as json.Number
+// instead of float64, so 64-bit IDs and amounts keep their precision.
+// Struct fields of type json.Number keep their precision without it.
+func WithUseNumber() ClientOption {
+	return func(client *Client) {
+		client.useNumber = true
+	}
+}
+
+func (c *Client) newDecoder(data []byte) *json.Decoder {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	if c.useNumber {
+		decoder.UseNumber()
+	}
+	return decoder
+}
+
+// decodeExtensions returns the extensions object of the response body,
+// undecoded and decoded. Both are nil if it has none.
+func (c *Client) decodeExtensions(body []byte) (json.RawMessage, map[string]interface{}) {
+	var payload struct {
+		Extensions json.RawMessage `json:"extensions"`
+	}
+	if err := json.Unmarshal(body, &payload); err != nil || len(payload.Extensions) == 0 {
+		return nil, nil
+	}
+	var extensions map[string]interface{}
+	if err := c.newDecoder(payload.Extensions).Decode(&extensions); err != nil || extensions == nil {
+		return nil, nil
+	}
+	return payload.Extensions, extensions
+}
+
+// checkTarget checks v can hold the data of a response: it must be nil, to
+// keep the data undecoded, or a non-nil pointer.
+func checkTarget(v interface{}) error {
+	if v == nil {
+		return nil
+	}
+	rv := reflect.ValueOf(v)
+	switch {
+	case rv.Kind() != reflect.Ptr:
+		return fmt.Errorf("graphql: cannot decode data into a %T: pass a pointer, such as &v", v)
+	case rv.IsNil():
+		return fmt.Errorf("graphql: cannot decode data into a nil %T", v)
+	}
+	return nil
+}
+
+// rawEnvelope is a response with its data left undecoded.
+type rawEnvelope struct {
+	Data   json.RawMessage
+	Errors []GraphErr
+}
+
+// decodeResponse decodes body into graphResponse, and its data into
+// graphResponse.Data, or into graphResponse.RawData if Data is nil.
+func (c *Client) decodeResponse(body []byte, graphResponse *GraphResponse) error {
+	strict := c.strictDecoding && c.codec == nil
+	unmarshal := func(data []byte, v interface{}) error {
+		return c.newDecoder(data).Decode(v)
+	}
+	// unmarshalData decodes the data, where unknown fields are rejected in
+	// strict mode, as opposed to the rest of the response.
+	unmarshalData := func(data []byte, v interface{}) error {
+		decoder := c.newDecoder(data)
+		if strict {
+			decoder.DisallowUnknownFields()
+		}
+		return decoder.Decode(v)
+	}
+	if c.codec != nil {
+		unmarshal, unmarshalData = c.codec.Unmarshal, c.codec.Unmarshal
+	}
+	if graphResponse.Data == nil {
+		var envelope rawEnvelope
+		if err := unmarshal(body, &envelope); err != nil {
+			return err
+		}
+		graphResponse.Errors = envelope.Errors
+		if len(envelope.Data) > 0 && !bytes.Equal(envelope.Data, []byte("null")) {
+			graphResponse.RawData = envelope.Data
+		}
+		graphResponse.NoData = graphResponse.RawData == nil
+		return nil
+	}
+	target := graphResponse.Data
+	defer func() { graphResponse.Data = target }()
+	if _, ok := target.(*Targets); ok || c.scalars != nil || len(c.decodeHooks) > 0 {
+		graphResponse.Data = &dataDecoder{scalars: c.scalars, hooks: c.decodeHooks, target: target, unmarshal: unmarshalData, strict: strict}
+	}
+	presence := &dataPresence{target: graphResponse.Data, unmarshal: unmarshalData}
+	graphResponse.Data = presence
+	var err error
+	if c.codec != nil {
+		err = c.codec.Unmarshal(body, graphResponse)
+	} else {
+		err = c.newDecoder(body).Decode(graphResponse)
+	}
+	graphResponse.NoData = !presence.set
+	return err
+}
+
+// dataPresence decodes the data of a response into target, recording
+// whether the response had non-null data.
+type dataPresence struct {
+	target    interface{}
+	unmarshal func(data []byte, v interface{}) error
+	set       bool
+}
+
+func (p *dataPresence) UnmarshalJSON(data []byte) error {
+	if bytes.Equal(data, []byte("null")) {
+		return nil
+	}
+	p.set = true
+	return p.unmarshal(data, p.target)
+}
@@ -0,0 +1,212 @@
+package graphql
+
+import (
+	"bytes"
+	"encoding"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// DecodeHook transforms a value of the data of a response before it is
+// decoded into a value of type to, like the decode hooks of mapstructure.
+// value is a string, json.Number, bool, nil, []interface{} or
+// map[string]interface{}. Hooks return values they don't transform
+// unchanged; a returned value is set as is if it is assignable to to, and
+// is decoded into to from its JSON otherwise.
+type DecodeHook func(to reflect.Type, value interface{}) (interface{}, error)
+
+// WithDecodeHooks runs hooks, in order, on every value of the responses
+// that isn't a struct, slice, array, map or pointer, or that decodes
+// itself, such as time.Time. Walking the responses costs a second parse
+// of their data. WithStrictDecoding still rejects unknown fields.
+//
+//	NewClient(url, WithDecodeHooks(TimeLayoutHook("02/01/2006")))
+func WithDecodeHooks(hooks ...DecodeHook) ClientOption {
+	return func(client *Client) {
+		client.decodeHooks = append(client.decodeHooks, hooks...)
+	}
+}
+
+// TimeLayoutHook returns a DecodeHook parsing strings decoded into
+// time.Time values with layout.
+func TimeLayoutHook(layout string) DecodeHook {
+	timeType := reflect.TypeOf(time.Time{})
+	return func(to reflect.Type, value interface{}) (interface{}, error) {
+		s, ok := value.(string)
+		if !ok || to != timeType {
+			return value, nil
+		}
+		return time.Parse(layout, s)
+	}
+}
+
+var textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+
+// decodesItself reports whether encoding/json decodes values of type t
+// with their own methods.
+func decodesItself(t reflect.Type) bool {
+	ptr := reflect.PtrTo(t)
+	return t.Implements(jsonUnmarshalerType) || ptr.Implements(jsonUnmarshalerType) ||
+		t.Implements(textUnmarshalerType) || ptr.Implements(textUnmarshalerType)
+}
+
+// dataDecoder decodes the data of a response into target, decoding values
+// of types registered in scalars with their codecs and running hooks.
+type dataDecoder struct {
+	scalars   *Scalars
+	hooks     []DecodeHook
+	target    interface{}
+	unmarshal func(data []byte, v interface{}) error
+	// strict rejects fields of objects the target has no field for.
+	strict bool
+}
+
+func (d *dataDecoder) UnmarshalJSON(data []byte) error {
+	if targets, ok := d.target.(*Targets); ok {
+		return targets.decode(data, d.unmarshal, func(data []byte, target interface{}) error {
+			return (&dataDecoder{scalars: d.scalars, hooks: d.hooks, target: target, unmarshal: d.unmarshal, strict: d.strict}).UnmarshalJSON(data)
+		})
+	}
+	v := reflect.ValueOf(d.target)
+	if v.Kind() != reflect.Ptr || v.IsNil() {
+		return d.unmarshal(data, d.target)
+	}
+	return d.decode(data, v.Elem())
+}
+
+func (d *dataDecoder) decode(data json.RawMessage, v reflect.Value) error {
+	if len(d.hooks) == 0 && (d.scalars == nil || !d.scalars.decodes(v.Type())) {
+		return d.unmarshal(data, v.Addr().Interface())
+	}
+	null := bytes.Equal(bytes.TrimSpace(data), []byte("null"))
+	if d.scalars != nil {
+		if name, codec, ok := d.scalars.lookup(v.Type()); ok {
+			if null {
+				v.Set(reflect.Zero(v.Type()))
+				return nil
+			}
+			decoded, err := codec.Decode(data)
+			if err != nil {
+				return errors.Wrapf(err, "decode %s", name)
+			}
+			value := reflect.ValueOf(decoded)
+			if !value.IsValid() || !value.Type().AssignableTo(v.Type()) {
+				return fmt.Errorf("decode %s: got %T, expected %s", name, decoded, v.Type())
+			}
+			v.Set(value)
+			return nil
+		}
+	}
+	switch {
+	case v.Kind() == reflect.Ptr:
+		if null {
+			v.Set(reflect.Zero(v.Type()))
+			return nil
+		}
+		if v.IsNil() {
+			v.Set(reflect.New(v.Type().Elem()))
+		}
+		return d.decode(data, v.Elem())
+	case decodesItself(v.Type()), v.Kind() == reflect.Slice && v.Type().Elem().Kind() == reflect.Uint8:
+	case v.Kind() == reflect.Slice, v.Kind() == reflect.Array:
+		if null {
+			if v.Kind() == reflect.Slice {
+				v.Set(reflect.Zero(v.Type()))
+			}
+			return nil
+		}
+		var items []json.RawMessage
+		if err := d.unmarshal(data, &items); err != nil {
+			return err
+		}
+		if v.Kind() == reflect.Slice {
+			v.Set(reflect.MakeSlice(v.Type(), len(items), len(items)))
+		}
+		for i, item := range items {
+			if i >= v.Len() {
+				break
+			}
+			if err := d.decode(item, v.Index(i)); err != nil {
+				return err
+			}
+		}
+		return nil
+	case v.Kind() == reflect.Map && v.Type().Key().Kind() == reflect.String:
+		if null {
+			v.Set(reflect.Zero(v.Type()))
+			return nil
+		}
+		var items map[string]json.RawMessage
+		if err := d.unmarshal(data, &items); err != nil {
+			return err
+		}
+		if v.IsNil() {
+			v.Set(reflect.MakeMapWithSize(v.Type(), len(items)))
+		}
+		for key, item := range items {
+			value := reflect.New(v.Type().Elem()).Elem()
+			if err := d.decode(item, value); err != nil {
+				return err
+			}
+			v.SetMapIndex(reflect.ValueOf(key).Convert(v.Type().Key()), value)
+		}
+		return nil
+	case v.Kind() == reflect.Struct:
+		if null {
+			return nil
+		}
+		var items map[string]json.RawMessage
+		if err := d.unmarshal(data, &items); err != nil {
+			return err
+		}
+		fields := structFields(v.Type())
+		for key, item := range items {
+			field, ok := matchField(fields, key)
+			if !ok {
+				if d.strict {
+					return fmt.Errorf("json: unknown field %q", key)
+				}
+				continue
+			}
+			fv, _ := fieldByIndex(v, field.index, true)
+			if err := d.decode(item, fv); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	if len(d.hooks) == 0 {
+		return d.unmarshal(data, v.Addr().Interface())
+	}
+	return d.runHooks(data, v)
+}
+
+// runHooks decodes data into v through the hooks.
+func (d *dataDecoder) runHooks(data json.RawMessage, v reflect.Value) error {
+	// numbers are kept as json.Number so they are encoded back exactly
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	var value interface{}
+	if err := decoder.Decode(&value); err != nil {
+		return err
+	}
+	for _, hook := range d.hooks {
+		var err error
+		if value, err = hook(v.Type(), value); err != nil {
+			return errors.Wrapf(err, "decode %s", v.Type())
+		}
+	}
+	if rv := reflect.ValueOf(value); rv.IsValid() && v.Kind() != reflect.Interface && rv.Type().AssignableTo(v.Type()) {
+		v.Set(rv)
+		return nil
+	}
+	encoded, err := json.Marshal(value)
+	if err != nil {
+		return errors.Wrapf(err, "decode %s", v.Type())
+	}
+	return d.unmarshal(encoded, v.Addr().Interface())
+}
@@ -0,0 +1,171 @@
+package graphql
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+type ticketState string
+
+type hookData struct {
+	On      time.Time
+	Until   *time.Time
+	Dates   []time.Time
+	ByName  map[string]time.Time
+	State   ticketState
+	Timeout time.Duration
+	Price   float64
+}
+
+var (
+	ticketStateType = reflect.TypeOf(ticketState(""))
+	durationType    = reflect.TypeOf(time.Duration(0))
+	float64Type     = reflect.TypeOf(float64(0))
+)
+
+// upperStateHook upper-cases the values of ticket states.
+func upperStateHook(to reflect.Type, value interface{}) (interface{}, error) {
+	if s, ok := value.(string); ok && to == ticketStateType {
+		return ticketState(strings.ToUpper(s)), nil
+	}
+	return value, nil
+}
+
+// secondsHook decodes durations from numbers of seconds.
+func secondsHook(to reflect.Type, value interface{}) (interface{}, error) {
+	if n, ok := value.(json.Number); ok && to == durationType {
+		seconds, err := n.Int64()
+		return time.Duration(seconds) * time.Second, err
+	}
+	return value, nil
+}
+
+// trimDollarHook drops the currency sign of prices.
+func trimDollarHook(to reflect.Type, value interface{}) (interface{}, error) {
+	if s, ok := value.(string); ok && to == float64Type {
+		return strings.TrimPrefix(s, "$"), nil
+	}
+	return value, nil
+}
+
+// numberHook turns strings decoded into floats into numbers.
+func numberHook(to reflect.Type, value interface{}) (interface{}, error) {
+	if s, ok := value.(string); ok && to == float64Type {
+		return json.Number(s), nil
+	}
+	return value, nil
+}
+
+func TestDecodeHooks(t *testing.T) {
+	first := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	second := first.AddDate(0, 0, 1)
+	tests := []struct {
+		name    string
+		hooks   []DecodeHook
+		strict  bool
+		data    string
+		want    hookData
+		wantErr string
+	}{
+		{
+			name:  "time layout",
+			hooks: []DecodeHook{TimeLayoutHook("02/01/2006")},
+			data:  `{"on":"01/03/2024","until":"02/03/2024"}`,
+			want:  hookData{On: first, Until: &second},
+		},
+		{
+			name:  "nested values",
+			hooks: []DecodeHook{TimeLayoutHook("02/01/2006")},
+			data:  `{"dates":["01/03/2024","02/03/2024"],"byName":{"start":"01/03/2024"}}`,
+			want:  hookData{Dates: []time.Time{first, second}, ByName: map[string]time.Time{"start": first}},
+		},
+		{
+			name:  "nulls",
+			hooks: []DecodeHook{TimeLayoutHook("02/01/2006")},
+			data:  `{"until":null,"dates":null}`,
+		},
+		{
+			name:  "named types",
+			hooks: []DecodeHook{upperStateHook},
+			data:  `{"state":"open"}`,
+			want:  hookData{State: "OPEN"},
+		},
+		{
+			name:  "numbers",
+			hooks: []DecodeHook{secondsHook},
+			data:  `{"timeout":90}`,
+			want:  hookData{Timeout: 90 * time.Second},
+		},
+		{
+			name:  "hooks in order",
+			hooks: []DecodeHook{trimDollarHook, numberHook},
+			data:  `{"price":"$1.50"}`,
+			want:  hookData{Price: 1.5},
+		},
+		{
+			name:  "values left alone",
+			hooks: []DecodeHook{upperStateHook},
+			data:  `{"on":"2024-03-01T00:00:00Z","timeout":5,"price":2}`,
+			want:  hookData{On: first, Timeout: 5, Price: 2},
+		},
+		{
+			name:    "hook error",
+			hooks:   []DecodeHook{TimeLayoutHook("02/01/2006")},
+			data:    `{"on":"2024-03-01"}`,
+			wantErr: "decode time.Time",
+		},
+		{
+			name:    "unknown field with strict decoding",
+			hooks:   []DecodeHook{upperStateHook},
+			strict:  true,
+			data:    `{"state":"open","assignee":"ann"}`,
+			wantErr: `unknown field "assignee"`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := newPayloadServer(t, tt.data)
+			opts := []ClientOption{WithDecodeHooks(tt.hooks...)}
+			if tt.strict {
+				opts = append(opts, WithStrictDecoding())
+			}
+			var got hookData
+			_, err := NewClient(srv.URL, opts...).Run(context.Background(), NewGraphqlRequest("{ ticket }"), &got)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("got error %v, want one containing %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("decoded %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDecodeHooksWithScalars(t *testing.T) {
+	failing := func(to reflect.Type, value interface{}) (interface{}, error) {
+		if to == reflect.TypeOf(time.Time{}) {
+			return nil, errors.New("hooks ran on a registered scalar")
+		}
+		return value, nil
+	}
+	srv := newPayloadServer(t, `{"on":"2024-03-01","state":"open"}`)
+	client := NewClient(srv.URL, WithScalars(dateScalars()), WithDecodeHooks(failing, upperStateHook))
+	var got hookData
+	if _, err := client.Run(context.Background(), NewGraphqlRequest("{ ticket }"), &got); err != nil {
+		t.Fatal(err)
+	}
+	if want := (hookData{On: time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC), State: "OPEN"}); !reflect.DeepEqual(got, want) {
+		t.Fatalf("decoded %+v, want %+v", got, want)
+	}
+}
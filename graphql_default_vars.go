@@ -0,0 +1,37 @@
+package graphql
+
+// WithDefaultVars merges vars into the variables of every request, e.g.
+// for a locale or tenant every operation takes. Variables set on the
+// request take precedence.
+func WithDefaultVars(vars map[string]interface{}) ClientOption {
+	return func(client *Client) {
+		if client.defaultVars == nil {
+			client.defaultVars = make(map[string]interface{}, len(vars))
+		}
+		for key, value := range vars {
+			client.defaultVars[key] = value
+		}
+	}
+}
+
+// withDefaultVars returns req with the client's default variables it
+// doesn't set.
+func (c *Client) withDefaultVars(req *GraphRequest) *GraphRequest {
+	missing := false
+	for key := range c.defaultVars {
+		if _, ok := req.vars[key]; !ok {
+			missing = true
+			break
+		}
+	}
+	if !missing {
+		return req
+	}
+	req = req.Clone()
+	for key, value := range c.defaultVars {
+		if _, ok := req.vars[key]; !ok {
+			req.Var(key, cloneValue(value))
+		}
+	}
+	return req
+}
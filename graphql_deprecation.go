@@ -0,0 +1,49 @@
+package graphql
+
+import "context"
+
+// Deprecation is the use of a deprecated field, argument, input field or
+// enum value by a query.
+type Deprecation struct {
+	// Coordinate is the schema coordinate of the deprecated element, such
+	// as "User.name", "Query.users(first:)" or "Role.ADMIN".
+	Coordinate string
+	Reason     string
+	// Location is where the query first uses the element.
+	Location Location
+}
+
+// DeprecationFunc receives the deprecated elements used by an operation.
+type DeprecationFunc func(ctx context.Context, info OperationInfo, deprecation Deprecation)
+
+// WithDeprecationWarnings reports every deprecated element of the schema
+// set with WithSchema used by an operation to fn, before it is sent.
+// Usages are logged to Client.Log with or without it.
+func WithDeprecationWarnings(fn DeprecationFunc) ClientOption {
+	return func(client *Client) {
+		client.onDeprecation = fn
+	}
+}
+
+// deprecate records the use of the deprecated element at coordinate.
+func (v *validator) deprecate(pos int, coordinate, reason string) {
+	if v.deprecated[coordinate] {
+		return
+	}
+	v.deprecated[coordinate] = true
+	v.deprecations = append(v.deprecations, Deprecation{
+		Coordinate: coordinate,
+		Reason:     reason,
+		Location:   locate(v.doc.src, pos),
+	})
+}
+
+func (c *Client) reportDeprecations(ctx context.Context, op *operation, deprecations []Deprecation) {
+	for _, deprecation := range deprecations {
+		c.logf(op, LogLevelInfo, LogDeprecations, ">> %s is deprecated: %s (%d:%d)",
+			deprecation.Coordinate, deprecation.Reason, deprecation.Location.Line, deprecation.Location.Column)
+		if c.onDeprecation != nil {
+			c.onDeprecation(ctx, c.operationInfo(op), deprecation)
+		}
+	}
+}
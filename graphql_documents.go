@@ -0,0 +1,132 @@
+package graphql
+
+import (
+	"fmt"
+	"io/fs"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// Documents is a registry of the operations defined in .graphql and .gql
+// files, by name.
+//
+//	//go:embed queries
+//	var queries embed.FS
+//
+//	documents, err := LoadDocuments(queries)
+//	...
+//	req, err := documents.NewRequestByName("GetUser")
+type Documents struct {
+	// operations are the texts of the operations, followed by the
+	// definitions of the fragments they spread.
+	operations map[string]string
+}
+
+type loadedDefinition struct {
+	text string
+	path string
+	// spreads are the names of the fragments the definition spreads.
+	spreads []string
+}
+
+// LoadDocuments reads every .graphql and .gql file of fsys. Operations must
+// be named, and names of operations and fragments must be unique across
+// files. Fragments can be spread by operations of any file.
+func LoadDocuments(fsys fs.FS) (*Documents, error) {
+	operations := make(map[string]loadedDefinition)
+	fragments := make(map[string]loadedDefinition)
+	err := fs.WalkDir(fsys, ".", func(name string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if ext := strings.ToLower(path.Ext(name)); entry.IsDir() || ext != ".graphql" && ext != ".gql" {
+			return nil
+		}
+		data, err := fs.ReadFile(fsys, name)
+		if err != nil {
+			return err
+		}
+		doc, err := parseQuery(string(data))
+		if err != nil {
+			return errors.Wrap(err, name)
+		}
+		for _, operation := range doc.operations {
+			if operation.name == "" {
+				return fmt.Errorf("graphql: %s: operations must be named", name)
+			}
+			if other, ok := operations[operation.name]; ok {
+				return fmt.Errorf("graphql: %s %q is defined in %s and %s", operation.typ, operation.name, other.path, name)
+			}
+			operations[operation.name] = loadedDefinition{
+				text:    doc.src[operation.pos:operation.end],
+				path:    name,
+				spreads: fragmentSpreads(operation.selections, nil),
+			}
+		}
+		for _, fragment := range doc.fragments {
+			if other, ok := fragments[fragment.name]; ok {
+				return fmt.Errorf("graphql: fragment %q is defined in %s and %s", fragment.name, other.path, name)
+			}
+			fragments[fragment.name] = loadedDefinition{
+				text:    doc.src[fragment.pos:fragment.end],
+				path:    name,
+				spreads: fragmentSpreads(fragment.selections, nil),
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "load documents")
+	}
+	d := &Documents{operations: make(map[string]string, len(operations))}
+	for name, operation := range operations {
+		d.operations[name] = withSpreadFragments(operation, fragments)
+	}
+	return d, nil
+}
+
+// withSpreadFragments returns the text of operation followed by the
+// definitions of the fragments it spreads, directly or through other
+// fragments. Unknown fragments are left out, for WithFragments to provide.
+func withSpreadFragments(operation loadedDefinition, fragments map[string]loadedDefinition) string {
+	included := make(map[string]bool)
+	parts := []string{operation.text}
+	pending := operation.spreads
+	for len(pending) > 0 {
+		name := pending[0]
+		pending = pending[1:]
+		fragment, ok := fragments[name]
+		if !ok || included[name] {
+			continue
+		}
+		included[name] = true
+		parts = append(parts, fragment.text)
+		pending = append(pending, fragment.spreads...)
+	}
+	return strings.Join(parts, "\n")
+}
+
+// NewRequestByName makes a new GraphRequest running the operation name,
+// with its document and operation name set.
+func (d *Documents) NewRequestByName(name string) (*GraphRequest, error) {
+	document, ok := d.operations[name]
+	if !ok {
+		return nil, fmt.Errorf("graphql: unknown operation %q", name)
+	}
+	req := NewGraphqlRequest(document)
+	req.SetOperationName(name)
+	return req, nil
+}
+
+// Names returns the names of the operations, sorted.
+func (d *Documents) Names() []string {
+	names := make([]string, 0, len(d.operations))
+	for name := range d.operations {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -1,12 +1,21 @@
 package graphql
 
-import "fmt"
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
 
 type GraphErr struct {
 	Message         interface{}            `json:"message"`
 	ErrorExtensions map[string]interface{} `json:"extensions"`
 	Locations       []Location             `json:"locations"`
-	Path            []string               `json:"path"`
+	Path            Path                   `json:"path"`
 }
 type Location struct {
 	Column int `json:"column"`
@@ -16,6 +25,106 @@ type Location struct {
 func (e *GraphErr) Extensions() map[string]interface{} {
 	return e.ErrorExtensions
 }
+
+// DecodeExtensions decodes the extensions of the error into v, a pointer
+// to a struct or map, following encoding/json rules.
+//
+//	var details struct {
+//		Code          string `json:"code"`
+//		CorrelationID string `json:"correlationId"`
+//	}
+//	err := graphErr.DecodeExtensions(&details)
+func (e *GraphErr) DecodeExtensions(v interface{}) error {
+	encoded, err := json.Marshal(e.ErrorExtensions)
+	if err != nil {
+		return errors.Wrap(err, "encode extensions")
+	}
+	if err := json.Unmarshal(encoded, v); err != nil {
+		return errors.Wrap(err, "decode extensions")
+	}
+	return nil
+}
 func (e GraphErr) Error() string {
 	return fmt.Sprintf("graphql: %v", e.Message)
 }
+
+// ResponseTooLargeError is returned when the response body exceeds the
+// limit set with WithMaxResponseBytes.
+type ResponseTooLargeError struct {
+	Limit int64
+}
+
+func (e *ResponseTooLargeError) Error() string {
+	return fmt.Sprintf("graphql: response body exceeds the limit of %d bytes", e.Limit)
+}
+
+// TimeoutError is returned when a request runs longer than the timeout set
+// with GraphRequest.SetTimeout. It unwraps to context.DeadlineExceeded.
+type TimeoutError struct {
+	Timeout time.Duration
+}
+
+func (e *TimeoutError) Error() string {
+	return fmt.Sprintf("graphql: request timed out after %s", e.Timeout)
+}
+
+// Unwrap returns context.DeadlineExceeded.
+func (e *TimeoutError) Unwrap() error {
+	return context.DeadlineExceeded
+}
+
+// ErrNoData is returned by Run[T] when the data of the response is null
+// or missing, usually along with GraphQL errors in the response.
+var ErrNoData = errors.New("graphql: response has no data")
+
+// maxHTTPErrorBody bounds the body kept by HTTPError.
+const maxHTTPErrorBody = 4 << 10
+
+// HTTPError is returned when the server responds with a status other than
+// 200 OK. Servers often send GraphQL errors along with 4xx statuses: they
+// are decoded into Errors, which errors.As and HasErrorCode find.
+type HTTPError struct {
+	StatusCode int
+	Header     http.Header
+	// Body holds up to the first 4KB of the response body.
+	Body []byte
+	// Errors are the GraphQL errors of the body, if it is a GraphQL
+	// response.
+	Errors GraphErrors
+}
+
+func newHTTPError(res *http.Response, body []byte) *HTTPError {
+	var payload struct {
+		Errors GraphErrors `json:"errors"`
+	}
+	_ = json.Unmarshal(body, &payload)
+	if len(body) > maxHTTPErrorBody {
+		body = body[:maxHTTPErrorBody]
+	}
+	return &HTTPError{
+		StatusCode: res.StatusCode,
+		Header:     res.Header,
+		Body:       append([]byte(nil), body...),
+		Errors:     payload.Errors,
+	}
+}
+
+func (e *HTTPError) Error() string {
+	message := fmt.Sprintf(messageCodeNotOK, e.StatusCode)
+	if len(e.Errors) == 0 {
+		return message
+	}
+	messages := make([]string, len(e.Errors))
+	for i := range e.Errors {
+		messages[i] = fmt.Sprint(e.Errors[i].Message)
+	}
+	return message + ": " + strings.Join(messages, "; ")
+}
+
+// Unwrap returns the GraphQL errors of the body, if any.
+func (e *HTTPError) Unwrap() error {
+	if len(e.Errors) == 0 {
+		return nil
+	}
+	return e.Errors
+}
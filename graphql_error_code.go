@@ -0,0 +1,70 @@
+package graphql
+
+import "github.com/pkg/errors"
+
+// ErrorCode is the code of a GraphQL error, taken from its "code"
+// extension. It implements error so that errors.Is(err, code) reports
+// whether err holds a GraphErr with the code.
+type ErrorCode string
+
+// Error codes commonly used by servers.
+const (
+	CodeUnauthenticated            ErrorCode = "UNAUTHENTICATED"
+	CodeForbidden                  ErrorCode = "FORBIDDEN"
+	CodeNotFound                   ErrorCode = "NOT_FOUND"
+	CodeBadUserInput               ErrorCode = "BAD_USER_INPUT"
+	CodeBadRequest                 ErrorCode = "BAD_REQUEST"
+	CodeParseFailed                ErrorCode = "GRAPHQL_PARSE_FAILED"
+	CodeValidationFailed           ErrorCode = "GRAPHQL_VALIDATION_FAILED"
+	CodePersistedQueryNotFound     ErrorCode = "PERSISTED_QUERY_NOT_FOUND"
+	CodePersistedQueryNotSupported ErrorCode = "PERSISTED_QUERY_NOT_SUPPORTED"
+	CodeInternalServerError        ErrorCode = "INTERNAL_SERVER_ERROR"
+	CodeServiceUnavailable         ErrorCode = "SERVICE_UNAVAILABLE"
+	CodeThrottled                  ErrorCode = "THROTTLED"
+	CodeRateLimited                ErrorCode = "RATE_LIMITED"
+)
+
+// Error returns the code itself, so codes print as they are.
+func (c ErrorCode) Error() string {
+	return string(c)
+}
+
+// Code returns the code of the error, or an empty ErrorCode if it has
+// none.
+func (e GraphErr) Code() ErrorCode {
+	code, _ := e.ErrorExtensions["code"].(string)
+	return ErrorCode(code)
+}
+
+// Is reports whether target is the ErrorCode of the error.
+func (e GraphErr) Is(target error) bool {
+	code, ok := target.(ErrorCode)
+	return ok && code != "" && code == e.Code()
+}
+
+// HasErrorCode reports whether err holds a GraphErr with code, e.g. when
+// Run returns GraphErrors with ErrorPolicyReturnAsError.
+func HasErrorCode(err error, code ErrorCode) bool {
+	return errors.Is(err, code)
+}
+
+// IsUnauthorized reports whether err holds an UNAUTHENTICATED error, the
+// counterpart of a 401 Unauthorized response.
+func IsUnauthorized(err error) bool {
+	return HasErrorCode(err, CodeUnauthenticated)
+}
+
+// IsForbidden reports whether err holds a FORBIDDEN error.
+func IsForbidden(err error) bool {
+	return HasErrorCode(err, CodeForbidden)
+}
+
+// IsNotFound reports whether err holds a NOT_FOUND error.
+func IsNotFound(err error) bool {
+	return HasErrorCode(err, CodeNotFound)
+}
+
+// IsBadUserInput reports whether err holds a BAD_USER_INPUT error.
+func IsBadUserInput(err error) bool {
+	return HasErrorCode(err, CodeBadUserInput)
+}
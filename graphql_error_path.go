@@ -0,0 +1,138 @@
+package graphql
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// Path is the path of a GraphQL error: the response keys of the fields
+// and the indices of the list items leading to the value that failed.
+type Path []PathSegment
+
+// String returns the segments of p joined with dots, e.g. "users.1.name".
+func (p Path) String() string {
+	segments := make([]string, len(p))
+	for i, segment := range p {
+		segments[i] = segment.String()
+	}
+	return strings.Join(segments, ".")
+}
+
+// PathSegment is a response key or a list index.
+type PathSegment struct {
+	key     string
+	index   int
+	isIndex bool
+}
+
+// KeySegment returns a segment holding a response key.
+func KeySegment(key string) PathSegment {
+	return PathSegment{key: key}
+}
+
+// IndexSegment returns a segment holding a list index.
+func IndexSegment(index int) PathSegment {
+	return PathSegment{index: index, isIndex: true}
+}
+
+// Key returns the response key of the segment, and whether it is one.
+func (s PathSegment) Key() (string, bool) {
+	return s.key, !s.isIndex
+}
+
+// Index returns the list index of the segment, and whether it is one.
+func (s PathSegment) Index() (int, bool) {
+	return s.index, s.isIndex
+}
+
+func (s PathSegment) String() string {
+	if s.isIndex {
+		return strconv.Itoa(s.index)
+	}
+	return s.key
+}
+
+// MarshalJSON encodes the segment as a JSON string or number.
+func (s PathSegment) MarshalJSON() ([]byte, error) {
+	if s.isIndex {
+		return json.Marshal(s.index)
+	}
+	return json.Marshal(s.key)
+}
+
+// UnmarshalJSON decodes a JSON string or integer.
+func (s *PathSegment) UnmarshalJSON(data []byte) error {
+	if bytes.HasPrefix(bytes.TrimSpace(data), []byte(`"`)) {
+		*s = PathSegment{}
+		return json.Unmarshal(data, &s.key)
+	}
+	var index int
+	if err := json.Unmarshal(data, &index); err != nil {
+		return errors.Wrap(err, "decode path segment")
+	}
+	*s = IndexSegment(index)
+	return nil
+}
+
+// FieldErrors maps errs to the fields of v, the value the data of the
+// response was decoded into, following json tags like the decoding did.
+// Keys are the Go names of the fields with indices in brackets, e.g.
+// "CreateUser.Emails[1]", so form-style errors can be shown next to their
+// fields. Path segments that don't match a field are kept as they are, and
+// errors without a path are keyed by "".
+func FieldErrors(v interface{}, errs []GraphErr) map[string][]GraphErr {
+	fieldErrors := make(map[string][]GraphErr)
+	t := reflect.TypeOf(v)
+	for _, err := range errs {
+		key := fieldPath(t, err.Path)
+		fieldErrors[key] = append(fieldErrors[key], err)
+	}
+	return fieldErrors
+}
+
+// fieldPath returns the path of the Go field of type t at path.
+func fieldPath(t reflect.Type, path Path) string {
+	var b strings.Builder
+	for _, segment := range path {
+		for t != nil && t.Kind() == reflect.Ptr {
+			t = t.Elem()
+		}
+		if index, ok := segment.Index(); ok {
+			fmt.Fprintf(&b, "[%d]", index)
+			if t != nil && (t.Kind() == reflect.Slice || t.Kind() == reflect.Array) {
+				t = t.Elem()
+			} else {
+				t = nil
+			}
+			continue
+		}
+		key, _ := segment.Key()
+		if b.Len() > 0 {
+			b.WriteByte('.')
+		}
+		switch {
+		case t != nil && t.Kind() == reflect.Struct:
+			field, ok := matchField(structFields(t), key)
+			if !ok {
+				b.WriteString(key)
+				t = nil
+				continue
+			}
+			b.WriteString(t.FieldByIndex(field.index).Name)
+			t = field.typ
+		case t != nil && t.Kind() == reflect.Map && t.Key().Kind() == reflect.String:
+			b.WriteString(key)
+			t = t.Elem()
+		default:
+			b.WriteString(key)
+			t = nil
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,61 @@
+package graphql
+
+import "strings"
+
+// ErrorPolicy controls how Run returns the GraphQL errors of a response.
+type ErrorPolicy int
+
+const (
+	// ErrorPolicyInResponse leaves GraphQL errors in GraphResponse.Errors
+	// and returns a nil error. It is the default.
+	ErrorPolicyInResponse ErrorPolicy = iota
+	// ErrorPolicyReturnAsError also returns the errors as GraphErrors,
+	// along with the response and the partial data that was decoded.
+	ErrorPolicyReturnAsError
+	// ErrorPolicyIgnore drops GraphQL errors from the response.
+	ErrorPolicyIgnore
+)
+
+// WithErrorPolicy sets how Run returns the GraphQL errors of a response.
+//
+//	NewClient(url, WithErrorPolicy(ErrorPolicyReturnAsError))
+func WithErrorPolicy(policy ErrorPolicy) ClientOption {
+	return func(client *Client) {
+		client.errorPolicy = policy
+	}
+}
+
+// GraphErrors are the GraphQL errors of a response, returned by Run with
+// ErrorPolicyReturnAsError. errors.As finds each GraphErr in it.
+type GraphErrors []GraphErr
+
+func (e GraphErrors) Error() string {
+	messages := make([]string, len(e))
+	for i := range e {
+		messages[i] = e[i].Error()
+	}
+	return strings.Join(messages, "; ")
+}
+
+// Unwrap returns the errors, for errors.Is and errors.As.
+func (e GraphErrors) Unwrap() []error {
+	errs := make([]error, len(e))
+	for i := range e {
+		errs[i] = e[i]
+	}
+	return errs
+}
+
+// applyErrorPolicy returns the error Run returns for graphResponse and err.
+func (c *Client) applyErrorPolicy(graphResponse *GraphResponse, err error) error {
+	if err != nil || graphResponse == nil || len(graphResponse.Errors) == 0 {
+		return err
+	}
+	switch c.errorPolicy {
+	case ErrorPolicyReturnAsError:
+		return GraphErrors(graphResponse.Errors)
+	case ErrorPolicyIgnore:
+		graphResponse.Errors = nil
+	}
+	return nil
+}
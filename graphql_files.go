@@ -0,0 +1,130 @@
+package graphql
+
+import (
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/textproto"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/pkg/errors"
+)
+
+// FileFromPath sets the file at path to upload, named after its base name.
+// The file is only opened when the request is sent, and closed once it has
+// been, whatever the outcome. Its content type is detected from its
+// extension, or from its content when the extension is unknown.
+func (req *GraphRequest) FileFromPath(fieldname, path string) {
+	req.File(fieldname, filepath.Base(path), &pathFile{path: path})
+}
+
+// pathFile is a file opened on first use. Closing it makes the next use
+// open it again from the start, so requests can be retried and reused.
+type pathFile struct {
+	path string
+
+	mu   sync.Mutex
+	file *os.File
+}
+
+func (f *pathFile) open() (*os.File, error) {
+	if f.file == nil {
+		file, err := os.Open(f.path)
+		if err != nil {
+			return nil, errors.Wrap(err, "open file")
+		}
+		f.file = file
+	}
+	return f.file, nil
+}
+
+func (f *pathFile) Read(p []byte) (int, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	file, err := f.open()
+	if err != nil {
+		return 0, err
+	}
+	return file.Read(p)
+}
+
+// Seek doesn't open the file, so recording and rewinding the position of
+// a file that hasn't been read yet is free.
+func (f *pathFile) Seek(offset int64, whence int) (int64, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.file == nil && offset == 0 && whence != io.SeekEnd {
+		return 0, nil
+	}
+	file, err := f.open()
+	if err != nil {
+		return 0, err
+	}
+	return file.Seek(offset, whence)
+}
+
+func (f *pathFile) Close() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.file == nil {
+		return nil
+	}
+	err := f.file.Close()
+	f.file = nil
+	return err
+}
+
+// contentType returns the MIME type of the file, from its extension or
+// else from its first bytes.
+func (f *pathFile) contentType() (string, error) {
+	if contentType := mime.TypeByExtension(filepath.Ext(f.path)); contentType != "" {
+		return contentType, nil
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	file, err := f.open()
+	if err != nil {
+		return "", err
+	}
+	head := make([]byte, 512)
+	n, err := file.ReadAt(head, 0)
+	if err != nil && err != io.EOF {
+		return "", errors.Wrap(err, "read file")
+	}
+	return http.DetectContentType(head[:n]), nil
+}
+
+// closeFiles closes the files opened by the client.
+func closeFiles(files []File) {
+	for i := range files {
+		if f, ok := files[i].R.(*pathFile); ok {
+			f.Close()
+		}
+	}
+}
+
+var quoteEscaper = strings.NewReplacer("\\", "\\\\", `"`, "\\\"")
+
+// createFilePart starts the multipart part of the i-th file.
+func createFilePart(writer *multipart.Writer, i int, file File) (io.Writer, error) {
+	contentType := file.ContentType
+	if f, ok := file.R.(*pathFile); ok && contentType == "" {
+		detected, err := f.contentType()
+		if err != nil {
+			return nil, err
+		}
+		contentType = detected
+	}
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+	header := make(textproto.MIMEHeader)
+	header.Set("Content-Disposition", `form-data; name="`+quoteEscaper.Replace(fileFieldName(i, file))+
+		`"; filename="`+quoteEscaper.Replace(file.Name)+`"`)
+	header.Set("Content-Type", contentType)
+	return writer.CreatePart(header)
+}
@@ -0,0 +1,111 @@
+package graphql
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// WithFragments registers the fragments defined in definitions, so
+// queries can spread them without defining them. The definitions of the
+// fragments a query spreads, directly or through other fragments, are
+// appended to it before it is sent, unless the query defines them itself.
+//
+//	NewClient(url, WithFragments(`fragment UserFields on User { id name }`))
+//
+// Run fails if definitions holds anything but fragment definitions.
+func WithFragments(definitions ...string) ClientOption {
+	return func(client *Client) {
+		if client.fragments == nil {
+			client.fragments = make(map[string]definedFragment)
+		}
+		for _, text := range definitions {
+			doc, err := parseQuery(text)
+			if err != nil {
+				client.fragmentsErr = errors.Wrap(err, "parse fragments")
+				continue
+			}
+			for _, operation := range doc.operations {
+				client.fragmentsErr = fmt.Errorf("graphql: expected fragment definitions, got %q", text[operation.pos:operation.end])
+			}
+			for _, fragment := range doc.fragments {
+				client.fragments[fragment.name] = definedFragment{
+					text:    text[fragment.pos:fragment.end],
+					spreads: fragmentSpreads(fragment.selections, nil),
+				}
+			}
+		}
+	}
+}
+
+// definedFragment is the definition of a fragment, and the names of the
+// fragments it spreads.
+type definedFragment struct {
+	text    string
+	spreads []string
+}
+
+// withFragments returns req with the definitions of the registered
+// fragments it spreads appended to its query. Queries that can't be parsed
+// are left as they are, for the server to reject.
+func (c *Client) withFragments(req *GraphRequest) (*GraphRequest, error) {
+	if c.fragmentsErr != nil {
+		return nil, c.fragmentsErr
+	}
+	if len(c.fragments) == 0 {
+		return req, nil
+	}
+	doc, err := parseQuery(req.query)
+	if err != nil {
+		return req, nil
+	}
+	defined := make(map[string]bool)
+	for _, fragment := range doc.fragments {
+		defined[fragment.name] = true
+	}
+	var appended []string
+	pending := doc.spreads()
+	for len(pending) > 0 {
+		name := pending[0]
+		pending = pending[1:]
+		fragment, ok := c.fragments[name]
+		if !ok || defined[name] {
+			continue
+		}
+		defined[name] = true
+		appended = append(appended, fragment.text)
+		pending = append(pending, fragment.spreads...)
+	}
+	if len(appended) == 0 {
+		return req, nil
+	}
+	req = req.Clone()
+	req.query = req.query + "\n" + strings.Join(appended, "\n")
+	return req, nil
+}
+
+// spreads returns the names of the fragments spread in the operations and
+// fragments of d, in order.
+func (d *document) spreads() []string {
+	var names []string
+	for _, operation := range d.operations {
+		names = fragmentSpreads(operation.selections, names)
+	}
+	for _, fragment := range d.fragments {
+		names = fragmentSpreads(fragment.selections, names)
+	}
+	return names
+}
+
+// fragmentSpreads appends the names of the fragments spread in selections
+// to names, in order.
+func fragmentSpreads(selections []*selectionNode, names []string) []string {
+	for _, selection := range selections {
+		if selection.kind == selectionFragmentSpread {
+			names = append(names, selection.name)
+		}
+		names = fragmentSpreads(selection.selections, names)
+	}
+	return names
+}
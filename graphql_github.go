@@ -0,0 +1,141 @@
+package graphql
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// GitHubEndpoint is the URL of the GitHub GraphQL API.
+const GitHubEndpoint = "https://api.github.com/graphql"
+
+// GitHubRateLimit is the rate limit status reported by the GitHub GraphQL
+// API in the X-RateLimit-* response headers and, when the query selects it,
+// in the rateLimit field.
+type GitHubRateLimit struct {
+	Limit     int
+	Remaining int
+	Used      int
+	// Cost and NodeCount are only known when the query selects them in
+	// the rateLimit field.
+	Cost      int
+	NodeCount int
+	Resource  string
+	ResetAt   time.Time
+}
+
+// NewGitHubClient makes a new Client for the GitHub GraphQL API,
+// authenticated with a personal access token or an app installation token.
+// The rate limit status of every response is available in
+// GraphResponse.GitHubRateLimit.
+func NewGitHubClient(token string, opts ...ClientOption) *Client {
+	defaults := []ClientOption{WithBearerToken(token), withGitHub()}
+	return NewClient(GitHubEndpoint, append(defaults, opts...)...)
+}
+
+// WithGitHubPacing makes a GitHub client wait for the rate limit window to
+// reset before sending a request once the remaining budget has dropped to
+// minRemaining points.
+func WithGitHubPacing(minRemaining int) ClientOption {
+	return func(client *Client) {
+		withGitHub()(client)
+		client.github.minRemaining = minRemaining
+	}
+}
+
+func withGitHub() ClientOption {
+	return func(client *Client) {
+		if client.github == nil {
+			client.github = &gitHub{minRemaining: -1}
+		}
+	}
+}
+
+type gitHub struct {
+	minRemaining int
+
+	mu   sync.Mutex
+	last *GitHubRateLimit
+}
+
+// pace waits until the rate limit resets if the remaining budget is low.
+func (g *gitHub) pace(ctx context.Context) error {
+	g.mu.Lock()
+	last := g.last
+	g.mu.Unlock()
+	if last == nil || last.Remaining > g.minRemaining {
+		return nil
+	}
+	if wait := time.Until(last.ResetAt); wait > 0 {
+		return sleepContext(ctx, wait)
+	}
+	return nil
+}
+
+// observe remembers the rate limit status of res, a response of any
+// status, for pacing.
+func (g *gitHub) observe(res *http.Response, body []byte) {
+	rateLimit := parseGitHubRateLimit(res.Header, body)
+	if rateLimit == nil {
+		return
+	}
+	g.mu.Lock()
+	g.last = rateLimit
+	g.mu.Unlock()
+}
+
+// parseGitHubRateLimit parses the rate limit status of a response with
+// header and body, or returns nil if it has none.
+func parseGitHubRateLimit(header http.Header, body []byte) *GitHubRateLimit {
+	rateLimit := &GitHubRateLimit{
+		Limit:     headerInt(header, "X-RateLimit-Limit"),
+		Remaining: headerInt(header, "X-RateLimit-Remaining"),
+		Used:      headerInt(header, "X-RateLimit-Used"),
+		Resource:  header.Get("X-RateLimit-Resource"),
+	}
+	if reset := headerInt(header, "X-RateLimit-Reset"); reset > 0 {
+		rateLimit.ResetAt = time.Unix(int64(reset), 0)
+	}
+	var payload struct {
+		Data struct {
+			RateLimit *struct {
+				Cost      *int       `json:"cost"`
+				Limit     *int       `json:"limit"`
+				Remaining *int       `json:"remaining"`
+				Used      *int       `json:"used"`
+				NodeCount *int       `json:"nodeCount"`
+				ResetAt   *time.Time `json:"resetAt"`
+			} `json:"rateLimit"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(body, &payload); err == nil && payload.Data.RateLimit != nil {
+		fields := payload.Data.RateLimit
+		setInt(&rateLimit.Cost, fields.Cost)
+		setInt(&rateLimit.Limit, fields.Limit)
+		setInt(&rateLimit.Remaining, fields.Remaining)
+		setInt(&rateLimit.Used, fields.Used)
+		setInt(&rateLimit.NodeCount, fields.NodeCount)
+		if fields.ResetAt != nil {
+			rateLimit.ResetAt = *fields.ResetAt
+		}
+	}
+	if rateLimit.Limit == 0 {
+		// neither the headers nor the body had rate limit information
+		return nil
+	}
+	return rateLimit
+}
+
+func headerInt(h http.Header, key string) int {
+	n, _ := strconv.Atoi(h.Get(key))
+	return n
+}
+
+func setInt(dst, src *int) {
+	if src != nil {
+		*dst = *src
+	}
+}
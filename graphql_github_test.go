@@ -0,0 +1,69 @@
+package graphql
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// rateLimitServer answers queries selecting an exhausted field with a 403
+// secondary rate limit response exhausting the budget, and other queries
+// with budget left.
+func rateLimitServer(t *testing.T, calls *int32) *httptest.Server {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(calls, 1)
+		body, _ := io.ReadAll(r.Body)
+		w.Header().Set("X-RateLimit-Limit", "5000")
+		w.Header().Set("X-RateLimit-Reset", strconv.FormatInt(time.Now().Add(time.Hour).Unix(), 10))
+		if bytes.Contains(body, []byte("exhausted")) {
+			w.Header().Set("X-RateLimit-Remaining", "0")
+			w.WriteHeader(http.StatusForbidden)
+			fmt.Fprint(w, `{"message":"You have exceeded a secondary rate limit"}`)
+			return
+		}
+		w.Header().Set("X-RateLimit-Remaining", "4000")
+		fmt.Fprint(w, `{"data":{"viewer":"alice"}}`)
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestGitHubPacing(t *testing.T) {
+	tests := map[string][]string{
+		"rate limited response":                     {"exhausted"},
+		"cache hit after the rate limited response": {"cached", "exhausted", "cached"},
+	}
+	for name, queries := range tests {
+		t.Run(name, func(t *testing.T) {
+			var calls int32
+			srv := rateLimitServer(t, &calls)
+			client := NewClient(srv.URL, WithGitHubPacing(0), WithResponseCache(CacheConfig{TTL: time.Minute}))
+			run := func(ctx context.Context, query string) error {
+				req := NewGraphqlRequest("{ viewer " + query + ": viewer }")
+				_, err := client.Run(ctx, req, &viewerData{})
+				return err
+			}
+			for _, query := range queries {
+				_ = run(context.Background(), query)
+			}
+			sent := atomic.LoadInt32(&calls)
+			ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+			defer cancel()
+			if err := run(ctx, "paced"); !errors.Is(err, context.DeadlineExceeded) {
+				t.Fatalf("got %v, want the request to wait for the rate limit reset", err)
+			}
+			if calls != sent {
+				t.Fatalf("server got %d requests, want %d", calls, sent)
+			}
+		})
+	}
+}
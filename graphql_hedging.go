@@ -0,0 +1,62 @@
+package graphql
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"time"
+)
+
+// WithHedging makes the client send a duplicate of a query when no
+// response has arrived after delay, and use whichever response arrives
+// first. Mutations, subscriptions and requests with files are never hedged.
+func WithHedging(delay time.Duration) ClientOption {
+	return func(client *Client) {
+		client.hedgeDelay = delay
+	}
+}
+
+type attemptResult struct {
+	res *http.Response
+	buf *bytes.Buffer
+	err error
+}
+
+// send performs a single attempt with reader as the request body, hedging
+// it with a second request built by body when hedging applies.
+func (c *Client) send(ctx context.Context, op *operation, contentType string,
+	reader io.Reader, body func() (io.Reader, error)) (*http.Response, *bytes.Buffer, error) {
+	if c.hedgeDelay <= 0 || len(op.req.files) > 0 || op.stream != nil || op.hasSideEffects() {
+		return c.attempt(ctx, op, contentType, reader)
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	results := make(chan attemptResult, 2)
+	launch := func(reader io.Reader) {
+		res, buf, err := c.attempt(ctx, op, contentType, reader)
+		results <- attemptResult{res: res, buf: buf, err: err}
+	}
+	go launch(reader)
+	hedge := time.NewTimer(c.hedgeDelay)
+	defer hedge.Stop()
+	launched, received := 1, 0
+	for {
+		select {
+		case <-hedge.C:
+			hedgeReader, err := body()
+			if err != nil {
+				c.logf(op, LogLevelInfo, LogWire, ">> not hedging request: %v", err)
+				continue
+			}
+			c.logf(op, LogLevelInfo, LogWire, ">> hedging request after %s", c.hedgeDelay)
+			launched++
+			go launch(hedgeReader)
+		case result := <-results:
+			received++
+			if result.err == nil || received == launched {
+				return result.res, result.buf, result.err
+			}
+		}
+	}
+}
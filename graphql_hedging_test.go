@@ -0,0 +1,165 @@
+package graphql
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// hedgeCall is how the hedging test server answers a request: after delay,
+// with the number of the request, or by dropping the connection.
+type hedgeCall struct {
+	delay time.Duration
+	drop  bool
+}
+
+func hedgeServer(t *testing.T, calls, canceled *int32, behaviors []hedgeCall) *httptest.Server {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := int(atomic.AddInt32(calls, 1))
+		if strings.HasPrefix(r.Header.Get("Content-Type"), "application/json") {
+			var payload graphqlModel
+			if err := json.NewDecoder(r.Body).Decode(&payload); err != nil || payload.Query == "" {
+				http.Error(w, "bad request body", http.StatusBadRequest)
+				return
+			}
+		}
+		behavior := behaviors[len(behaviors)-1]
+		if n <= len(behaviors) {
+			behavior = behaviors[n-1]
+		}
+		select {
+		case <-r.Context().Done():
+			atomic.AddInt32(canceled, 1)
+			return
+		case <-time.After(behavior.delay):
+		}
+		if behavior.drop {
+			conn, _, err := w.(http.Hijacker).Hijack()
+			if err == nil {
+				conn.Close()
+			}
+			return
+		}
+		fmt.Fprintf(w, `{"data":{"n":%d}}`, n)
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestHedging(t *testing.T) {
+	tests := []struct {
+		name      string
+		delay     time.Duration
+		query     string
+		file      bool
+		behaviors []hedgeCall
+		// wantN is the request whose response is used.
+		wantN     int
+		wantCalls int32
+	}{
+		{
+			name:      "slow request",
+			delay:     20 * time.Millisecond,
+			query:     "{ n }",
+			behaviors: []hedgeCall{{delay: time.Second}, {}},
+			wantN:     2,
+			wantCalls: 2,
+		},
+		{
+			name:      "fast request",
+			delay:     200 * time.Millisecond,
+			query:     "{ n }",
+			behaviors: []hedgeCall{{}},
+			wantN:     1,
+			wantCalls: 1,
+		},
+		{
+			name:      "first response wins",
+			delay:     20 * time.Millisecond,
+			query:     "{ n }",
+			behaviors: []hedgeCall{{delay: 60 * time.Millisecond}, {delay: time.Second}},
+			wantN:     1,
+			wantCalls: 2,
+		},
+		{
+			name:      "failed request",
+			delay:     20 * time.Millisecond,
+			query:     "{ n }",
+			behaviors: []hedgeCall{{delay: 40 * time.Millisecond, drop: true}, {delay: 80 * time.Millisecond}},
+			wantN:     2,
+			wantCalls: 2,
+		},
+		{
+			name:      "mutation",
+			delay:     10 * time.Millisecond,
+			query:     "mutation { n }",
+			behaviors: []hedgeCall{{delay: 60 * time.Millisecond}},
+			wantN:     1,
+			wantCalls: 1,
+		},
+		{
+			name:      "files",
+			delay:     10 * time.Millisecond,
+			query:     "{ n }",
+			file:      true,
+			behaviors: []hedgeCall{{delay: 60 * time.Millisecond}},
+			wantN:     1,
+			wantCalls: 1,
+		},
+		{
+			name:      "disabled",
+			query:     "{ n }",
+			behaviors: []hedgeCall{{delay: 60 * time.Millisecond}},
+			wantN:     1,
+			wantCalls: 1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var calls, canceled int32
+			srv := hedgeServer(t, &calls, &canceled, tt.behaviors)
+			client := NewClient(srv.URL, WithHedging(tt.delay))
+			req := NewGraphqlRequest(tt.query)
+			if tt.file {
+				client = NewClient(srv.URL, WithHedging(tt.delay), UseMultipartForm())
+				req.File("upload", "a.txt", strings.NewReader("content"))
+			}
+			var data struct{ N int }
+			if _, err := client.Run(context.Background(), req, &data); err != nil {
+				t.Fatal(err)
+			}
+			if data.N != tt.wantN {
+				t.Fatalf("used the response to request %d, want %d", data.N, tt.wantN)
+			}
+			if got := atomic.LoadInt32(&calls); got != tt.wantCalls {
+				t.Fatalf("sent %d requests, want %d", got, tt.wantCalls)
+			}
+		})
+	}
+}
+
+func TestHedgingCancelsTheLosingRequest(t *testing.T) {
+	var calls, canceled int32
+	srv := hedgeServer(t, &calls, &canceled, []hedgeCall{{delay: 10 * time.Second}, {}})
+	client := NewClient(srv.URL, WithHedging(10*time.Millisecond))
+	start := time.Now()
+	if _, err := client.Run(context.Background(), NewGraphqlRequest("{ n }"), nil); err != nil {
+		t.Fatal(err)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Fatalf("waited %s for the slow request", elapsed)
+	}
+	deadline := time.Now().Add(5 * time.Second)
+	for atomic.LoadInt32(&canceled) == 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("the slow request wasn't canceled")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
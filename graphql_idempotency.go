@@ -0,0 +1,55 @@
+package graphql
+
+// DefaultIdempotencyKeyHeader is the header idempotency keys are sent in
+// when WithIdempotencyKeys is given an empty header name.
+const DefaultIdempotencyKeyHeader = "Idempotency-Key"
+
+// WithIdempotencyKeys sends an idempotency key in header with every
+// mutation, so servers can recognize retries of a mutation they already
+// applied. The key is the one set with GraphRequest.SetIdempotencyKey, or
+// a random UUID generated once per Run. Either way, every attempt of the
+// operation, retries and hedged requests included, sends the same key.
+func WithIdempotencyKeys(header string) ClientOption {
+	if header == "" {
+		header = DefaultIdempotencyKeyHeader
+	}
+	return func(client *Client) {
+		client.idempotencyKeyHeader = header
+	}
+}
+
+// SetIdempotencyKey sets the idempotency key of this request, sent in the
+// header set with WithIdempotencyKeys, or in Idempotency-Key without it.
+// Reuse the key when running the request again for the same logical
+// mutation.
+func (req *GraphRequest) SetIdempotencyKey(key string) {
+	req.idempotencyKey = key
+}
+
+// IdempotencyKey returns the key set with SetIdempotencyKey.
+func (req *GraphRequest) IdempotencyKey() string {
+	return req.idempotencyKey
+}
+
+// idempotencyKey returns the idempotency key of op, if it has one.
+func (c *Client) idempotencyKey(op *operation) string {
+	if op.req.idempotencyKey != "" {
+		return op.req.idempotencyKey
+	}
+	if c.idempotencyKeyHeader == "" {
+		return ""
+	}
+	for _, definition := range op.definitions {
+		if definition.Type == "mutation" {
+			return newUUID()
+		}
+	}
+	return ""
+}
+
+func (c *Client) idempotencyHeader() string {
+	if c.idempotencyKeyHeader == "" {
+		return DefaultIdempotencyKeyHeader
+	}
+	return c.idempotencyKeyHeader
+}
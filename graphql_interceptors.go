@@ -0,0 +1,58 @@
+package graphql
+
+import "context"
+
+// BeforeRequestFunc is called before a request is sent and may modify it,
+// e.g. to rewrite its query or add variables. It works on a copy, so the
+// caller's GraphRequest is left untouched. Returning an error aborts Run.
+type BeforeRequestFunc func(ctx context.Context, req *GraphRequest) error
+
+// AfterResponseFunc is called with the outcome of a request: the response,
+// if one was decoded, and the error Run is about to return. The error it
+// returns replaces the one Run returns.
+type AfterResponseFunc func(ctx context.Context, req *GraphRequest, resp *GraphResponse, err error) error
+
+// WithBeforeRequest adds interceptors called, in order, before every
+// request is sent.
+func WithBeforeRequest(interceptors ...BeforeRequestFunc) ClientOption {
+	return func(client *Client) {
+		client.beforeRequest = append(client.beforeRequest, interceptors...)
+	}
+}
+
+// WithAfterResponse adds interceptors called, in order, with the outcome of
+// every request.
+//
+//	NewClient(url, WithAfterResponse(func(ctx context.Context, req *GraphRequest, resp *GraphResponse, err error) error {
+//		if err == nil && len(resp.Errors) > 0 {
+//			return resp.Errors[0]
+//		}
+//		return err
+//	}))
+func WithAfterResponse(interceptors ...AfterResponseFunc) ClientOption {
+	return func(client *Client) {
+		client.afterResponse = append(client.afterResponse, interceptors...)
+	}
+}
+
+// interceptRequest runs the BeforeRequest interceptors on a copy of req.
+func (c *Client) interceptRequest(ctx context.Context, req *GraphRequest) (*GraphRequest, error) {
+	if len(c.beforeRequest) == 0 {
+		return req, nil
+	}
+	req = req.Clone()
+	for _, interceptor := range c.beforeRequest {
+		if err := interceptor(ctx, req); err != nil {
+			return nil, err
+		}
+	}
+	return req, nil
+}
+
+// interceptResponse runs the AfterResponse interceptors.
+func (c *Client) interceptResponse(ctx context.Context, req *GraphRequest, graphResponse *GraphResponse, err error) error {
+	for _, interceptor := range c.afterResponse {
+		err = interceptor(ctx, req, graphResponse, err)
+	}
+	return err
+}
@@ -0,0 +1,192 @@
+package graphql
+
+import (
+	"fmt"
+	"strings"
+)
+
+type tokenKind int
+
+const (
+	tokenEOF tokenKind = iota
+	tokenPunctuator
+	tokenName
+	tokenInt
+	tokenFloat
+	tokenString
+	tokenBlockString
+)
+
+// token is a lexical token of a GraphQL document. Its text is the source
+// text of the token, quotes included for strings.
+type token struct {
+	kind tokenKind
+	text string
+	pos  int
+}
+
+// lexer splits a GraphQL document into tokens, skipping whitespace,
+// commas and comments.
+type lexer struct {
+	src string
+	pos int
+}
+
+func newLexer(src string) *lexer {
+	return &lexer{src: src}
+}
+
+func (l *lexer) errorf(pos int, format string, args ...interface{}) error {
+	location := locate(l.src, pos)
+	return fmt.Errorf("graphql: syntax error at %d:%d: %s", location.Line, location.Column, fmt.Sprintf(format, args...))
+}
+
+// locate returns the line and column of the byte at pos in src.
+func locate(src string, pos int) Location {
+	location := Location{Line: 1, Column: 1}
+	for i := 0; i < pos && i < len(src); i++ {
+		if src[i] == '\n' {
+			location.Line, location.Column = location.Line+1, 1
+			continue
+		}
+		location.Column++
+	}
+	return location
+}
+
+// next returns the next token, or a token of kind tokenEOF at the end of
+// the document.
+func (l *lexer) next() (token, error) {
+	l.skipIgnored()
+	if l.pos >= len(l.src) {
+		return token{kind: tokenEOF, pos: l.pos}, nil
+	}
+	start := l.pos
+	ch := l.src[l.pos]
+	switch {
+	case strings.IndexByte("!$&()=:@[]{}|", ch) >= 0:
+		l.pos++
+		return token{kind: tokenPunctuator, text: l.src[start:l.pos], pos: start}, nil
+	case ch == '.':
+		if !strings.HasPrefix(l.src[l.pos:], "...") {
+			return token{}, l.errorf(start, "unexpected %q", ch)
+		}
+		l.pos += 3
+		return token{kind: tokenPunctuator, text: "...", pos: start}, nil
+	case isNameStart(ch):
+		for l.pos < len(l.src) && isNameContinue(l.src[l.pos]) {
+			l.pos++
+		}
+		return token{kind: tokenName, text: l.src[start:l.pos], pos: start}, nil
+	case ch == '-' || isDigit(ch):
+		return l.number()
+	case ch == '"':
+		if strings.HasPrefix(l.src[l.pos:], `"""`) {
+			return l.blockString()
+		}
+		return l.string()
+	}
+	return token{}, l.errorf(start, "unexpected %q", ch)
+}
+
+func (l *lexer) skipIgnored() {
+	for l.pos < len(l.src) {
+		switch l.src[l.pos] {
+		case ' ', '\t', '\n', '\r', ',':
+			l.pos++
+		case '\xef':
+			if !strings.HasPrefix(l.src[l.pos:], "\ufeff") {
+				return
+			}
+			l.pos += len("\ufeff")
+		case '#':
+			for l.pos < len(l.src) && l.src[l.pos] != '\n' && l.src[l.pos] != '\r' {
+				l.pos++
+			}
+		default:
+			return
+		}
+	}
+}
+
+func (l *lexer) number() (token, error) {
+	start := l.pos
+	kind := tokenInt
+	if l.src[l.pos] == '-' {
+		l.pos++
+	}
+	if !l.digits() {
+		return token{}, l.errorf(l.pos, "invalid number")
+	}
+	if l.pos < len(l.src) && l.src[l.pos] == '.' {
+		kind = tokenFloat
+		l.pos++
+		if !l.digits() {
+			return token{}, l.errorf(l.pos, "invalid number")
+		}
+	}
+	if l.pos < len(l.src) && (l.src[l.pos] == 'e' || l.src[l.pos] == 'E') {
+		kind = tokenFloat
+		l.pos++
+		if l.pos < len(l.src) && (l.src[l.pos] == '+' || l.src[l.pos] == '-') {
+			l.pos++
+		}
+		if !l.digits() {
+			return token{}, l.errorf(l.pos, "invalid number")
+		}
+	}
+	if l.pos < len(l.src) && (isNameStart(l.src[l.pos]) || l.src[l.pos] == '.') {
+		return token{}, l.errorf(l.pos, "invalid number")
+	}
+	return token{kind: kind, text: l.src[start:l.pos], pos: start}, nil
+}
+
+func (l *lexer) digits() bool {
+	start := l.pos
+	for l.pos < len(l.src) && isDigit(l.src[l.pos]) {
+		l.pos++
+	}
+	return l.pos > start
+}
+
+func (l *lexer) string() (token, error) {
+	start := l.pos
+	for l.pos++; l.pos < len(l.src); l.pos++ {
+		switch l.src[l.pos] {
+		case '\\':
+			l.pos++
+		case '\n', '\r':
+			return token{}, l.errorf(start, "unterminated string")
+		case '"':
+			l.pos++
+			return token{kind: tokenString, text: l.src[start:l.pos], pos: start}, nil
+		}
+	}
+	return token{}, l.errorf(start, "unterminated string")
+}
+
+func (l *lexer) blockString() (token, error) {
+	start := l.pos
+	for l.pos += 3; l.pos < len(l.src); l.pos++ {
+		switch {
+		case strings.HasPrefix(l.src[l.pos:], `\"""`):
+			l.pos += 3
+		case strings.HasPrefix(l.src[l.pos:], `"""`):
+			l.pos += 3
+			return token{kind: tokenBlockString, text: l.src[start:l.pos], pos: start}, nil
+		}
+	}
+	return token{}, l.errorf(start, "unterminated block string")
+}
+
+func isNameStart(ch byte) bool {
+	return ch == '_' || (ch >= 'a' && ch <= 'z') || (ch >= 'A' && ch <= 'Z')
+}
+
+func isNameContinue(ch byte) bool {
+	return isNameStart(ch) || isDigit(ch)
+}
+
+func isDigit(ch byte) bool {
+	return ch >= '0' && ch <= '9'
+}
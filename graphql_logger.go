@@ -0,0 +1,90 @@
+package graphql
+
+import (
+	"context"
+	"log/slog"
+	"sync/atomic"
+	"time"
+)
+
+// LogEntry is the structured record of an operation run by the Client.
+type LogEntry struct {
+	// OperationName is the name of the operation, if the query names it.
+	OperationName string
+	// RequestID is set when request IDs are enabled with WithRequestID.
+	RequestID string
+	// Label is the label set with GraphRequest.SetLabel.
+	Label    string
+	Duration time.Duration
+	// Attempts is the number of HTTP requests sent, including retries.
+	Attempts int
+	// StatusCode is the HTTP status of the last response, or zero if
+	// none was received.
+	StatusCode    int
+	RequestBytes  int64
+	ResponseBytes int
+	Err           error
+}
+
+// Logger receives a structured entry for every operation run by the
+// Client.
+type Logger interface {
+	LogOperation(ctx context.Context, entry LogEntry)
+}
+
+// LoggerFunc is an adapter to use an ordinary function as a Logger.
+type LoggerFunc func(ctx context.Context, entry LogEntry)
+
+// LogOperation calls f(ctx, entry).
+func (f LoggerFunc) LogOperation(ctx context.Context, entry LogEntry) {
+	f(ctx, entry)
+}
+
+// WithLogger sends a structured entry for every operation to logger.
+//
+//	NewClient(url, WithLogger(NewSlogLogger(slog.Default())))
+func WithLogger(logger Logger) ClientOption {
+	return func(client *Client) {
+		client.logger = logger
+	}
+}
+
+// NewSlogLogger returns a Logger that writes entries to logger, at the
+// info level or at the error level for failed operations.
+func NewSlogLogger(logger *slog.Logger) Logger {
+	return LoggerFunc(func(ctx context.Context, entry LogEntry) {
+		level := slog.LevelInfo
+		attrs := []slog.Attr{
+			slog.String("operation", entry.OperationName),
+			slog.String("request_id", entry.RequestID),
+			slog.String("label", entry.Label),
+			slog.Duration("duration", entry.Duration),
+			slog.Int("attempts", entry.Attempts),
+			slog.Int("status", entry.StatusCode),
+			slog.Int64("request_bytes", entry.RequestBytes),
+			slog.Int("response_bytes", entry.ResponseBytes),
+		}
+		if entry.Err != nil {
+			level = slog.LevelError
+			attrs = append(attrs, slog.String("error", entry.Err.Error()))
+		}
+		logger.LogAttrs(ctx, level, "graphql operation", attrs...)
+	})
+}
+
+func (c *Client) logOperation(ctx context.Context, op *operation, err error) {
+	if c.logger == nil {
+		return
+	}
+	c.logger.LogOperation(ctx, LogEntry{
+		OperationName: op.name,
+		RequestID:     op.requestID,
+		Label:         op.req.label,
+		Duration:      time.Since(op.start),
+		Attempts:      op.attempts,
+		StatusCode:    op.statusCode,
+		RequestBytes:  atomic.LoadInt64(&op.requestBytes),
+		ResponseBytes: op.responseBytes,
+		Err:           err,
+	})
+}
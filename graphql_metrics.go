@@ -0,0 +1,62 @@
+package graphql
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// OperationMetrics are the measurements of a single operation, labeled by
+// its OperationInfo.
+type OperationMetrics struct {
+	OperationInfo
+	Duration      time.Duration
+	StatusCode    int
+	RequestBytes  int64
+	ResponseBytes int
+	// GraphQLErrors is the number of errors in the response.
+	GraphQLErrors int
+	// Failed is true when Run returned an error.
+	Failed bool
+	// FromCache is true when the response came from the response cache.
+	FromCache bool
+}
+
+// MetricsCollector records the metrics of every operation run by the
+// Client. It is meant to be adapted to a metrics backend, e.g. with
+// Prometheus:
+//
+//	type promCollector struct{ requests *prometheus.CounterVec; latency *prometheus.HistogramVec }
+//
+//	func (p promCollector) ObserveOperation(m graphql.OperationMetrics) {
+//		p.requests.WithLabelValues(m.Name, m.Endpoint, strconv.FormatBool(m.Failed)).Inc()
+//		p.latency.WithLabelValues(m.Name, m.Endpoint).Observe(m.Duration.Seconds())
+//	}
+type MetricsCollector interface {
+	ObserveOperation(metrics OperationMetrics)
+}
+
+// WithMetrics reports the metrics of every operation to collector.
+func WithMetrics(collector MetricsCollector) ClientOption {
+	return func(client *Client) {
+		client.metrics = collector
+	}
+}
+
+func (c *Client) recordMetrics(op *operation, graphResponse *GraphResponse, err error) {
+	if c.metrics == nil {
+		return
+	}
+	metrics := OperationMetrics{
+		OperationInfo: c.operationInfo(op),
+		Duration:      time.Since(op.start),
+		StatusCode:    op.statusCode,
+		RequestBytes:  atomic.LoadInt64(&op.requestBytes),
+		ResponseBytes: op.responseBytes,
+		Failed:        err != nil,
+	}
+	if graphResponse != nil {
+		metrics.GraphQLErrors = len(graphResponse.Errors)
+		metrics.FromCache = graphResponse.FromCache
+	}
+	c.metrics.ObserveOperation(metrics)
+}
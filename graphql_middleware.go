@@ -0,0 +1,39 @@
+package graphql
+
+import "net/http"
+
+// HTTPDoerFunc is an adapter to use an ordinary function as an HTTPDoer.
+type HTTPDoerFunc func(req *http.Request) (*http.Response, error)
+
+// Do calls f(req).
+func (f HTTPDoerFunc) Do(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+// Middleware wraps the HTTPDoer the Client sends requests with.
+type Middleware func(next HTTPDoer) HTTPDoer
+
+// WithMiddleware wraps the client's HTTPDoer with middleware. The first
+// middleware is the outermost one, it sees requests first and responses
+// last. Calling WithMiddleware more than once appends to the chain.
+//
+//	logRequests := func(next HTTPDoer) HTTPDoer {
+//		return HTTPDoerFunc(func(r *http.Request) (*http.Response, error) {
+//			log.Println(r.Method, r.URL)
+//			return next.Do(r)
+//		})
+//	}
+//	NewClient(url, WithMiddleware(logRequests))
+func WithMiddleware(middleware ...Middleware) ClientOption {
+	return func(client *Client) {
+		client.middleware = append(client.middleware, middleware...)
+	}
+}
+
+// chain wraps doer with middleware, the first one being the outermost.
+func chain(doer HTTPDoer, middleware []Middleware) HTTPDoer {
+	for i := len(middleware) - 1; i >= 0; i-- {
+		doer = middleware[i](doer)
+	}
+	return doer
+}
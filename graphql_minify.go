@@ -0,0 +1,53 @@
+package graphql
+
+import "strings"
+
+// WithQueryMinification strips comments and insignificant whitespace and
+// commas from queries before they are sent, shrinking large generated
+// documents. Queries that can't be tokenized are sent as they are.
+func WithQueryMinification() ClientOption {
+	return func(client *Client) {
+		client.minifyQueries = true
+	}
+}
+
+// minifyQuery returns query without its ignored tokens, with a single
+// space only between tokens that would otherwise merge, the way
+// graphql-js's stripIgnoredCharacters does. String and block string
+// tokens are kept verbatim.
+func minifyQuery(query string) (string, error) {
+	var b strings.Builder
+	b.Grow(len(query))
+	l := newLexer(query)
+	separate := false
+	for {
+		tok, err := l.next()
+		if err != nil {
+			return "", err
+		}
+		if tok.kind == tokenEOF {
+			return b.String(), nil
+		}
+		nonPunctuator := tok.kind != tokenPunctuator
+		if separate && (nonPunctuator || tok.text == "...") {
+			b.WriteByte(' ')
+		}
+		b.WriteString(tok.text)
+		separate = nonPunctuator
+	}
+}
+
+// withMinifiedQuery returns req with its query minified, if the client
+// minifies queries.
+func (c *Client) withMinifiedQuery(req *GraphRequest) *GraphRequest {
+	if !c.minifyQueries {
+		return req
+	}
+	minified, err := minifyQuery(req.query)
+	if err != nil || minified == req.query {
+		return req
+	}
+	req = req.Clone()
+	req.query = minified
+	return req
+}
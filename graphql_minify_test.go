@@ -0,0 +1,59 @@
+package graphql
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestMinifyQuery(t *testing.T) {
+	tests := []struct {
+		name  string
+		query string
+		want  string
+		// wantErr is a part of the expected error, empty for none.
+		wantErr string
+	}{
+		{name: "whitespace and commas", query: "query Q {\n\ta,\n\tb\n}", want: "query Q{a b}"},
+		{name: "comments", query: "# header\n{ a # trailing\n  b }", want: "{a b}"},
+		{name: "arguments", query: `{ a(n: 1, m: -2.5e3, e: ENUM, l: [1, 2]) }`, want: `{a(n:1 m:-2.5e3 e:ENUM l:[1 2])}`},
+		{name: "variables and directives", query: `query ($a: Int = 1, $b: [ID!]!) @d { x @include(if: true) }`, want: `query($a:Int=1$b:[ID!]!)@d{x@include(if:true)}`},
+		{name: "spreads", query: `{ ...F ... on T { a } } fragment F on T { b }`, want: `{...F ...on T{a}}fragment F on T{b}`},
+		{name: "strings", query: `{ a(s: "x  , # y") }`, want: `{a(s:"x  , # y")}`},
+		{name: "block strings", query: "{ a(s: \"\"\" x\n  # y \"\"\") }", want: "{a(s:\"\"\" x\n  # y \"\"\")}"},
+		{name: "already minified", query: `{a b}`, want: `{a b}`},
+		{name: "unterminated string", query: `{ a(s: "x) }`, wantErr: "syntax error"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := minifyQuery(tt.query)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("got %q and error %v, want an error containing %q", got, err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Fatalf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWithQueryMinification(t *testing.T) {
+	srv := newPayloadServer(t, `{"a":1}`)
+	query := "query Q {\n  a # the answer\n}"
+	req := NewGraphqlRequest(query)
+	if _, err := NewClient(srv.URL, WithQueryMinification()).Run(context.Background(), req, nil); err != nil {
+		t.Fatal(err)
+	}
+	if got := srv.last().Query; got != "query Q{a}" {
+		t.Fatalf("sent %q", got)
+	}
+	if req.Query() != query {
+		t.Fatalf("changed the query of the request to %q", req.Query())
+	}
+}
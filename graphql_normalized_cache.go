@@ -0,0 +1,495 @@
+package graphql
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/pkg/errors"
+)
+
+// rootQueryKey is the key of the record holding the root fields of queries.
+const rootQueryKey = "ROOT_QUERY"
+
+// NormalizedCacheConfig configures a NormalizedCache.
+type NormalizedCacheConfig struct {
+	// KeyFields are the fields identifying the objects of each type, by
+	// type name. Objects of other types are identified by their id field.
+	KeyFields map[string][]string
+	// PossibleTypes are the object types of interfaces and unions, by
+	// name, for fragments on them to be read from the cache. The types of
+	// the schema set with WithSchema are known without it.
+	PossibleTypes map[string][]string
+}
+
+// NormalizedCache stores the objects of responses by type and key rather
+// than by query, like the Apollo cache: an object fetched by one query
+// answers the fields of other queries selecting it, and updates of an
+// object, e.g. returned by a mutation, are seen by all of them.
+//
+// Objects are identified by their __typename and key fields, which queries
+// must select for them to be shared. Objects without them are stored
+// within their parent.
+type NormalizedCache struct {
+	config NormalizedCacheConfig
+
+	mu      sync.RWMutex
+	records map[string]map[string]interface{}
+}
+
+// entityRef is a reference to the record of an object.
+type entityRef string
+
+// NewNormalizedCache returns an empty NormalizedCache.
+func NewNormalizedCache(config NormalizedCacheConfig) *NormalizedCache {
+	return &NormalizedCache{
+		config:  config,
+		records: make(map[string]map[string]interface{}),
+	}
+}
+
+// WithNormalizedCache answers queries from cache when it holds all of the
+// fields they select, and stores the data of responses without errors in
+// it. Responses answered from cache have GraphResponse.FromCache set.
+//
+// The objects of the cache are shared by all callers of the client, so
+// requests authenticated by an AuthProvider, set with WithAuth or SetAuth,
+// or carrying their own Authorization or Cookie header, neither read nor
+// write it.
+func WithNormalizedCache(cache *NormalizedCache) ClientOption {
+	return func(client *Client) {
+		client.normalized = cache
+	}
+}
+
+// Key returns the key of the object of type typename with the given values
+// of its key fields: "User:42" for a user with ID 42, or
+// `Book:{"isbn":"0262510871","lang":"en"}` for a type with several key
+// fields.
+func (n *NormalizedCache) Key(typename string, keyValues ...interface{}) string {
+	if len(keyValues) == 1 {
+		return typename + ":" + keyString(keyValues[0])
+	}
+	fields := n.keyFields(typename)
+	values := make(map[string]interface{}, len(fields))
+	for i, field := range fields {
+		if i < len(keyValues) {
+			values[field] = keyValues[i]
+		}
+	}
+	encoded, _ := json.Marshal(values)
+	return typename + ":" + string(encoded)
+}
+
+// Evict removes the object with the given key, as returned by Key, so the
+// queries selecting it are sent to the server again.
+func (n *NormalizedCache) Evict(key string) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	delete(n.records, key)
+}
+
+// EvictType removes all objects of type typename.
+func (n *NormalizedCache) EvictType(typename string) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	for key := range n.records {
+		if strings.HasPrefix(key, typename+":") {
+			delete(n.records, key)
+		}
+	}
+}
+
+// EvictRootField removes the root query field named name, with all of its
+// arguments, e.g. a list a mutation added an object to.
+func (n *NormalizedCache) EvictRootField(name string) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	root := n.records[rootQueryKey]
+	for key := range root {
+		if key == name || strings.HasPrefix(key, name+"(") {
+			delete(root, key)
+		}
+	}
+}
+
+// Clear removes everything from the cache.
+func (n *NormalizedCache) Clear() {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.records = make(map[string]map[string]interface{})
+}
+
+// Read answers the query of req from the cache, as much as it can. It
+// returns the data the cache holds, and the paths of the fields it
+// doesn't, which are missing from the data.
+func (n *NormalizedCache) Read(req *GraphRequest) (map[string]interface{}, []Path, error) {
+	return n.read(req, nil)
+}
+
+func (n *NormalizedCache) read(req *GraphRequest, schema *Schema) (map[string]interface{}, []Path, error) {
+	walk, err := newCacheWalk(n, req, schema)
+	if err != nil {
+		return nil, nil, err
+	}
+	if walk.operation.typ != "query" {
+		return nil, nil, fmt.Errorf("graphql: cannot read a %s from cache", walk.operation.typ)
+	}
+	n.mu.RLock()
+	defer n.mu.RUnlock()
+	data := make(map[string]interface{})
+	walk.readSelections(n.records[rootQueryKey], walk.operation.selections, nil, data)
+	return data, walk.missing, nil
+}
+
+// write stores the data of body, the response to req.
+func (n *NormalizedCache) write(req *GraphRequest, body []byte) error {
+	walk, err := newCacheWalk(n, req, nil)
+	if err != nil {
+		return err
+	}
+	var payload struct {
+		Data map[string]interface{}
+	}
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	decoder.UseNumber()
+	if err := decoder.Decode(&payload); err != nil {
+		return err
+	}
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	// only the objects returned by mutations and subscriptions are stored
+	root := make(map[string]interface{})
+	if walk.operation.typ == "query" {
+		if root = n.records[rootQueryKey]; root == nil {
+			root = make(map[string]interface{})
+			n.records[rootQueryKey] = root
+		}
+	}
+	walk.writeSelections(root, payload.Data, walk.operation.selections)
+	return nil
+}
+
+func (n *NormalizedCache) keyFields(typename string) []string {
+	if fields, ok := n.config.KeyFields[typename]; ok {
+		return fields
+	}
+	return []string{"id"}
+}
+
+// entityKey returns the key of object, or "" if it has no __typename or
+// key fields.
+func (n *NormalizedCache) entityKey(object map[string]interface{}) string {
+	typename, ok := object["__typename"].(string)
+	if !ok {
+		return ""
+	}
+	fields := n.keyFields(typename)
+	values := make([]interface{}, len(fields))
+	for i, field := range fields {
+		value, ok := object[field]
+		if !ok || value == nil {
+			return ""
+		}
+		values[i] = value
+	}
+	return n.Key(typename, values...)
+}
+
+func keyString(value interface{}) string {
+	switch value := value.(type) {
+	case string:
+		return value
+	case json.Number:
+		return value.String()
+	}
+	encoded, _ := json.Marshal(value)
+	return string(encoded)
+}
+
+// cacheWalk reads or writes the fields an operation selects.
+type cacheWalk struct {
+	cache     *NormalizedCache
+	schema    *Schema
+	operation *operationNode
+	fragments map[string]*fragmentNode
+	vars      map[string]interface{}
+	missing   []Path
+}
+
+func newCacheWalk(n *NormalizedCache, req *GraphRequest, schema *Schema) (*cacheWalk, error) {
+	doc, err := parseQuery(req.query)
+	if err != nil {
+		return nil, err
+	}
+	operation := doc.operation(req.operationName)
+	if operation == nil {
+		return nil, fmt.Errorf("graphql: unknown operation %q", req.operationName)
+	}
+	w := &cacheWalk{
+		cache:     n,
+		schema:    schema,
+		operation: operation,
+		fragments: make(map[string]*fragmentNode),
+		vars:      make(map[string]interface{}),
+	}
+	for _, fragment := range doc.fragments {
+		w.fragments[fragment.name] = fragment
+	}
+	for _, variable := range operation.variables {
+		if variable.defaultValue != nil {
+			w.vars[variable.name] = w.literal(variable.defaultValue)
+		}
+	}
+	if len(req.vars) > 0 {
+		encoded, err := json.Marshal(req.vars)
+		if err != nil {
+			return nil, errors.Wrap(err, "encode variables")
+		}
+		decoder := json.NewDecoder(bytes.NewReader(encoded))
+		decoder.UseNumber()
+		if err := decoder.Decode(&w.vars); err != nil {
+			return nil, errors.Wrap(err, "decode variables")
+		}
+	}
+	return w, nil
+}
+
+// literal returns the JSON value of value.
+func (w *cacheWalk) literal(value *valueNode) interface{} {
+	switch value.kind {
+	case valueVariable:
+		return w.vars[value.text]
+	case valueInt, valueFloat:
+		return json.Number(value.text)
+	case valueString, valueEnum:
+		return value.text
+	case valueBoolean:
+		return value.text == "true"
+	case valueList:
+		list := make([]interface{}, len(value.list))
+		for i, item := range value.list {
+			list[i] = w.literal(item)
+		}
+		return list
+	case valueObject:
+		object := make(map[string]interface{}, len(value.fields))
+		for _, field := range value.fields {
+			object[field.name] = w.literal(field.value)
+		}
+		return object
+	}
+	return nil
+}
+
+// storageKey returns the key of the field selected by selection in its
+// record: its name, followed by its arguments if it has any.
+func (w *cacheWalk) storageKey(selection *selectionNode) string {
+	if len(selection.arguments) == 0 {
+		return selection.name
+	}
+	arguments := make(map[string]interface{}, len(selection.arguments))
+	for _, argument := range selection.arguments {
+		arguments[argument.name] = w.literal(argument.value)
+	}
+	encoded, _ := json.Marshal(arguments)
+	return selection.name + "(" + string(encoded) + ")"
+}
+
+// fragmentApplies reports whether a fragment on typeCondition applies to
+// the object of type typename, and whether that is known.
+func (w *cacheWalk) fragmentApplies(typeCondition, typename string) (bool, bool) {
+	if typeCondition == "" || typeCondition == typename {
+		return true, true
+	}
+	if typename == "" {
+		return false, false
+	}
+	if possibleTypes, ok := w.cache.config.PossibleTypes[typeCondition]; ok {
+		for _, possibleType := range possibleTypes {
+			if possibleType == typename {
+				return true, true
+			}
+		}
+		return false, true
+	}
+	if w.schema != nil {
+		if t := w.schema.types[typeCondition]; t != nil {
+			return t.possibleTypes[typename], true
+		}
+	}
+	return false, false
+}
+
+func (w *cacheWalk) writeSelections(record, object map[string]interface{}, selections []*selectionNode) {
+	for _, selection := range selections {
+		switch selection.kind {
+		case selectionField:
+			value, ok := object[selection.responseKey()]
+			if !ok {
+				continue
+			}
+			key := w.storageKey(selection)
+			record[key] = w.writeValue(value, selection.selections, record[key])
+		case selectionFragmentSpread:
+			if fragment := w.fragments[selection.name]; fragment != nil {
+				w.writeSelections(record, object, fragment.selections)
+			}
+		case selectionInlineFragment:
+			w.writeSelections(record, object, selection.selections)
+		}
+	}
+}
+
+// writeValue returns what to store for value, selected with selections,
+// in place of existing.
+func (w *cacheWalk) writeValue(value interface{}, selections []*selectionNode, existing interface{}) interface{} {
+	if len(selections) == 0 {
+		return value
+	}
+	switch value := value.(type) {
+	case []interface{}:
+		list := make([]interface{}, len(value))
+		for i, item := range value {
+			list[i] = w.writeValue(item, selections, nil)
+		}
+		return list
+	case map[string]interface{}:
+		if key := w.cache.entityKey(value); key != "" {
+			record := w.cache.records[key]
+			if record == nil {
+				record = make(map[string]interface{})
+				w.cache.records[key] = record
+			}
+			w.writeSelections(record, value, selections)
+			return entityRef(key)
+		}
+		// objects without a key are stored within their parent
+		record := make(map[string]interface{})
+		if previous, ok := existing.(map[string]interface{}); ok {
+			for field, fieldValue := range previous {
+				record[field] = fieldValue
+			}
+		}
+		w.writeSelections(record, value, selections)
+		return record
+	}
+	return value
+}
+
+func (w *cacheWalk) readSelections(record map[string]interface{}, selections []*selectionNode, path Path, out map[string]interface{}) {
+	typename, _ := record["__typename"].(string)
+	for _, selection := range selections {
+		included, known := selectionIncluded(selection.directives, w.vars)
+		if !known {
+			w.missing = append(w.missing, append(Path(nil), path...))
+			continue
+		}
+		if !included {
+			continue
+		}
+		switch selection.kind {
+		case selectionField:
+			fieldPath := append(append(Path(nil), path...), KeySegment(selection.responseKey()))
+			value, ok := record[w.storageKey(selection)]
+			if !ok {
+				w.missing = append(w.missing, fieldPath)
+				continue
+			}
+			out[selection.responseKey()] = w.readValue(value, selection.selections, fieldPath)
+		case selectionFragmentSpread:
+			fragment := w.fragments[selection.name]
+			if fragment == nil {
+				continue
+			}
+			w.readFragment(record, fragment.typeCondition, typename, fragment.selections, path, out)
+		case selectionInlineFragment:
+			w.readFragment(record, selection.typeCondition, typename, selection.selections, path, out)
+		}
+	}
+}
+
+func (w *cacheWalk) readFragment(record map[string]interface{}, typeCondition, typename string, selections []*selectionNode, path Path, out map[string]interface{}) {
+	applies, known := w.fragmentApplies(typeCondition, typename)
+	if !known {
+		w.missing = append(w.missing, append(Path(nil), path...))
+		return
+	}
+	if applies {
+		w.readSelections(record, selections, path, out)
+	}
+}
+
+func (w *cacheWalk) readValue(value interface{}, selections []*selectionNode, path Path) interface{} {
+	if len(selections) == 0 {
+		return value
+	}
+	switch value := value.(type) {
+	case []interface{}:
+		list := make([]interface{}, len(value))
+		for i, item := range value {
+			list[i] = w.readValue(item, selections, append(append(Path(nil), path...), IndexSegment(i)))
+		}
+		return list
+	case entityRef:
+		record, ok := w.cache.records[string(value)]
+		if !ok {
+			w.missing = append(w.missing, path)
+			return nil
+		}
+		object := make(map[string]interface{})
+		w.readSelections(record, selections, path, object)
+		return object
+	case map[string]interface{}:
+		object := make(map[string]interface{})
+		w.readSelections(value, selections, path, object)
+		return object
+	}
+	return value
+}
+
+// normalizedResponse returns the response to op from the normalized
+// cache, decoded into graphqlResponse, if it holds all of its fields.
+func (c *Client) normalizedResponse(op *operation, graphqlResponse interface{}) (*GraphResponse, bool) {
+	if c.normalized == nil || op.hasSideEffects() || len(op.req.files) > 0 || op.stream != nil || c.hasCallerCredentials(op.req) {
+		return nil, false
+	}
+	data, missing, err := c.normalized.read(op.req, c.schema)
+	if err != nil || len(missing) > 0 {
+		return nil, false
+	}
+	body, err := json.Marshal(map[string]interface{}{"data": data})
+	if err != nil {
+		return nil, false
+	}
+	graphResponse, err := c.decodeCacheEntry(op, &cacheEntry{Header: http.Header{}, Body: body}, graphqlResponse)
+	if err != nil {
+		return nil, false
+	}
+	c.logf(op, LogLevelDebug, LogWire, "<< normalized cache response")
+	return graphResponse, true
+}
+
+// normalizeResponse stores body, the response to op, in the normalized
+// cache unless it has errors.
+func (c *Client) normalizeResponse(op *operation, graphResponse *GraphResponse, body []byte) {
+	if c.normalized == nil || len(graphResponse.Errors) > 0 || graphResponse.NoData || op.stream != nil || op.req.cacheControl.NoCache {
+		return
+	}
+	if c.hasCallerCredentials(op.req) {
+		return
+	}
+	if err := c.normalized.write(op.req, body); err != nil {
+		c.logf(op, LogLevelInfo, LogWire, "<< normalized cache: %v", err)
+	}
+}
+
+// hasCallerCredentials reports whether req may be authenticated as a
+// different caller than other requests of the client: by an AuthProvider,
+// whose credentials may depend on the context, or by its own headers.
+func (c *Client) hasCallerCredentials(req *GraphRequest) bool {
+	return c.authProvider(req) != nil || req.Header.Get("Authorization") != "" || req.Header.Get("Cookie") != ""
+}
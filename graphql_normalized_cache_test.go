@@ -0,0 +1,350 @@
+package graphql
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+const normalizedViewerQuery = "{ viewer { __typename id name } }"
+
+// normalizedServer answers with a user named after the Authorization header
+// of the request.
+func normalizedServer(t *testing.T, calls *int32) *httptest.Server {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(calls, 1)
+		fmt.Fprintf(w, `{"data":{"viewer":{"__typename":"User","id":"1","name":%q}}}`, r.Header.Get("Authorization"))
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+type normalizedViewer struct {
+	Viewer struct {
+		ID   string
+		Name string
+	}
+}
+
+func TestNormalizedCacheAnswersQueries(t *testing.T) {
+	var calls int32
+	srv := normalizedServer(t, &calls)
+	client := NewClient(srv.URL, WithNormalizedCache(NewNormalizedCache(NormalizedCacheConfig{})))
+	for i := 0; i < 2; i++ {
+		var data normalizedViewer
+		res, err := client.Run(context.Background(), NewGraphqlRequest(normalizedViewerQuery), &data)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if res.FromCache != (i == 1) || data.Viewer.ID != "1" {
+			t.Fatalf("run %d: got %+v, FromCache = %t", i, data, res.FromCache)
+		}
+	}
+	if calls != 1 {
+		t.Fatalf("server got %d requests, want 1", calls)
+	}
+}
+
+func TestNormalizedCacheSkipsCallerCredentials(t *testing.T) {
+	type userKey struct{}
+	auth := AuthProviderFunc(func(ctx context.Context, r *http.Request) error {
+		r.Header.Set("Authorization", ctx.Value(userKey{}).(string))
+		return nil
+	})
+	tests := map[string]func(req *GraphRequest, user string){
+		"client auth provider": nil,
+		"request auth provider": func(req *GraphRequest, user string) {
+			req.SetAuth(auth)
+		},
+		"request header": func(req *GraphRequest, user string) {
+			req.Header.Set("Authorization", user)
+		},
+	}
+	for name, setup := range tests {
+		t.Run(name, func(t *testing.T) {
+			var calls int32
+			srv := normalizedServer(t, &calls)
+			opts := []ClientOption{WithNormalizedCache(NewNormalizedCache(NormalizedCacheConfig{}))}
+			if setup == nil {
+				opts = append(opts, WithAuth(auth))
+			}
+			client := NewClient(srv.URL, opts...)
+			for _, user := range []string{"alice", "bob"} {
+				req := NewGraphqlRequest(normalizedViewerQuery)
+				if setup != nil {
+					setup(req, user)
+				}
+				ctx := context.WithValue(context.Background(), userKey{}, user)
+				var data normalizedViewer
+				res, err := client.Run(ctx, req, &data)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if data.Viewer.Name != user || res.FromCache {
+					t.Fatalf("got %q from cache %t, want %q from the server", data.Viewer.Name, res.FromCache, user)
+				}
+			}
+		})
+	}
+}
+
+// normalizedStep is a request run against a normalized cache, or a change
+// of the cache made before it.
+type normalizedStep struct {
+	query string
+	vars  map[string]interface{}
+	// before, if set, is called with the cache before the request.
+	before    func(n *NormalizedCache)
+	fromCache bool
+	// want is the JSON of the data of the response.
+	want string
+}
+
+// canonicalJSON re-encodes s with sorted keys and without spaces.
+func canonicalJSON(t *testing.T, s string) string {
+	t.Helper()
+	var v interface{}
+	if err := json.Unmarshal([]byte(s), &v); err != nil {
+		t.Fatalf("invalid JSON %s: %v", s, err)
+	}
+	encoded, err := json.Marshal(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(encoded)
+}
+
+func TestNormalizedCache(t *testing.T) {
+	const (
+		userQuery  = `{ user(id: 1) { __typename id name } }`
+		userData   = `{"user":{"__typename":"User","id":"1","name":"Ann"}}`
+		viewerName = `{ viewer { __typename id name } }`
+	)
+	bodies := map[string]string{
+		userQuery:                                                        `{"data":` + userData + `}`,
+		`{ user(id: 2) { __typename id name } }`:                         `{"data":{"user":{"__typename":"User","id":"2","name":"Bob"}}}`,
+		`{ user(id: 1) { __typename id name email } }`:                   `{"data":{"user":{"__typename":"User","id":"1","name":"Ann","email":"ann@example.com"}}}`,
+		`query($id: ID!) { user(id: $id) { __typename id name } }`:       `{"data":` + userData + `}`,
+		`{ viewer { __typename id } }`:                                   `{"data":{"viewer":{"__typename":"User","id":"1"}}}`,
+		viewerName:                                                       `{"data":{"viewer":{"__typename":"User","id":"1","name":"Ann"}}}`,
+		`mutation { rename(id: 1, name: "Bea") { __typename id name } }`: `{"data":{"rename":{"__typename":"User","id":"1","name":"Bea"}}}`,
+		`{ book(isbn: "x") { __typename isbn title } }`:                  `{"data":{"book":{"__typename":"Book","isbn":"x","title":"Go"}}}`,
+		`{ shelf { books { __typename isbn } } }`:                        `{"data":{"shelf":{"books":[{"__typename":"Book","isbn":"x"}]}}}`,
+		`{ shelf { books { __typename isbn title } } }`:                  `{"data":{"shelf":{"books":[{"__typename":"Book","isbn":"x","title":"Go"}]}}}`,
+		`{ node(id: 1) { __typename ... on Node { id } } }`:              `{"data":{"node":{"__typename":"User","id":"1"}}}`,
+		`{ failing { __typename id } }`:                                  `{"data":{"failing":{"__typename":"User","id":"1"}},"errors":[{"message":"partial"}]}`,
+	}
+	tests := []struct {
+		name   string
+		config NormalizedCacheConfig
+		steps  []normalizedStep
+	}{
+		{
+			name: "same query",
+			steps: []normalizedStep{
+				{query: userQuery, want: userData},
+				{query: userQuery, fromCache: true, want: userData},
+			},
+		},
+		{
+			name: "objects shared by queries",
+			steps: []normalizedStep{
+				{query: userQuery, want: userData},
+				{query: `{ viewer { __typename id } }`, want: `{"viewer":{"__typename":"User","id":"1"}}`},
+				{query: viewerName, fromCache: true, want: `{"viewer":{"__typename":"User","id":"1","name":"Ann"}}`},
+			},
+		},
+		{
+			name: "mutation updates objects",
+			steps: []normalizedStep{
+				{query: userQuery, want: userData},
+				{query: `mutation { rename(id: 1, name: "Bea") { __typename id name } }`, want: `{"rename":{"__typename":"User","id":"1","name":"Bea"}}`},
+				{query: userQuery, fromCache: true, want: `{"user":{"__typename":"User","id":"1","name":"Bea"}}`},
+			},
+		},
+		{
+			name: "missing field",
+			steps: []normalizedStep{
+				{query: userQuery, want: userData},
+				{query: `{ user(id: 1) { __typename id name email } }`, want: `{"user":{"__typename":"User","id":"1","name":"Ann","email":"ann@example.com"}}`},
+			},
+		},
+		{
+			name: "arguments",
+			steps: []normalizedStep{
+				{query: userQuery, want: userData},
+				{query: `{ user(id: 2) { __typename id name } }`, want: `{"user":{"__typename":"User","id":"2","name":"Bob"}}`},
+				{query: `query($id: ID!) { user(id: $id) { __typename id name } }`, vars: map[string]interface{}{"id": 1}, fromCache: true, want: userData},
+			},
+		},
+		{
+			name: "evict",
+			steps: []normalizedStep{
+				{query: userQuery, want: userData},
+				{query: userQuery, before: func(n *NormalizedCache) { n.Evict(n.Key("User", "1")) }, want: userData},
+				{query: userQuery, fromCache: true, want: userData},
+			},
+		},
+		{
+			name: "evict type",
+			steps: []normalizedStep{
+				{query: userQuery, want: userData},
+				{query: userQuery, before: func(n *NormalizedCache) { n.EvictType("User") }, want: userData},
+			},
+		},
+		{
+			name: "evict root field",
+			steps: []normalizedStep{
+				{query: userQuery, want: userData},
+				{query: `{ viewer { __typename id } }`, want: `{"viewer":{"__typename":"User","id":"1"}}`},
+				{query: userQuery, before: func(n *NormalizedCache) { n.EvictRootField("user") }, want: userData},
+				{query: viewerName, fromCache: true, want: `{"viewer":{"__typename":"User","id":"1","name":"Ann"}}`},
+			},
+		},
+		{
+			name: "clear",
+			steps: []normalizedStep{
+				{query: userQuery, want: userData},
+				{query: userQuery, before: (*NormalizedCache).Clear, want: userData},
+			},
+		},
+		{
+			name:   "key fields",
+			config: NormalizedCacheConfig{KeyFields: map[string][]string{"Book": {"isbn"}}},
+			steps: []normalizedStep{
+				{query: `{ book(isbn: "x") { __typename isbn title } }`, want: `{"book":{"__typename":"Book","isbn":"x","title":"Go"}}`},
+				{query: `{ shelf { books { __typename isbn } } }`, want: `{"shelf":{"books":[{"__typename":"Book","isbn":"x"}]}}`},
+				{query: `{ shelf { books { __typename isbn title } } }`, fromCache: true, want: `{"shelf":{"books":[{"__typename":"Book","isbn":"x","title":"Go"}]}}`},
+			},
+		},
+		{
+			name:   "fragments on possible types",
+			config: NormalizedCacheConfig{PossibleTypes: map[string][]string{"Node": {"User"}}},
+			steps: []normalizedStep{
+				{query: `{ node(id: 1) { __typename ... on Node { id } } }`, want: `{"node":{"__typename":"User","id":"1"}}`},
+				{query: `{ node(id: 1) { __typename ... on Node { id } } }`, fromCache: true, want: `{"node":{"__typename":"User","id":"1"}}`},
+			},
+		},
+		{
+			name: "fragments on unknown types",
+			steps: []normalizedStep{
+				{query: `{ node(id: 1) { __typename ... on Node { id } } }`, want: `{"node":{"__typename":"User","id":"1"}}`},
+				{query: `{ node(id: 1) { __typename ... on Node { id } } }`, want: `{"node":{"__typename":"User","id":"1"}}`},
+			},
+		},
+		{
+			name: "responses with errors",
+			steps: []normalizedStep{
+				{query: `{ failing { __typename id } }`, want: `{"failing":{"__typename":"User","id":"1"}}`},
+				{query: `{ failing { __typename id } }`, want: `{"failing":{"__typename":"User","id":"1"}}`},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				var payload graphqlModel
+				if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+					http.Error(w, err.Error(), http.StatusBadRequest)
+					return
+				}
+				body, ok := bodies[payload.Query]
+				if !ok {
+					http.Error(w, "unexpected query "+payload.Query, http.StatusBadRequest)
+					return
+				}
+				fmt.Fprint(w, body)
+			}))
+			defer srv.Close()
+			cache := NewNormalizedCache(tt.config)
+			client := NewClient(srv.URL, WithNormalizedCache(cache))
+			for i, step := range tt.steps {
+				if step.before != nil {
+					step.before(cache)
+				}
+				req := NewGraphqlRequest(step.query)
+				for name, value := range step.vars {
+					req.Var(name, value)
+				}
+				var data map[string]interface{}
+				res, err := client.Run(context.Background(), req, &data)
+				if err != nil && !strings.Contains(err.Error(), "partial") {
+					t.Fatalf("step %d: %v", i, err)
+				}
+				got, _ := json.Marshal(data)
+				if string(got) != canonicalJSON(t, step.want) || res.FromCache != step.fromCache {
+					t.Fatalf("step %d: got %s from cache %t, want %s from cache %t", i, got, res.FromCache, step.want, step.fromCache)
+				}
+			}
+		})
+	}
+}
+
+func TestNormalizedCacheRead(t *testing.T) {
+	srv := newPayloadServer(t, `{"user":{"__typename":"User","id":"1","name":"Ann","friends":[{"__typename":"User","id":"2"}]}}`)
+	cache := NewNormalizedCache(NormalizedCacheConfig{})
+	client := NewClient(srv.URL, WithNormalizedCache(cache))
+	if _, err := client.Run(context.Background(), NewGraphqlRequest(`{ user(id: 1) { __typename id name friends { __typename id } } }`), nil); err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name        string
+		query       string
+		want        string
+		wantMissing []string
+		wantErr     string
+	}{
+		{
+			name:  "cached fields",
+			query: `{ user(id: 1) { name friends { id } } }`,
+			want:  `{"user":{"name":"Ann","friends":[{"id":"2"}]}}`,
+		},
+		{
+			name:        "missing fields",
+			query:       `{ user(id: 1) { name email friends { name } } other }`,
+			want:        `{"user":{"name":"Ann","friends":[{}]}}`,
+			wantMissing: []string{"user.email", "user.friends.0.name", "other"},
+		},
+		{
+			name:        "unknown directive condition",
+			query:       `query($full: Boolean!) { user(id: 1) { name @include(if: $full) } }`,
+			want:        `{"user":{}}`,
+			wantMissing: []string{"user"},
+		},
+		{
+			name:    "mutation",
+			query:   `mutation { rename(id: 1, name: "Bea") { id } }`,
+			wantErr: "cannot read a mutation from cache",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data, missing, err := cache.Read(NewGraphqlRequest(tt.query))
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("got %v, want an error containing %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got, _ := json.Marshal(data); string(got) != canonicalJSON(t, tt.want) {
+				t.Fatalf("read %s, want %s", got, tt.want)
+			}
+			var gotMissing []string
+			for _, path := range missing {
+				gotMissing = append(gotMissing, path.String())
+			}
+			if !reflect.DeepEqual(gotMissing, tt.wantMissing) {
+				t.Fatalf("missing %q, want %q", gotMissing, tt.wantMissing)
+			}
+		})
+	}
+}
@@ -0,0 +1,110 @@
+package graphql
+
+import (
+	"io"
+	"sync/atomic"
+	"time"
+)
+
+// operation holds the state of a single Run call.
+type operation struct {
+	req       *GraphRequest
+	name      string
+	start     time.Time
+	requestID string
+	// idempotencyKey is sent with every attempt, if set.
+	idempotencyKey string
+	// stream is set by RunRaw and RunRawData.
+	stream *responseStream
+	// cacheKey is the key of the response in the response cache, or empty
+	// if it isn't cached.
+	cacheKey string
+	// revalidate is the expired cached response whose ETag is sent in
+	// If-None-Match, if any.
+	revalidate *cacheEntry
+	// refreshCache is set to skip the cached responses, and replace them.
+	refreshCache bool
+	// dryRun is set for requests that are built but never sent, by Curl.
+	// Auth providers and CSRF tokens are skipped since they may send
+	// requests of their own to obtain credentials.
+	dryRun bool
+	// definitions are the operations defined in the query document, or
+	// only the one to execute when the request names it. It is nil if the
+	// document can't be parsed.
+	definitions []operationDefinition
+
+	// requestBytes is updated atomically since hedged attempts run
+	// concurrently.
+	requestBytes  int64
+	attempts      int
+	statusCode    int
+	responseBytes int
+}
+
+func newOperation(req *GraphRequest) *operation {
+	op := &operation{
+		req:         req,
+		start:       time.Now(),
+		definitions: parseOperations(req.query),
+	}
+	if len(op.definitions) > 0 {
+		op.name = op.definitions[0].Name
+	}
+	if req.operationName != "" {
+		op.name = req.operationName
+		for _, definition := range op.definitions {
+			if definition.Name == req.operationName {
+				op.definitions = []operationDefinition{definition}
+				break
+			}
+		}
+	}
+	return op
+}
+
+// hasSideEffects reports whether the document defines a mutation or a
+// subscription operation. Documents that can't be parsed are assumed to.
+func (op *operation) hasSideEffects() bool {
+	if op.definitions == nil {
+		return true
+	}
+	for _, definition := range op.definitions {
+		if definition.Type != "query" {
+			return true
+		}
+	}
+	return false
+}
+
+// countingReader counts the bytes read from r into n.
+type countingReader struct {
+	r io.Reader
+	n *int64
+}
+
+func (r *countingReader) Read(p []byte) (int, error) {
+	n, err := r.r.Read(p)
+	atomic.AddInt64(r.n, int64(n))
+	return n, err
+}
+
+// operationDefinition is the header of an operation defined in a document.
+type operationDefinition struct {
+	// Type is query, mutation or subscription.
+	Type string
+	Name string
+}
+
+// parseOperations returns the operations defined in a GraphQL document,
+// or nil if it can't be parsed.
+func parseOperations(query string) []operationDefinition {
+	doc, err := parseQuery(query)
+	if err != nil {
+		return nil
+	}
+	definitions := make([]operationDefinition, len(doc.operations))
+	for i, operation := range doc.operations {
+		definitions[i] = operationDefinition{Type: operation.typ, Name: operation.name}
+	}
+	return definitions
+}
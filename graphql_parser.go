@@ -0,0 +1,584 @@
+package graphql
+
+import (
+	"encoding/json"
+	"strings"
+)
+
+// document is the syntax tree of an executable GraphQL document.
+type document struct {
+	src        string
+	operations []*operationNode
+	fragments  []*fragmentNode
+}
+
+type operationNode struct {
+	// typ is query, mutation or subscription.
+	typ        string
+	name       string
+	variables  []*variableNode
+	directives []*directiveNode
+	selections []*selectionNode
+	pos        int
+	// end is the offset past the last token of the operation.
+	end int
+}
+
+type fragmentNode struct {
+	name          string
+	typeCondition string
+	directives    []*directiveNode
+	selections    []*selectionNode
+	pos           int
+	// end is the offset past the last token of the fragment.
+	end int
+}
+
+type variableNode struct {
+	name         string
+	typ          *typeRef
+	defaultValue *valueNode
+	directives   []*directiveNode
+	pos          int
+}
+
+// operation returns the operation of d named name, or its only operation
+// if name is empty. It returns nil if there is none.
+func (d *document) operation(name string) *operationNode {
+	for _, operation := range d.operations {
+		if operation.name == name || name == "" && len(d.operations) == 1 {
+			return operation
+		}
+	}
+	return nil
+}
+
+// typeRef is a reference to a type: a named type, or a list of elem.
+type typeRef struct {
+	name    string
+	elem    *typeRef
+	nonNull bool
+}
+
+func (t *typeRef) String() string {
+	s := t.name
+	if t.elem != nil {
+		s = "[" + t.elem.String() + "]"
+	}
+	if t.nonNull {
+		s += "!"
+	}
+	return s
+}
+
+// namedType returns the name of the type t wraps.
+func (t *typeRef) namedType() string {
+	for t.elem != nil {
+		t = t.elem
+	}
+	return t.name
+}
+
+type selectionKind int
+
+const (
+	selectionField selectionKind = iota
+	selectionFragmentSpread
+	selectionInlineFragment
+)
+
+type selectionNode struct {
+	kind selectionKind
+	// name is the name of a field or of a spread fragment.
+	name          string
+	alias         string
+	arguments     []*argumentNode
+	directives    []*directiveNode
+	typeCondition string
+	selections    []*selectionNode
+	pos           int
+}
+
+// responseKey returns the key of a field in the response.
+func (s *selectionNode) responseKey() string {
+	if s.alias != "" {
+		return s.alias
+	}
+	return s.name
+}
+
+type directiveNode struct {
+	name      string
+	arguments []*argumentNode
+	pos       int
+}
+
+type argumentNode struct {
+	name  string
+	value *valueNode
+	pos   int
+}
+
+type valueKind int
+
+const (
+	valueVariable valueKind = iota
+	valueInt
+	valueFloat
+	valueString
+	valueBoolean
+	valueNull
+	valueEnum
+	valueList
+	valueObject
+)
+
+type valueNode struct {
+	kind valueKind
+	// text is the name of a variable or enum value, the decoded content
+	// of a string, or the source of any other scalar.
+	text   string
+	list   []*valueNode
+	fields []*argumentNode
+	pos    int
+}
+
+// parser is a recursive descent parser of GraphQL documents.
+type parser struct {
+	lex *lexer
+	tok token
+	// end is the offset past the previous token.
+	end int
+}
+
+func newParser(src string) (*parser, error) {
+	p := &parser{lex: newLexer(src)}
+	return p, p.advance()
+}
+
+func (p *parser) advance() error {
+	tok, err := p.lex.next()
+	if err != nil {
+		return err
+	}
+	p.end = p.tok.pos + len(p.tok.text)
+	p.tok = tok
+	return nil
+}
+
+// peek reports whether the current token is the punctuator text.
+func (p *parser) peek(text string) bool {
+	return p.tok.kind == tokenPunctuator && p.tok.text == text
+}
+
+// peekName reports whether the current token is the name text.
+func (p *parser) peekName(text string) bool {
+	return p.tok.kind == tokenName && p.tok.text == text
+}
+
+// skip advances past the punctuator text, reporting whether it was there.
+func (p *parser) skip(text string) (bool, error) {
+	if !p.peek(text) {
+		return false, nil
+	}
+	return true, p.advance()
+}
+
+func (p *parser) expect(text string) error {
+	if !p.peek(text) {
+		return p.unexpected("expected %q", text)
+	}
+	return p.advance()
+}
+
+func (p *parser) expectName(text string) error {
+	if !p.peekName(text) {
+		return p.unexpected("expected %q", text)
+	}
+	return p.advance()
+}
+
+func (p *parser) name() (string, error) {
+	if p.tok.kind != tokenName {
+		return "", p.unexpected("expected a name")
+	}
+	name := p.tok.text
+	return name, p.advance()
+}
+
+func (p *parser) unexpected(format string, args ...interface{}) error {
+	found := "end of document"
+	if p.tok.kind != tokenEOF {
+		found = p.tok.text
+	}
+	return p.lex.errorf(p.tok.pos, format+", found %q", append(args, found)...)
+}
+
+// parseQuery parses an executable document.
+func parseQuery(src string) (*document, error) {
+	p, err := newParser(src)
+	if err != nil {
+		return nil, err
+	}
+	doc := &document{src: src}
+	for p.tok.kind != tokenEOF {
+		switch {
+		case p.peek("{") || p.peekName("query") || p.peekName("mutation") || p.peekName("subscription"):
+			operation, err := p.operation()
+			if err != nil {
+				return nil, err
+			}
+			operation.end = p.end
+			doc.operations = append(doc.operations, operation)
+		case p.peekName("fragment"):
+			fragment, err := p.fragment()
+			if err != nil {
+				return nil, err
+			}
+			fragment.end = p.end
+			doc.fragments = append(doc.fragments, fragment)
+		default:
+			return nil, p.unexpected("expected an operation or a fragment")
+		}
+	}
+	return doc, nil
+}
+
+func (p *parser) operation() (*operationNode, error) {
+	operation := &operationNode{typ: "query", pos: p.tok.pos}
+	if p.tok.kind == tokenName {
+		operation.typ = p.tok.text
+		if err := p.advance(); err != nil {
+			return nil, err
+		}
+		var err error
+		if p.tok.kind == tokenName {
+			if operation.name, err = p.name(); err != nil {
+				return nil, err
+			}
+		}
+		if operation.variables, err = p.variableDefinitions(); err != nil {
+			return nil, err
+		}
+		if operation.directives, err = p.directives(false); err != nil {
+			return nil, err
+		}
+	}
+	var err error
+	operation.selections, err = p.selectionSet()
+	return operation, err
+}
+
+func (p *parser) variableDefinitions() ([]*variableNode, error) {
+	if ok, err := p.skip("("); !ok || err != nil {
+		return nil, err
+	}
+	var variables []*variableNode
+	for {
+		if ok, err := p.skip(")"); ok || err != nil {
+			return variables, err
+		}
+		variable := &variableNode{pos: p.tok.pos}
+		if err := p.expect("$"); err != nil {
+			return nil, err
+		}
+		var err error
+		if variable.name, err = p.name(); err != nil {
+			return nil, err
+		}
+		if err := p.expect(":"); err != nil {
+			return nil, err
+		}
+		if variable.typ, err = p.typeRef(); err != nil {
+			return nil, err
+		}
+		if ok, err := p.skip("="); err != nil {
+			return nil, err
+		} else if ok {
+			if variable.defaultValue, err = p.value(true); err != nil {
+				return nil, err
+			}
+		}
+		if variable.directives, err = p.directives(true); err != nil {
+			return nil, err
+		}
+		variables = append(variables, variable)
+	}
+}
+
+func (p *parser) typeRef() (*typeRef, error) {
+	t := &typeRef{}
+	if ok, err := p.skip("["); err != nil {
+		return nil, err
+	} else if ok {
+		if t.elem, err = p.typeRef(); err != nil {
+			return nil, err
+		}
+		if err := p.expect("]"); err != nil {
+			return nil, err
+		}
+	} else if t.name, err = p.name(); err != nil {
+		return nil, err
+	}
+	nonNull, err := p.skip("!")
+	t.nonNull = nonNull
+	return t, err
+}
+
+func (p *parser) directives(isConst bool) ([]*directiveNode, error) {
+	var directives []*directiveNode
+	for p.peek("@") {
+		directive := &directiveNode{pos: p.tok.pos}
+		if err := p.advance(); err != nil {
+			return nil, err
+		}
+		var err error
+		if directive.name, err = p.name(); err != nil {
+			return nil, err
+		}
+		if directive.arguments, err = p.arguments(isConst); err != nil {
+			return nil, err
+		}
+		directives = append(directives, directive)
+	}
+	return directives, nil
+}
+
+func (p *parser) arguments(isConst bool) ([]*argumentNode, error) {
+	if ok, err := p.skip("("); !ok || err != nil {
+		return nil, err
+	}
+	var arguments []*argumentNode
+	for {
+		if ok, err := p.skip(")"); ok || err != nil {
+			return arguments, err
+		}
+		argument, err := p.argument(isConst)
+		if err != nil {
+			return nil, err
+		}
+		arguments = append(arguments, argument)
+	}
+}
+
+// argument parses name: value, as found in arguments and object values.
+func (p *parser) argument(isConst bool) (*argumentNode, error) {
+	argument := &argumentNode{pos: p.tok.pos}
+	var err error
+	if argument.name, err = p.name(); err != nil {
+		return nil, err
+	}
+	if err := p.expect(":"); err != nil {
+		return nil, err
+	}
+	argument.value, err = p.value(isConst)
+	return argument, err
+}
+
+func (p *parser) selectionSet() ([]*selectionNode, error) {
+	if err := p.expect("{"); err != nil {
+		return nil, err
+	}
+	var selections []*selectionNode
+	for {
+		if ok, err := p.skip("}"); err != nil {
+			return nil, err
+		} else if ok {
+			if len(selections) == 0 {
+				return nil, p.lex.errorf(p.tok.pos, "empty selection set")
+			}
+			return selections, nil
+		}
+		selection, err := p.selection()
+		if err != nil {
+			return nil, err
+		}
+		selections = append(selections, selection)
+	}
+}
+
+func (p *parser) selection() (*selectionNode, error) {
+	selection := &selectionNode{pos: p.tok.pos}
+	var err error
+	if ok, err := p.skip("..."); err != nil {
+		return nil, err
+	} else if ok {
+		selection.kind = selectionInlineFragment
+		if p.peekName("on") {
+			if err := p.advance(); err != nil {
+				return nil, err
+			}
+			if selection.typeCondition, err = p.name(); err != nil {
+				return nil, err
+			}
+		} else if p.tok.kind == tokenName {
+			selection.kind = selectionFragmentSpread
+			if selection.name, err = p.name(); err != nil {
+				return nil, err
+			}
+			selection.directives, err = p.directives(false)
+			return selection, err
+		}
+		if selection.directives, err = p.directives(false); err != nil {
+			return nil, err
+		}
+		selection.selections, err = p.selectionSet()
+		return selection, err
+	}
+	if selection.name, err = p.name(); err != nil {
+		return nil, err
+	}
+	if ok, err := p.skip(":"); err != nil {
+		return nil, err
+	} else if ok {
+		selection.alias = selection.name
+		if selection.name, err = p.name(); err != nil {
+			return nil, err
+		}
+	}
+	if selection.arguments, err = p.arguments(false); err != nil {
+		return nil, err
+	}
+	if selection.directives, err = p.directives(false); err != nil {
+		return nil, err
+	}
+	if p.peek("{") {
+		selection.selections, err = p.selectionSet()
+	}
+	return selection, err
+}
+
+func (p *parser) fragment() (*fragmentNode, error) {
+	fragment := &fragmentNode{pos: p.tok.pos}
+	if err := p.expectName("fragment"); err != nil {
+		return nil, err
+	}
+	var err error
+	if fragment.name, err = p.name(); err != nil {
+		return nil, err
+	}
+	if fragment.name == "on" {
+		return nil, p.lex.errorf(fragment.pos, "fragments can't be named \"on\"")
+	}
+	if err := p.expectName("on"); err != nil {
+		return nil, err
+	}
+	if fragment.typeCondition, err = p.name(); err != nil {
+		return nil, err
+	}
+	if fragment.directives, err = p.directives(false); err != nil {
+		return nil, err
+	}
+	fragment.selections, err = p.selectionSet()
+	return fragment, err
+}
+
+// value parses a value. Constant values can't reference variables.
+func (p *parser) value(isConst bool) (*valueNode, error) {
+	value := &valueNode{pos: p.tok.pos, text: p.tok.text}
+	switch p.tok.kind {
+	case tokenInt:
+		value.kind = valueInt
+	case tokenFloat:
+		value.kind = valueFloat
+	case tokenString:
+		value.kind = valueString
+		if err := json.Unmarshal([]byte(p.tok.text), &value.text); err != nil {
+			return nil, p.lex.errorf(p.tok.pos, "invalid string")
+		}
+	case tokenBlockString:
+		value.kind = valueString
+		value.text = blockStringValue(p.tok.text)
+	case tokenName:
+		switch p.tok.text {
+		case "true", "false":
+			value.kind = valueBoolean
+		case "null":
+			value.kind = valueNull
+		default:
+			value.kind = valueEnum
+		}
+	case tokenPunctuator:
+		switch p.tok.text {
+		case "$":
+			if isConst {
+				return nil, p.unexpected("expected a constant value")
+			}
+			value.kind = valueVariable
+			if err := p.advance(); err != nil {
+				return nil, err
+			}
+			var err error
+			value.text, err = p.name()
+			return value, err
+		case "[":
+			value.kind = valueList
+			if err := p.advance(); err != nil {
+				return nil, err
+			}
+			for {
+				if ok, err := p.skip("]"); ok || err != nil {
+					return value, err
+				}
+				item, err := p.value(isConst)
+				if err != nil {
+					return nil, err
+				}
+				value.list = append(value.list, item)
+			}
+		case "{":
+			value.kind = valueObject
+			if err := p.advance(); err != nil {
+				return nil, err
+			}
+			for {
+				if ok, err := p.skip("}"); ok || err != nil {
+					return value, err
+				}
+				field, err := p.argument(isConst)
+				if err != nil {
+					return nil, err
+				}
+				value.fields = append(value.fields, field)
+			}
+		}
+		return nil, p.unexpected("expected a value")
+	default:
+		return nil, p.unexpected("expected a value")
+	}
+	return value, p.advance()
+}
+
+// blockStringValue returns the value of a block string token, with its
+// common indentation and leading and trailing blank lines removed.
+func blockStringValue(raw string) string {
+	raw = strings.TrimSuffix(strings.TrimPrefix(raw, `"""`), `"""`)
+	raw = strings.ReplaceAll(raw, `\"""`, `"""`)
+	lines := strings.Split(strings.NewReplacer("\r\n", "\n", "\r", "\n").Replace(raw), "\n")
+	indent := -1
+	for _, line := range lines[1:] {
+		trimmed := strings.TrimLeft(line, " \t")
+		if trimmed == "" {
+			continue
+		}
+		if n := len(line) - len(trimmed); indent < 0 || n < indent {
+			indent = n
+		}
+	}
+	if indent > 0 {
+		for i := 1; i < len(lines); i++ {
+			if len(lines[i]) >= indent {
+				lines[i] = lines[i][indent:]
+			} else {
+				lines[i] = strings.TrimLeft(lines[i], " \t")
+			}
+		}
+	}
+	for len(lines) > 0 && strings.TrimLeft(lines[0], " \t") == "" {
+		lines = lines[1:]
+	}
+	for len(lines) > 0 && strings.TrimLeft(lines[len(lines)-1], " \t") == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return strings.Join(lines, "\n")
+}
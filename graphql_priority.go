@@ -0,0 +1,131 @@
+package graphql
+
+import (
+	"context"
+	"sync"
+)
+
+// Priority is the priority of a request, normal by default.
+type Priority int
+
+// Priorities of requests.
+const (
+	PriorityLow    Priority = -1
+	PriorityNormal Priority = 0
+	PriorityHigh   Priority = 1
+)
+
+func (p Priority) String() string {
+	switch {
+	case p < PriorityNormal:
+		return "low"
+	case p > PriorityNormal:
+		return "high"
+	}
+	return "normal"
+}
+
+// SetPriority sets the priority of this request. It is sent in the header
+// set with WithPriorityHeader, and orders dispatch when the Client limits
+// the operations in flight with WithScheduler.
+func (req *GraphRequest) SetPriority(priority Priority) {
+	req.priority = priority
+}
+
+// Priority returns the priority set with SetPriority.
+func (req *GraphRequest) Priority() Priority {
+	return req.priority
+}
+
+// WithPriorityHeader sends the priority of every request, "low", "normal"
+// or "high", in the header name.
+func WithPriorityHeader(name string) ClientOption {
+	return func(client *Client) {
+		client.priorityHeader = name
+	}
+}
+
+// WithScheduler limits the operations in flight to maxInFlight. Waiting
+// operations are dispatched by priority, then in the order they were run,
+// so background work doesn't starve interactive requests. A slot is held
+// for the whole operation, retries included.
+func WithScheduler(maxInFlight int) ClientOption {
+	return func(client *Client) {
+		if maxInFlight < 1 {
+			maxInFlight = 1
+		}
+		client.scheduler = &scheduler{max: maxInFlight}
+	}
+}
+
+type scheduler struct {
+	mu       sync.Mutex
+	max      int
+	inFlight int
+	// waiting are the operations waiting for a slot, by priority from low
+	// to high.
+	waiting [3][]chan struct{}
+}
+
+func (s *scheduler) queue(priority Priority) int {
+	switch {
+	case priority < PriorityNormal:
+		return 0
+	case priority > PriorityNormal:
+		return 2
+	}
+	return 1
+}
+
+// acquire waits for a slot. Slots are handed over to waiting operations
+// by release, so an operation never overtakes one of higher priority.
+func (s *scheduler) acquire(ctx context.Context, priority Priority) error {
+	s.mu.Lock()
+	if s.inFlight < s.max {
+		s.inFlight++
+		s.mu.Unlock()
+		return nil
+	}
+	ready := make(chan struct{})
+	i := s.queue(priority)
+	s.waiting[i] = append(s.waiting[i], ready)
+	s.mu.Unlock()
+	select {
+	case <-ready:
+		return nil
+	case <-ctx.Done():
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	select {
+	case <-ready:
+		// The slot was handed over while the context was done.
+		s.releaseLocked()
+	default:
+		for j, waiting := range s.waiting[i] {
+			if waiting == ready {
+				s.waiting[i] = append(s.waiting[i][:j], s.waiting[i][j+1:]...)
+				break
+			}
+		}
+	}
+	return ctx.Err()
+}
+
+func (s *scheduler) release() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.releaseLocked()
+}
+
+func (s *scheduler) releaseLocked() {
+	for i := len(s.waiting) - 1; i >= 0; i-- {
+		if len(s.waiting[i]) > 0 {
+			ready := s.waiting[i][0]
+			s.waiting[i] = s.waiting[i][1:]
+			close(ready)
+			return
+		}
+	}
+	s.inFlight--
+}
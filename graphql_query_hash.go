@@ -0,0 +1,37 @@
+package graphql
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+)
+
+// CanonicalQuery normalizes query so that documents differing only in
+// formatting are equal. The rules are stable across releases:
+//
+//   - comments, whitespace, line terminators, commas and byte order marks
+//     are removed;
+//   - a single space is kept between two names, numbers or strings, and
+//     before "..." when it follows one of them, where removing it would
+//     change the meaning of the document;
+//   - strings and block strings are kept verbatim, escapes and indentation
+//     included;
+//   - nothing else changes: definitions, selections and arguments keep
+//     their order.
+//
+// These are the rules of graphql-js's stripIgnoredCharacters. Queries that
+// can't be tokenized return an error.
+func CanonicalQuery(query string) (string, error) {
+	return minifyQuery(query)
+}
+
+// QueryHash returns the lowercase hex SHA-256 hash of the canonical form of
+// query, as returned by CanonicalQuery, for persisted queries, allowlists,
+// cache keys or log correlation.
+func QueryHash(query string) (string, error) {
+	canonical, err := CanonicalQuery(query)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256([]byte(canonical))
+	return hex.EncodeToString(sum[:]), nil
+}
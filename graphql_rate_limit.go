@@ -0,0 +1,89 @@
+package graphql
+
+import (
+	"context"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// ErrRateLimited is returned by Run when the client was configured with
+// WithRateLimitNoWait and no token is available.
+var ErrRateLimited = errors.New("graphql: client-side rate limit exceeded")
+
+// WithRateLimit throttles outgoing operations with a token bucket that
+// refills at rps tokens per second and holds up to burst tokens. Run blocks
+// until a token is available or its context is done.
+func WithRateLimit(rps float64, burst int) ClientOption {
+	return func(client *Client) {
+		client.limiter = newTokenBucket(rps, burst, true)
+	}
+}
+
+// WithRateLimitNoWait is like WithRateLimit, but Run fails immediately
+// with ErrRateLimited instead of waiting for a token.
+func WithRateLimitNoWait(rps float64, burst int) ClientOption {
+	return func(client *Client) {
+		client.limiter = newTokenBucket(rps, burst, false)
+	}
+}
+
+type tokenBucket struct {
+	rate  float64
+	burst float64
+	wait  bool
+
+	mu     sync.Mutex
+	tokens float64
+	last   time.Time
+}
+
+func newTokenBucket(rps float64, burst int, wait bool) *tokenBucket {
+	if burst < 1 {
+		burst = 1
+	}
+	return &tokenBucket{
+		rate:   rps,
+		burst:  float64(burst),
+		wait:   wait,
+		tokens: float64(burst),
+		last:   time.Now(),
+	}
+}
+
+// take removes a token from the bucket, waiting for one to become
+// available if the bucket was configured to wait.
+func (b *tokenBucket) take(ctx context.Context) error {
+	for {
+		delay, ok := b.reserve()
+		if ok {
+			return nil
+		}
+		if !b.wait {
+			return ErrRateLimited
+		}
+		if err := sleepContext(ctx, delay); err != nil {
+			return err
+		}
+	}
+}
+
+// reserve takes a token if one is available, otherwise it returns how
+// long until the next one is.
+func (b *tokenBucket) reserve() (time.Duration, bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	now := time.Now()
+	b.tokens = math.Min(b.burst, b.tokens+now.Sub(b.last).Seconds()*b.rate)
+	b.last = now
+	if b.tokens >= 1 {
+		b.tokens--
+		return 0, true
+	}
+	if b.rate <= 0 {
+		return time.Second, false
+	}
+	return time.Duration((1 - b.tokens) / b.rate * float64(time.Second)), false
+}
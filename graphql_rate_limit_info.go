@@ -0,0 +1,126 @@
+package graphql
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// RateLimit is the rate limit status a server reported with a response,
+// so callers can pace themselves. Unknown fields are zero.
+type RateLimit struct {
+	Limit     int
+	Remaining int
+	Used      int
+	ResetAt   time.Time
+	// RetryAfter is the delay requested by a Retry-After header.
+	RetryAfter time.Duration
+	// Cost is the cost of the operation, for APIs reporting it in a cost
+	// extension.
+	Cost int
+}
+
+// RateLimitExtractor returns the rate limit status of a response, or nil if
+// it reported none. Its Header and Extensions are set.
+type RateLimitExtractor func(res *GraphResponse) *RateLimit
+
+// WithRateLimitExtractor replaces DefaultRateLimitExtractor, for APIs
+// reporting their rate limits in other formats. A nil extractor disables
+// the extraction.
+func WithRateLimitExtractor(extractor RateLimitExtractor) ClientOption {
+	return func(client *Client) {
+		client.rateLimitExtractor = extractor
+	}
+}
+
+// resetEpoch tells epoch seconds from delays in seconds in reset headers.
+const resetEpoch = 1e9
+
+// DefaultRateLimitExtractor reads the X-RateLimit-Limit, -Remaining, -Used
+// and -Reset headers, their RateLimit-* equivalents, Retry-After, and the
+// cost extension of Shopify-style APIs:
+//
+//	{"cost": {"actualQueryCost": 12, "throttleStatus": {"maximumAvailable": 1000, "currentlyAvailable": 988}}}
+//
+// Resets are read as epoch seconds, or as delays in seconds if small.
+func DefaultRateLimitExtractor(res *GraphResponse) *RateLimit {
+	rateLimit := &RateLimit{}
+	found := false
+	readInt := func(dst *int, keys ...string) {
+		for _, key := range keys {
+			if n, err := strconv.Atoi(res.Header.Get(key)); err == nil {
+				*dst, found = n, true
+				return
+			}
+		}
+	}
+	readInt(&rateLimit.Limit, "X-RateLimit-Limit", "RateLimit-Limit")
+	readInt(&rateLimit.Remaining, "X-RateLimit-Remaining", "RateLimit-Remaining")
+	readInt(&rateLimit.Used, "X-RateLimit-Used", "RateLimit-Used")
+	var reset int
+	readInt(&reset, "X-RateLimit-Reset", "RateLimit-Reset")
+	if reset >= resetEpoch {
+		rateLimit.ResetAt = time.Unix(int64(reset), 0)
+	} else if reset > 0 {
+		rateLimit.ResetAt = time.Now().Add(time.Duration(reset) * time.Second)
+	}
+	if retryAfter, ok := parseRetryAfter(res.Header, time.Now()); ok {
+		rateLimit.RetryAfter, found = retryAfter, true
+	}
+	if cost, ok := res.Extensions["cost"].(map[string]interface{}); ok {
+		found = true
+		if !setNumber(&rateLimit.Cost, cost["actualQueryCost"]) {
+			setNumber(&rateLimit.Cost, cost["requestedQueryCost"])
+		}
+		if throttle, ok := cost["throttleStatus"].(map[string]interface{}); ok {
+			setNumber(&rateLimit.Limit, throttle["maximumAvailable"])
+			setNumber(&rateLimit.Remaining, throttle["currentlyAvailable"])
+		}
+	}
+	if !found {
+		return nil
+	}
+	return rateLimit
+}
+
+// parseRetryAfter parses a Retry-After header, in seconds or as an HTTP
+// date relative to now.
+func parseRetryAfter(h http.Header, now time.Time) (time.Duration, bool) {
+	value := h.Get("Retry-After")
+	if value == "" {
+		return 0, false
+	}
+	if seconds, err := strconv.Atoi(value); err == nil {
+		if seconds < 0 {
+			return 0, false
+		}
+		return time.Duration(seconds) * time.Second, true
+	}
+	date, err := http.ParseTime(value)
+	if err != nil {
+		return 0, false
+	}
+	if delay := date.Sub(now); delay > 0 {
+		return delay, true
+	}
+	return 0, true
+}
+
+// setNumber sets dst to a number decoded from JSON, reporting whether v
+// was one.
+func setNumber(dst *int, v interface{}) bool {
+	switch v := v.(type) {
+	case float64:
+		*dst = int(v)
+	case json.Number:
+		n, err := v.Float64()
+		if err != nil {
+			return false
+		}
+		*dst = int(n)
+	default:
+		return false
+	}
+	return true
+}
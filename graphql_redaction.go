@@ -0,0 +1,130 @@
+package graphql
+
+import (
+	"encoding/json"
+	"net/http"
+	"regexp"
+	"strings"
+)
+
+const redacted = "[REDACTED]"
+
+// RedactionPolicy describes the sensitive data removed from the debug
+// information passed to Client.Log.
+type RedactionPolicy struct {
+	// Headers are the names of the headers whose values are redacted.
+	Headers []string
+	// VariableKeys are the keys, at any depth, of the variables whose
+	// values are redacted. They are matched case-insensitively.
+	VariableKeys []string
+	// Patterns are redacted from every log line, including queries and
+	// response bodies.
+	Patterns []*regexp.Regexp
+	// Replacement replaces redacted values, "[REDACTED]" by default.
+	Replacement string
+}
+
+// DefaultRedactionPolicy returns a RedactionPolicy covering the usual
+// credential headers and variable names.
+func DefaultRedactionPolicy() RedactionPolicy {
+	return RedactionPolicy{
+		Headers: []string{
+			"Authorization", "Proxy-Authorization", "Cookie", "Set-Cookie", "X-Api-Key",
+		},
+		VariableKeys: []string{
+			"password", "token", "accessToken", "refreshToken", "secret", "apiKey",
+		},
+	}
+}
+
+// WithRedaction redacts sensitive data from the debug information passed
+// to Client.Log.
+//
+//	NewClient(url, WithRedaction(DefaultRedactionPolicy()))
+func WithRedaction(policy RedactionPolicy) ClientOption {
+	if policy.Replacement == "" {
+		policy.Replacement = redacted
+	}
+	return func(client *Client) {
+		client.redaction = &policy
+	}
+}
+
+// redactHeaders returns a copy of h without the values of the sensitive
+// headers.
+func (p *RedactionPolicy) redactHeaders(h http.Header) http.Header {
+	if p == nil || len(p.Headers) == 0 {
+		return h
+	}
+	clone := h.Clone()
+	for _, name := range p.Headers {
+		if values := clone.Values(name); len(values) > 0 {
+			clone[http.CanonicalHeaderKey(name)] = []string{p.Replacement}
+		}
+	}
+	return clone
+}
+
+// redactVariables returns a copy of vars without the values of the
+// sensitive keys.
+func (p *RedactionPolicy) redactVariables(vars map[string]interface{}) map[string]interface{} {
+	if p == nil || len(p.VariableKeys) == 0 || vars == nil {
+		return vars
+	}
+	return p.redactValue(vars).(map[string]interface{})
+}
+
+func (p *RedactionPolicy) redactValue(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		clone := make(map[string]interface{}, len(v))
+		for key, item := range v {
+			if p.sensitiveKey(key) {
+				clone[key] = p.Replacement
+				continue
+			}
+			clone[key] = p.redactValue(item)
+		}
+		return clone
+	case []interface{}:
+		clone := make([]interface{}, len(v))
+		for i, item := range v {
+			clone[i] = p.redactValue(item)
+		}
+		return clone
+	}
+	return value
+}
+
+func (p *RedactionPolicy) sensitiveKey(key string) bool {
+	for _, sensitive := range p.VariableKeys {
+		if strings.EqualFold(key, sensitive) {
+			return true
+		}
+	}
+	return false
+}
+
+// redactText removes the sensitive patterns from s.
+func (p *RedactionPolicy) redactText(s string) string {
+	if p == nil {
+		return s
+	}
+	for _, pattern := range p.Patterns {
+		s = pattern.ReplaceAllString(s, p.Replacement)
+	}
+	return s
+}
+
+// redactedJSON returns the JSON encoding of vars, redacted, or encoded as
+// is when there's nothing to redact.
+func (c *Client) redactedJSON(vars map[string]interface{}, encoded []byte) string {
+	if c.redaction == nil || len(c.redaction.VariableKeys) == 0 {
+		return string(encoded)
+	}
+	redactedVars, err := json.Marshal(c.redaction.redactVariables(vars))
+	if err != nil {
+		return c.redaction.Replacement
+	}
+	return string(redactedVars)
+}
@@ -1,17 +1,32 @@
 package graphql
 
 import (
-	"fmt"
+	"bytes"
+	"encoding/json"
 	"io"
 	"net/http"
+	"time"
+
+	"github.com/pkg/errors"
 )
 
 // GraphRequest is a GraphQL request.
 type GraphRequest struct {
-	query  string
-	vars   map[string]interface{}
-	files  []File
-	Header http.Header
+	query         string
+	operationName string
+	label         string
+	vars          map[string]interface{}
+	extensions    map[string]interface{}
+	files         []File
+	auth          AuthProvider
+	// timeout bounds Run, see SetTimeout.
+	timeout        time.Duration
+	priority       Priority
+	idempotencyKey string
+	cacheControl   CacheControl
+	// uploadProgress overrides the Client's UploadProgressFunc.
+	uploadProgress UploadProgressFunc
+	Header         http.Header
 }
 
 // NewGraphqlRequest makes a new GraphRequest with the specified query string.
@@ -31,11 +46,89 @@ func (req *GraphRequest) Var(key string, value interface{}) {
 	req.vars[key] = value
 }
 
+// VarsJSON sets a variable for every member of raw, a JSON object. The
+// values are sent as they are, without being decoded. Values of single
+// variables can be pre-serialized too, by passing a json.RawMessage or a
+// json.Marshaler to Var.
+func (req *GraphRequest) VarsJSON(raw []byte) error {
+	var vars map[string]json.RawMessage
+	if err := json.Unmarshal(raw, &vars); err != nil {
+		return errors.Wrap(err, "decode variables")
+	}
+	for key, value := range vars {
+		req.Var(key, value)
+	}
+	return nil
+}
+
+// VarsFromStruct sets a variable for every field of v, a struct or a
+// pointer to one, as encoded to JSON: json tags, omitempty and custom
+// marshalers are honored. Numbers keep their exact encoding.
+func (req *GraphRequest) VarsFromStruct(v interface{}) error {
+	encoded, err := json.Marshal(v)
+	if err != nil {
+		return errors.Wrap(err, "encode variables")
+	}
+	decoder := json.NewDecoder(bytes.NewReader(encoded))
+	decoder.UseNumber()
+	var vars map[string]interface{}
+	if err := decoder.Decode(&vars); err != nil {
+		return errors.Wrapf(err, "variables from %T", v)
+	}
+	for key, value := range vars {
+		req.Var(key, value)
+	}
+	return nil
+}
+
+// SetExtension sets the key of the extensions object sent along with the
+// query and variables, e.g. for persisted queries or vendor-specific
+// features.
+func (req *GraphRequest) SetExtension(key string, value interface{}) {
+	if req.extensions == nil {
+		req.extensions = make(map[string]interface{})
+	}
+	req.extensions[key] = value
+}
+
+// Extensions gets the extensions for this GraphRequest.
+func (req *GraphRequest) Extensions() map[string]interface{} {
+	return req.extensions
+}
+
 // Vars gets the variables for this GraphRequest.
 func (req *GraphRequest) Vars() map[string]interface{} {
 	return req.vars
 }
 
+// SetQuery replaces the query string of this request.
+func (req *GraphRequest) SetQuery(query string) {
+	req.query = query
+}
+
+// SetOperationName sets the name of the operation to execute, which is
+// required when the query document defines several operations.
+func (req *GraphRequest) SetOperationName(name string) {
+	req.operationName = name
+}
+
+// OperationName gets the name of the operation to execute.
+func (req *GraphRequest) OperationName() string {
+	return req.operationName
+}
+
+// SetLabel tags the request with a logical label, e.g.
+// "checkout.load-cart", sent in the X-GraphQL-Operation header and passed
+// to loggers, metrics, tracers and hooks through OperationInfo.
+func (req *GraphRequest) SetLabel(label string) {
+	req.label = label
+}
+
+// Label gets the label of this request.
+func (req *GraphRequest) Label() string {
+	return req.label
+}
+
 // Files gets the files in this request.
 func (req *GraphRequest) Files() []File {
 	return req.files
@@ -57,13 +150,83 @@ func (req *GraphRequest) File(fieldname, filename string, r io.Reader) {
 	})
 }
 
+// AddFile sets a file to upload, e.g. with its content type.
+func (req *GraphRequest) AddFile(file File) {
+	req.files = append(req.files, file)
+}
+
+// SetAuth sets the credentials of this request, overriding the ones the
+// Client was configured with, e.g. to call the API on behalf of a
+// different user.
+func (req *GraphRequest) SetAuth(provider AuthProvider) {
+	req.auth = provider
+}
+
+// SetTimeout bounds the time Run may take to run this request, retries
+// included, on top of the deadline of the context passed to Run. Run
+// returns a *TimeoutError when the timeout expires. Zero means no timeout.
+func (req *GraphRequest) SetTimeout(timeout time.Duration) {
+	req.timeout = timeout
+}
+
+// Timeout returns the timeout set with SetTimeout.
+func (req *GraphRequest) Timeout() time.Duration {
+	return req.timeout
+}
+
+// Clone returns a copy of req that doesn't share its variables,
+// extensions, files or headers, so a prepared request can be reused as a
+// template and run concurrently with different variables. Nested maps and
+// slices of variables and extensions are copied too. File readers are
+// shared, except those of files set with FileFromPath, which every clone
+// opens on its own.
+func (req *GraphRequest) Clone() *GraphRequest {
+	clone := *req
+	if req.vars != nil {
+		clone.vars = cloneValue(req.vars).(map[string]interface{})
+	}
+	if req.extensions != nil {
+		clone.extensions = cloneValue(req.extensions).(map[string]interface{})
+	}
+	clone.files = append([]File(nil), req.files...)
+	for i := range clone.files {
+		if f, ok := clone.files[i].R.(*pathFile); ok {
+			clone.files[i].R = &pathFile{path: f.path}
+		}
+	}
+	clone.Header = req.Header.Clone()
+	return &clone
+}
+
+func cloneValue(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		clone := make(map[string]interface{}, len(v))
+		for key, item := range v {
+			clone[key] = cloneValue(item)
+		}
+		return clone
+	case []interface{}:
+		clone := make([]interface{}, len(v))
+		for i, item := range v {
+			clone[i] = cloneValue(item)
+		}
+		return clone
+	}
+	return value
+}
+
 // File represents a file to upload.
 type File struct {
 	Field string
 	Name  string
 	R     io.Reader
-}
+	// ContentType is the content type of the multipart part, by default
+	// application/octet-stream, or detected for files set with
+	// FileFromPath.
+	ContentType string
 
-func (c *Client) logf(format string, args ...interface{}) {
-	c.Log(fmt.Sprintf(format, args...))
+	// variablePath is the path of the variable set with Upload or
+	// UploadList, e.g. "variables.docs.0".
+	variablePath string
 }
@@ -0,0 +1,63 @@
+package graphql
+
+import (
+	"context"
+	"crypto/rand"
+	"fmt"
+)
+
+// DefaultRequestIDHeader is the header request IDs are sent in when
+// WithRequestID is given an empty header name.
+const DefaultRequestIDHeader = "X-Request-ID"
+
+type requestIDKey struct{}
+
+// WithRequestID gives every operation a request ID, sent in header, added
+// to the debug log lines and surfaced in GraphResponse.RequestID, so client
+// and server logs can be correlated. The ID is taken from the context when
+// set with ContextWithRequestID, and generated otherwise.
+func WithRequestID(header string) ClientOption {
+	if header == "" {
+		header = DefaultRequestIDHeader
+	}
+	return func(client *Client) {
+		client.requestIDHeader = header
+	}
+}
+
+// ContextWithRequestID returns a copy of ctx carrying the request ID the
+// Client should use for operations run with it.
+func ContextWithRequestID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, requestIDKey{}, id)
+}
+
+// RequestIDFromContext returns the request ID carried by ctx. Within
+// interceptors and hooks, it is the ID of the current operation.
+func RequestIDFromContext(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey{}).(string)
+	return id
+}
+
+// requestID returns the request ID of an operation run with ctx, if
+// enabled, and a context carrying it.
+func (c *Client) requestID(ctx context.Context) (context.Context, string) {
+	if c.requestIDHeader == "" {
+		return ctx, ""
+	}
+	if id := RequestIDFromContext(ctx); id != "" {
+		return ctx, id
+	}
+	id := newUUID()
+	return ContextWithRequestID(ctx, id), id
+}
+
+// newUUID returns a random (version 4) UUID.
+func newUUID() string {
+	var b [16]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		panic(fmt.Sprintf("graphql: generating UUID: %v", err))
+	}
+	b[6] = b[6]&0x0f | 0x40
+	b[8] = b[8]&0x3f | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:16])
+}
@@ -0,0 +1,55 @@
+package graphql
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+)
+
+func TestCloneOpensPathFilesApart(t *testing.T) {
+	content := bytes.Repeat([]byte("0123456789abcdef"), 1<<16)
+	path := filepath.Join(t.TempDir(), "upload.bin")
+	if err := os.WriteFile(path, content, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		file, _, err := r.FormFile("file")
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		defer file.Close()
+		got, err := io.ReadAll(file)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		fmt.Fprintf(w, `{"data":{"intact":%t}}`, bytes.Equal(got, content))
+	}))
+	defer srv.Close()
+	client := NewClient(srv.URL, UseMultipartForm())
+	template := NewGraphqlRequest("mutation { upload }")
+	template.FileFromPath("file", path)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			var data struct{ Intact bool }
+			if _, err := client.Run(context.Background(), template.Clone(), &data); err != nil {
+				t.Errorf("clone %d: %v", i, err)
+			} else if !data.Intact {
+				t.Errorf("clone %d: the server got a corrupted file", i)
+			}
+		}(i)
+	}
+	wg.Wait()
+}
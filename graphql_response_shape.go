@@ -0,0 +1,329 @@
+package graphql
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// ShapeError is a difference between the data of a response and the types
+// the schema gives to the fields the query selects, such as a missing
+// field or a null non-null field.
+type ShapeError struct {
+	Path    Path
+	Message string
+}
+
+func (e ShapeError) Error() string {
+	return fmt.Sprintf("graphql: %s: %s", e.Path, e.Message)
+}
+
+// ShapeErrors are the ShapeError of a response.
+type ShapeErrors []ShapeError
+
+func (e ShapeErrors) Error() string {
+	messages := make([]string, len(e))
+	for i := range e {
+		messages[i] = e[i].Path.String() + ": " + e[i].Message
+	}
+	return "graphql: response does not match the schema: " + strings.Join(messages, "; ")
+}
+
+// ShapeFunc receives the differences between the data of a response and
+// the schema.
+type ShapeFunc func(ctx context.Context, info OperationInfo, errs ShapeErrors)
+
+// WithResponseShapeWarnings checks the data of every response against the
+// schema set with WithSchema, and calls fn with the differences, e.g. to
+// monitor the contract with the server in production.
+func WithResponseShapeWarnings(fn ShapeFunc) ClientOption {
+	return func(client *Client) {
+		client.onShape = fn
+	}
+}
+
+// WithResponseShapeErrors checks the data of every response against the
+// schema set with WithSchema, and makes Run return the differences as
+// ShapeErrors, along with the response.
+func WithResponseShapeErrors() ClientOption {
+	return func(client *Client) {
+		client.shapeErrors = true
+	}
+}
+
+// ValidateResponse checks the data of body, the response to req, against
+// s. Values nulled by the errors of the response are accepted. It returns
+// nil if the data matches, ShapeErrors if it doesn't, and other errors if
+// req or body cannot be parsed.
+func (s *Schema) ValidateResponse(req *GraphRequest, body []byte) error {
+	doc, err := parseQuery(req.query)
+	if err != nil {
+		return err
+	}
+	operation := doc.operation(req.operationName)
+	if operation == nil {
+		return fmt.Errorf("graphql: unknown operation %q", req.operationName)
+	}
+	var payload struct {
+		Data   interface{}
+		Errors []GraphErr
+	}
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	decoder.UseNumber()
+	if err := decoder.Decode(&payload); err != nil {
+		return err
+	}
+	data, ok := payload.Data.(map[string]interface{})
+	root := s.rootType(operation.typ)
+	if !ok || root == nil {
+		return nil
+	}
+	c := &shapeChecker{
+		schema:    s,
+		fragments: make(map[string]*fragmentNode),
+		vars:      make(map[string]interface{}),
+	}
+	for _, fragment := range doc.fragments {
+		c.fragments[fragment.name] = fragment
+	}
+	for _, variable := range operation.variables {
+		if variable.defaultValue != nil && variable.defaultValue.kind == valueBoolean {
+			c.vars[variable.name] = variable.defaultValue.text == "true"
+		}
+	}
+	for name, value := range req.vars {
+		c.vars[name] = value
+	}
+	for _, graphErr := range payload.Errors {
+		c.errorPaths = append(c.errorPaths, graphErr.Path)
+	}
+	c.checkSelections(data, root, operation.selections, nil)
+	if len(c.errs) > 0 {
+		return c.errs
+	}
+	return nil
+}
+
+// checkResponseShape checks body, the response to op, against the schema
+// of the client if enabled, and returns the error Run returns for it.
+func (c *Client) checkResponseShape(ctx context.Context, op *operation, body []byte) error {
+	if c.schema == nil || c.onShape == nil && !c.shapeErrors {
+		return nil
+	}
+	err := c.schema.ValidateResponse(op.req, body)
+	shapeErrs, ok := err.(ShapeErrors)
+	if !ok {
+		// the query was validated before it was sent, so failing to
+		// parse it or the response is reported by other means
+		return nil
+	}
+	c.logf(op, LogLevelInfo, LogResponseShape, "<< %v", shapeErrs)
+	if c.onShape != nil {
+		c.onShape(ctx, c.operationInfo(op), shapeErrs)
+	}
+	if c.shapeErrors {
+		return shapeErrs
+	}
+	return nil
+}
+
+type shapeChecker struct {
+	schema    *Schema
+	fragments map[string]*fragmentNode
+	// vars are the boolean values of the variables, for @skip and
+	// @include.
+	vars       map[string]interface{}
+	errorPaths []Path
+	errs       ShapeErrors
+}
+
+func (c *shapeChecker) errorf(path Path, format string, args ...interface{}) {
+	c.errs = append(c.errs, ShapeError{Path: append(Path(nil), path...), Message: fmt.Sprintf(format, args...)})
+}
+
+// nulledByError reports whether an error of the response is at or below
+// path, in which case the value at path may have been nulled.
+func (c *shapeChecker) nulledByError(path Path) bool {
+	for _, errorPath := range c.errorPaths {
+		if len(errorPath) < len(path) {
+			continue
+		}
+		matches := true
+		for i := range path {
+			if errorPath[i] != path[i] {
+				matches = false
+				break
+			}
+		}
+		if matches {
+			return true
+		}
+	}
+	return false
+}
+
+// included reports whether the @skip and @include directives keep a
+// selection, and whether that is known.
+func (c *shapeChecker) included(directives []*directiveNode) (bool, bool) {
+	return selectionIncluded(directives, c.vars)
+}
+
+// selectionIncluded reports whether the @skip and @include directives keep
+// a selection given the values of the variables, and whether that is known.
+func selectionIncluded(directives []*directiveNode, vars map[string]interface{}) (bool, bool) {
+	for _, directive := range directives {
+		if directive.name != "skip" && directive.name != "include" {
+			continue
+		}
+		for _, argument := range directive.arguments {
+			if argument.name != "if" {
+				continue
+			}
+			var condition, known bool
+			switch argument.value.kind {
+			case valueBoolean:
+				condition, known = argument.value.text == "true", true
+			case valueVariable:
+				condition, known = vars[argument.value.text].(bool)
+			}
+			if !known {
+				return false, false
+			}
+			if condition == (directive.name == "skip") {
+				return false, true
+			}
+		}
+	}
+	return true, true
+}
+
+func (c *shapeChecker) checkSelections(object map[string]interface{}, parent *schemaType, selections []*selectionNode, path Path) {
+	for _, selection := range selections {
+		included, known := c.included(selection.directives)
+		if known && !included {
+			continue
+		}
+		switch selection.kind {
+		case selectionField:
+			key := selection.responseKey()
+			value, ok := object[key]
+			if !ok {
+				if known {
+					c.errorf(append(path, KeySegment(key)), "field is missing")
+				}
+				continue
+			}
+			if selection.name == "__typename" {
+				if _, ok := value.(string); !ok {
+					c.errorf(append(path, KeySegment(key)), "expected a type name, got %s", jsonKind(value))
+				}
+				continue
+			}
+			if field := parent.fields[selection.name]; field != nil {
+				c.checkValue(value, field.typ, selection.selections, append(path, KeySegment(key)))
+			}
+		case selectionFragmentSpread:
+			if fragment := c.fragments[selection.name]; fragment != nil {
+				if t := c.fragmentType(fragment.typeCondition, parent); t != nil {
+					c.checkSelections(object, t, fragment.selections, path)
+				}
+			}
+		case selectionInlineFragment:
+			t := parent
+			if selection.typeCondition != "" {
+				t = c.fragmentType(selection.typeCondition, parent)
+			}
+			if t != nil {
+				c.checkSelections(object, t, selection.selections, path)
+			}
+		}
+	}
+}
+
+// fragmentType returns the type to check the selections of a fragment on
+// typeCondition with, or nil if they don't apply to objects of type
+// parent, or if that isn't known because parent is abstract.
+func (c *shapeChecker) fragmentType(typeCondition string, parent *schemaType) *schemaType {
+	condition := c.schema.types[typeCondition]
+	switch {
+	case condition == nil:
+		return nil
+	case typeCondition == parent.name:
+		return parent
+	case parent.kind == kindObject && condition.possibleTypes[parent.name]:
+		return parent
+	}
+	return nil
+}
+
+func (c *shapeChecker) checkValue(value interface{}, t *typeRef, selections []*selectionNode, path Path) {
+	if value == nil {
+		if t.nonNull && !c.nulledByError(path) {
+			c.errorf(path, "non-null %s is null", t)
+		}
+		return
+	}
+	if t.elem != nil {
+		list, ok := value.([]interface{})
+		if !ok {
+			c.errorf(path, "expected %s, got %s", t, jsonKind(value))
+			return
+		}
+		for i, item := range list {
+			c.checkValue(item, t.elem, selections, append(path, IndexSegment(i)))
+		}
+		return
+	}
+	named := c.schema.types[t.name]
+	if named == nil {
+		return
+	}
+	switch named.kind {
+	case kindScalar:
+		if !jsonIsScalar(value, named.name) {
+			c.errorf(path, "expected %s, got %s", named.name, jsonKind(value))
+		}
+	case kindEnum:
+		if s, ok := value.(string); !ok || named.enumValues[s] == nil {
+			c.errorf(path, "expected a value of enum %s, got %s", named.name, jsonKind(value))
+		}
+	case kindObject, kindInterface, kindUnion:
+		object, ok := value.(map[string]interface{})
+		if !ok {
+			c.errorf(path, "expected an object of type %s, got %s", named.name, jsonKind(value))
+			return
+		}
+		if named.kind != kindObject {
+			if typeName, ok := object["__typename"].(string); ok {
+				concrete := c.schema.types[typeName]
+				if concrete == nil || concrete.kind != kindObject || !named.possibleTypes[typeName] {
+					c.errorf(path, "%s is not a possible type of %s", typeName, named.name)
+					return
+				}
+				named = concrete
+			}
+		}
+		c.checkSelections(object, named, selections, path)
+	}
+}
+
+// jsonKind describes a decoded JSON value in messages.
+func jsonKind(value interface{}) string {
+	switch value := value.(type) {
+	case nil:
+		return "null"
+	case string:
+		return fmt.Sprintf("string %q", value)
+	case json.Number:
+		return "number " + value.String()
+	case bool:
+		return fmt.Sprintf("boolean %t", value)
+	case []interface{}:
+		return "a list"
+	case map[string]interface{}:
+		return "an object"
+	}
+	return fmt.Sprintf("%T", value)
+}
@@ -0,0 +1,211 @@
+package graphql
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+const shapeSchema = `
+type Query {
+	user(id: ID!): User
+	users: [User!]!
+	node(id: ID!): Node
+}
+
+interface Node { id: ID! }
+
+type User implements Node {
+	id: ID!
+	name: String
+	role: Role!
+	age: Int
+}
+
+type Post implements Node {
+	id: ID!
+	title: String!
+}
+
+enum Role { ADMIN MEMBER }
+`
+
+func TestSchemaValidateResponse(t *testing.T) {
+	schema, err := ParseSchema(shapeSchema)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name  string
+		query string
+		vars  map[string]interface{}
+		body  string
+		// want are the expected differences, as "path: message".
+		want []string
+	}{
+		{
+			name:  "matching",
+			query: `{ user(id: 1) { id name role age } }`,
+			body:  `{"data":{"user":{"id":"1","name":null,"role":"ADMIN","age":30}}}`,
+		},
+		{
+			name:  "null data",
+			query: `{ user(id: 1) { id } }`,
+			body:  `{"data":null,"errors":[{"message":"boom"}]}`,
+		},
+		{
+			name:  "missing field",
+			query: `{ user(id: 1) { id name } }`,
+			body:  `{"data":{"user":{"id":"1"}}}`,
+			want:  []string{"user.name: field is missing"},
+		},
+		{
+			name:  "null non-null field",
+			query: `{ user(id: 1) { id role } }`,
+			body:  `{"data":{"user":{"id":"1","role":null}}}`,
+			want:  []string{"user.role: non-null Role! is null"},
+		},
+		{
+			name:  "field nulled by an error",
+			query: `{ user(id: 1) { id role } }`,
+			body:  `{"data":{"user":{"id":"1","role":null}},"errors":[{"message":"denied","path":["user","role"]}]}`,
+		},
+		{
+			name:  "wrong scalar",
+			query: `{ user(id: 1) { age } }`,
+			body:  `{"data":{"user":{"age":"ten"}}}`,
+			want:  []string{`user.age: expected Int, got string "ten"`},
+		},
+		{
+			name:  "int out of range",
+			query: `{ user(id: 1) { age } }`,
+			body:  `{"data":{"user":{"age":3000000000}}}`,
+			want:  []string{"user.age: expected Int, got number 3000000000"},
+		},
+		{
+			name:  "unknown enum value",
+			query: `{ user(id: 1) { role } }`,
+			body:  `{"data":{"user":{"role":"OWNER"}}}`,
+			want:  []string{`user.role: expected a value of enum Role, got string "OWNER"`},
+		},
+		{
+			name:  "list items",
+			query: `{ users { id } }`,
+			body:  `{"data":{"users":[{"id":"1"},{"id":null}]}}`,
+			want:  []string{"users.1.id: non-null ID! is null"},
+		},
+		{
+			name:  "not a list",
+			query: `{ users { id } }`,
+			body:  `{"data":{"users":{"id":"1"}}}`,
+			want:  []string{"users: expected [User!]!, got an object"},
+		},
+		{
+			name:  "alias",
+			query: `{ me: user(id: 1) { id } }`,
+			body:  `{"data":{"me":{"id":7}}}`,
+		},
+		{
+			name:  "skipped field",
+			query: `query($full: Boolean!) { user(id: 1) { id name @include(if: $full) } }`,
+			vars:  map[string]interface{}{"full": false},
+			body:  `{"data":{"user":{"id":"1"}}}`,
+		},
+		{
+			name:  "fragment on the concrete type",
+			query: `{ node(id: 1) { __typename id ... on Post { title } } }`,
+			body:  `{"data":{"node":{"__typename":"Post","id":"1"}}}`,
+			want:  []string{"node.title: field is missing"},
+		},
+		{
+			name:  "fragment on another type",
+			query: `{ node(id: 1) { __typename id ... on Post { title } } }`,
+			body:  `{"data":{"node":{"__typename":"User","id":"1"}}}`,
+		},
+		{
+			name:  "impossible type",
+			query: `{ node(id: 1) { __typename id } }`,
+			body:  `{"data":{"node":{"__typename":"Comment","id":"1"}}}`,
+			want:  []string{"node: Comment is not a possible type of Node"},
+		},
+		{
+			name:  "several differences",
+			query: `{ user(id: 1) { id name role } }`,
+			body:  `{"data":{"user":{"id":true,"role":"OWNER"}}}`,
+			want: []string{
+				"user.id: expected ID, got boolean true",
+				"user.name: field is missing",
+				`user.role: expected a value of enum Role, got string "OWNER"`,
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := NewGraphqlRequest(tt.query)
+			for name, value := range tt.vars {
+				req.Var(name, value)
+			}
+			err := schema.ValidateResponse(req, []byte(tt.body))
+			if len(tt.want) == 0 {
+				if err != nil {
+					t.Fatalf("got %v, want no error", err)
+				}
+				return
+			}
+			shapeErrs, ok := err.(ShapeErrors)
+			if !ok {
+				t.Fatalf("got %v, want ShapeErrors", err)
+			}
+			got := make([]string, len(shapeErrs))
+			for i, shapeErr := range shapeErrs {
+				got[i] = shapeErr.Path.String() + ": " + shapeErr.Message
+			}
+			if strings.Join(got, "\n") != strings.Join(tt.want, "\n") {
+				t.Fatalf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestResponseShapeOptions(t *testing.T) {
+	schema, err := ParseSchema(shapeSchema)
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := newPayloadServer(t, `{"user":{"id":"1"}}`)
+	query := `query GetUser { user(id: 1) { id name } }`
+
+	t.Run("errors", func(t *testing.T) {
+		client := NewClient(srv.URL, WithSchema(schema), WithResponseShapeErrors())
+		var data struct{ User struct{ ID string } }
+		graphResponse, err := client.Run(context.Background(), NewGraphqlRequest(query), &data)
+		shapeErrs, ok := err.(ShapeErrors)
+		if !ok || len(shapeErrs) != 1 || shapeErrs[0].Path.String() != "user.name" {
+			t.Fatalf("got %v, want the missing name", err)
+		}
+		if graphResponse == nil || data.User.ID != "1" {
+			t.Fatalf("got response %+v and data %+v, want both", graphResponse, data)
+		}
+	})
+
+	t.Run("warnings", func(t *testing.T) {
+		var warned ShapeErrors
+		var operation string
+		client := NewClient(srv.URL, WithSchema(schema), WithResponseShapeWarnings(func(ctx context.Context, info OperationInfo, errs ShapeErrors) {
+			operation, warned = info.Name, errs
+		}))
+		if _, err := client.Run(context.Background(), NewGraphqlRequest(query), nil); err != nil {
+			t.Fatal(err)
+		}
+		if operation != "GetUser" || len(warned) != 1 || warned[0].Message != "field is missing" {
+			t.Fatalf("warned about %q with %v", operation, warned)
+		}
+	})
+
+	t.Run("without a schema", func(t *testing.T) {
+		client := NewClient(srv.URL, WithResponseShapeErrors())
+		if _, err := client.Run(context.Background(), NewGraphqlRequest(query), nil); err != nil {
+			t.Fatalf("got %v, want no check without a schema", err)
+		}
+	})
+}
@@ -0,0 +1,75 @@
+package graphql
+
+import "context"
+
+// ResponseWarning is a warning a server sent in the extensions of a
+// response, such as the use of a deprecated field. GitHub and other
+// gateways send them as:
+//
+//	{"extensions": {"warnings": [{"type": "DEPRECATION", "message": "...", "link": "..."}]}}
+//
+// Entries of a "deprecations" extension are read as warnings of type
+// DEPRECATION.
+type ResponseWarning struct {
+	// Type is the kind of warning, e.g. DEPRECATION, if the server sent it.
+	Type    string
+	Message string
+	Link    string
+	// Fields holds every field of the warning, including the ones above.
+	Fields map[string]interface{}
+}
+
+// ResponseWarningFunc receives a warning sent with a response.
+type ResponseWarningFunc func(ctx context.Context, info OperationInfo, warning ResponseWarning)
+
+// WithResponseWarnings calls fn with every warning sent in the extensions
+// of responses, e.g. to alert on deprecated fields used in production.
+// The warnings are also in GraphResponse.Warnings and passed to Client.Log.
+func WithResponseWarnings(fn ResponseWarningFunc) ClientOption {
+	return func(client *Client) {
+		client.onResponseWarning = fn
+	}
+}
+
+// parseResponseWarnings returns the warnings of the extensions of a
+// response.
+func parseResponseWarnings(extensions map[string]interface{}) []ResponseWarning {
+	var warnings []ResponseWarning
+	for _, source := range []struct {
+		key, typ string
+	}{{"warnings", ""}, {"deprecations", "DEPRECATION"}} {
+		entries, _ := extensions[source.key].([]interface{})
+		for _, entry := range entries {
+			warning := ResponseWarning{Type: source.typ}
+			switch entry := entry.(type) {
+			case string:
+				warning.Message = entry
+			case map[string]interface{}:
+				warning.Fields = entry
+				if typ, ok := entry["type"].(string); ok {
+					warning.Type = typ
+				}
+				warning.Message, _ = entry["message"].(string)
+				warning.Link, _ = entry["link"].(string)
+			default:
+				continue
+			}
+			warnings = append(warnings, warning)
+		}
+	}
+	return warnings
+}
+
+// reportResponseWarnings logs the warnings of graphResponse and passes them
+// to the callback set with WithResponseWarnings.
+func (c *Client) reportResponseWarnings(ctx context.Context, op *operation, graphResponse *GraphResponse) {
+	if graphResponse == nil {
+		return
+	}
+	for _, warning := range graphResponse.Warnings {
+		c.logf(op, LogLevelInfo, LogDeprecations, "<< warning: %s %s", warning.Type, warning.Message)
+		if c.onResponseWarning != nil {
+			c.onResponseWarning(ctx, c.operationInfo(op), warning)
+		}
+	}
+}
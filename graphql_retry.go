@@ -0,0 +1,275 @@
+package graphql
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"math"
+	"math/rand"
+	"net"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// RetryPolicy controls how the Client retries requests that fail with a
+// transient error, as told by DefaultRetryClassifier or the classifier set
+// with WithRetryClassifier. The delay requested by the Retry-After header
+// of 429 and 503 responses replaces the backoff.
+type RetryPolicy struct {
+	// MaxAttempts is the total number of attempts, including the first one.
+	MaxAttempts int
+	// InitialBackoff is the delay before the first retry.
+	InitialBackoff time.Duration
+	// MaxBackoff caps the delay between two attempts.
+	MaxBackoff time.Duration
+	// Multiplier is applied to the delay after every attempt.
+	Multiplier float64
+	// Jitter is the fraction (between 0 and 1) of each delay that is
+	// randomized to avoid synchronized retries.
+	Jitter float64
+	// PerAttemptTimeout, if set, bounds every single attempt independently
+	// of the deadline of the context passed to Run, so a slow attempt can
+	// be retried before the overall budget is spent.
+	PerAttemptTimeout time.Duration
+	// MaxRetryAfter, if set, caps the delay a server can request with the
+	// Retry-After header of a 429 or 503 response: responses asking for
+	// longer are returned without retrying.
+	MaxRetryAfter time.Duration
+}
+
+// DefaultRetryPolicy returns a RetryPolicy with three attempts and an
+// exponential backoff starting at 100ms.
+func DefaultRetryPolicy() RetryPolicy {
+	return RetryPolicy{
+		MaxAttempts:    3,
+		InitialBackoff: 100 * time.Millisecond,
+		MaxBackoff:     2 * time.Second,
+		Multiplier:     2,
+		Jitter:         0.2,
+		MaxRetryAfter:  30 * time.Second,
+	}
+}
+
+// WithRetry enables retries of transient failures using the given policy.
+// Retries are disabled by default. Requests with files that can't be
+// rewound (that don't implement io.Seeker) are never retried.
+//
+//	NewClient(url, WithRetry(DefaultRetryPolicy()))
+func WithRetry(policy RetryPolicy) ClientOption {
+	return func(client *Client) {
+		client.retry = &policy
+	}
+}
+
+// Retry describes a retry about to be made.
+type Retry struct {
+	// Attempt is the number of the failed attempt, starting at 1.
+	Attempt int
+	// Delay is the wait before the next attempt.
+	Delay time.Duration
+	// RetryAfter is set when Delay was requested by the server with a
+	// Retry-After header rather than computed from the backoff.
+	RetryAfter bool
+	// StatusCode is the status of the failed attempt, or 0 if it failed
+	// with Err.
+	StatusCode int
+	Err        error
+}
+
+// RetryFunc is called before every retry.
+type RetryFunc func(ctx context.Context, info OperationInfo, retry Retry)
+
+// WithRetryHook calls fn before every retry made by the RetryPolicy set
+// with WithRetry, e.g. to count retries or log the waits.
+func WithRetryHook(fn RetryFunc) ClientOption {
+	return func(client *Client) {
+		client.onRetry = fn
+	}
+}
+
+// attemptContext derives the context of a single attempt from ctx.
+func (p *RetryPolicy) attemptContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	if p == nil || p.PerAttemptTimeout <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, p.PerAttemptTimeout)
+}
+
+// backoff returns the delay to wait after the given attempt (starting at 1).
+func (p *RetryPolicy) backoff(attempt int) time.Duration {
+	multiplier := p.Multiplier
+	if multiplier < 1 {
+		multiplier = 1
+	}
+	delay := float64(p.InitialBackoff) * math.Pow(multiplier, float64(attempt-1))
+	if p.MaxBackoff > 0 && delay > float64(p.MaxBackoff) {
+		delay = float64(p.MaxBackoff)
+	}
+	if p.Jitter > 0 {
+		jitter := math.Min(p.Jitter, 1)
+		delay = delay*(1-jitter) + delay*jitter*rand.Float64() //nolint:gosec
+	}
+	return time.Duration(delay)
+}
+
+// delay returns the wait after the given attempt, which ended with res:
+// the Retry-After delay of 429 and 503 responses, or the backoff. It
+// reports false if the server asked to wait longer than MaxRetryAfter.
+func (p *RetryPolicy) delay(attempt int, res *http.Response) (time.Duration, bool, bool) {
+	if res != nil && (res.StatusCode == http.StatusTooManyRequests || res.StatusCode == http.StatusServiceUnavailable) {
+		if retryAfter, ok := parseRetryAfter(res.Header, time.Now()); ok {
+			if p.MaxRetryAfter > 0 && retryAfter > p.MaxRetryAfter {
+				return 0, true, false
+			}
+			return retryAfter, true, true
+		}
+	}
+	return p.backoff(attempt), false, true
+}
+
+// RetryClassifier reports whether an operation that failed with err is
+// worth retrying.
+type RetryClassifier func(err error) bool
+
+// WithRetryClassifier replaces DefaultRetryClassifier to decide which
+// attempts the RetryPolicy set with WithRetry retries. Failed attempts are
+// classified as the error Run would return: an *HTTPError for statuses
+// other than 200, or the transport error, and GraphErrors for 200
+// responses with errors.
+func WithRetryClassifier(classifier RetryClassifier) ClientOption {
+	return func(client *Client) {
+		client.retryClassifier = classifier
+	}
+}
+
+// retryableCodes are the error codes of transient GraphQL errors.
+var retryableCodes = []ErrorCode{CodeServiceUnavailable, CodeThrottled, CodeRateLimited}
+
+// DefaultRetryClassifier retries network errors and timeouts, 429 Too Many
+// Requests and 5xx responses, and GraphQL errors with the codes
+// SERVICE_UNAVAILABLE, THROTTLED or RATE_LIMITED. Canceled operations and
+// responses exceeding WithMaxResponseBytes aren't retried.
+func DefaultRetryClassifier(err error) bool {
+	var tooLarge *ResponseTooLargeError
+	var httpErr *HTTPError
+	var timeout *TimeoutError
+	var netErr net.Error
+	var urlErr *url.Error
+	switch {
+	case err == nil, errors.Is(err, context.Canceled), errors.As(err, &tooLarge):
+		return false
+	case errors.As(err, &httpErr):
+		return httpErr.StatusCode == http.StatusTooManyRequests || httpErr.StatusCode >= http.StatusInternalServerError
+	case errors.As(err, &timeout), errors.Is(err, context.DeadlineExceeded),
+		errors.As(err, &netErr), errors.As(err, &urlErr),
+		errors.Is(err, io.ErrUnexpectedEOF), errors.Is(err, io.EOF):
+		return true
+	}
+	for _, code := range retryableCodes {
+		if HasErrorCode(err, code) {
+			return true
+		}
+	}
+	return false
+}
+
+// IsRetryable reports whether an operation that failed with err is worth
+// retrying, according to DefaultRetryClassifier. It is meant for callers
+// running their own retry loops.
+func IsRetryable(err error) bool {
+	return DefaultRetryClassifier(err)
+}
+
+// attemptError returns the error Run would return for an attempt that
+// ended with res, its body, and err.
+func attemptError(res *http.Response, body *bytes.Buffer, err error) error {
+	if err != nil || res == nil {
+		return err
+	}
+	var data []byte
+	if body != nil {
+		data = body.Bytes()
+	}
+	if res.StatusCode != http.StatusOK {
+		return newHTTPError(res, data)
+	}
+	if !bytes.Contains(data, []byte(`"errors"`)) {
+		return nil
+	}
+	var payload struct {
+		Errors GraphErrors `json:"errors"`
+	}
+	if json.Unmarshal(data, &payload) != nil || len(payload.Errors) == 0 {
+		return nil
+	}
+	return payload.Errors
+}
+
+// shouldRetry reports whether an attempt that ended with res, its body and
+// err failed with an error the classifier of the client retries.
+func (c *Client) shouldRetry(ctx context.Context, res *http.Response, body *bytes.Buffer, err error) bool {
+	if ctx.Err() != nil {
+		return false
+	}
+	classifier := c.retryClassifier
+	if classifier == nil {
+		classifier = DefaultRetryClassifier
+	}
+	return classifier(attemptError(res, body, err))
+}
+
+// isTransientFailure reports whether res and err describe a network error,
+// a 429 Too Many Requests or a 5xx response.
+func isTransientFailure(res *http.Response, err error) bool {
+	if err == nil && res != nil && res.StatusCode == http.StatusOK {
+		return false
+	}
+	return DefaultRetryClassifier(attemptError(res, nil, err))
+}
+
+func sleepContext(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// fileOffsets records the current position of every file reader so they
+// can be rewound before a retry. It reports false if any of them isn't an
+// io.Seeker.
+func fileOffsets(files []File) ([]int64, bool) {
+	offsets := make([]int64, len(files))
+	for i := range files {
+		seeker, ok := files[i].R.(io.Seeker)
+		if !ok {
+			return nil, false
+		}
+		offset, err := seeker.Seek(0, io.SeekCurrent)
+		if err != nil {
+			return nil, false
+		}
+		offsets[i] = offset
+	}
+	return offsets, true
+}
+
+// rewindFiles moves every file reader back to its recorded offset.
+func rewindFiles(files []File, offsets []int64) error {
+	if offsets == nil {
+		return nil
+	}
+	for i := range files {
+		if _, err := files[i].R.(io.Seeker).Seek(offsets[i], io.SeekStart); err != nil {
+			return errors.Wrap(err, "rewind file")
+		}
+	}
+	return nil
+}
@@ -0,0 +1,370 @@
+package graphql
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// ScalarCodec converts the values of a custom scalar between a Go type and
+// its JSON representation.
+type ScalarCodec struct {
+	// Type is the Go type of the values, e.g. reflect.TypeOf(time.Time{}).
+	Type reflect.Type
+	// Encode returns the JSON value of a variable of type Type, such as a
+	// string.
+	Encode func(value interface{}) (interface{}, error)
+	// Decode returns the value of type Type of a field of a response. It is
+	// not called for null.
+	Decode func(data json.RawMessage) (interface{}, error)
+}
+
+// TimeScalar is a ScalarCodec for time.Time values formatted with layout,
+// e.g. TimeScalar("2006-01-02") for a Date scalar.
+func TimeScalar(layout string) ScalarCodec {
+	return ScalarCodec{
+		Type: reflect.TypeOf(time.Time{}),
+		Encode: func(value interface{}) (interface{}, error) {
+			return value.(time.Time).Format(layout), nil
+		},
+		Decode: func(data json.RawMessage) (interface{}, error) {
+			var s string
+			if err := json.Unmarshal(data, &s); err != nil {
+				return nil, err
+			}
+			return time.Parse(layout, s)
+		},
+	}
+}
+
+// Scalars is a registry of codecs for custom scalars, by scalar name.
+// Variables and fields of responses of the registered Go types are
+// converted by the codecs, wherever they are nested in maps, slices,
+// pointers and structs.
+//
+//	scalars := NewScalars()
+//	scalars.Register("Date", TimeScalar("2006-01-02"))
+//	client := NewClient(url, WithScalars(scalars))
+type Scalars struct {
+	mu     sync.RWMutex
+	byName map[string]ScalarCodec
+	byType map[reflect.Type]string
+	// encoded and decoded cache whether types hold values of registered
+	// types to encode or decode.
+	encoded map[reflect.Type]bool
+	decoded map[reflect.Type]bool
+}
+
+// NewScalars makes a new empty Scalars.
+func NewScalars() *Scalars {
+	return &Scalars{
+		byName:  make(map[string]ScalarCodec),
+		byType:  make(map[reflect.Type]string),
+		encoded: make(map[reflect.Type]bool),
+		decoded: make(map[reflect.Type]bool),
+	}
+}
+
+// Register registers the codec of the scalar name, replacing any codec
+// previously registered for the same name or Go type.
+func (s *Scalars) Register(name string, codec ScalarCodec) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if previous, ok := s.byName[name]; ok {
+		delete(s.byType, previous.Type)
+	}
+	if previous, ok := s.byType[codec.Type]; ok {
+		delete(s.byName, previous)
+	}
+	s.byName[name] = codec
+	s.byType[codec.Type] = name
+	s.encoded = make(map[reflect.Type]bool)
+	s.decoded = make(map[reflect.Type]bool)
+}
+
+// Codec returns the codec registered for the scalar name.
+func (s *Scalars) Codec(name string) (ScalarCodec, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	codec, ok := s.byName[name]
+	return codec, ok
+}
+
+// WithScalars converts variables and responses with the codecs of scalars.
+// WithStrictDecoding still rejects unknown fields of structs holding
+// registered types.
+func WithScalars(scalars *Scalars) ClientOption {
+	return func(client *Client) {
+		client.scalars = scalars
+	}
+}
+
+var jsonMarshalerType = reflect.TypeOf((*json.Marshaler)(nil)).Elem()
+
+// lookup returns the name and codec registered for t.
+func (s *Scalars) lookup(t reflect.Type) (string, ScalarCodec, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	name, ok := s.byType[t]
+	return name, s.byName[name], ok
+}
+
+// encodes reports whether values of type t may hold values of registered
+// types, which interfaces may.
+func (s *Scalars) encodes(t reflect.Type) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.converts(t, s.encoded, true)
+}
+
+// decodes reports whether values of type t hold values of registered
+// types. Interfaces are decoded by encoding/json.
+func (s *Scalars) decodes(t reflect.Type) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.converts(t, s.decoded, false)
+}
+
+// converts reports whether values of type t hold values of registered
+// types, caching it in cache. Types marshaling themselves are left to
+// encoding/json.
+func (s *Scalars) converts(t reflect.Type, cache map[reflect.Type]bool, interfaces bool) bool {
+	if converted, ok := cache[t]; ok {
+		return converted
+	}
+	if _, ok := s.byType[t]; ok {
+		cache[t] = true
+		return true
+	}
+	// recursive types are assumed not to hold registered types until
+	// proven otherwise
+	cache[t] = false
+	converted := false
+	switch {
+	// pointers come first, as they have the methods of the registered
+	// types they point to
+	case t.Kind() == reflect.Ptr:
+		converted = s.converts(t.Elem(), cache, interfaces)
+	case t.Implements(jsonMarshalerType), t.Implements(jsonUnmarshalerType),
+		reflect.PtrTo(t).Implements(jsonMarshalerType), reflect.PtrTo(t).Implements(jsonUnmarshalerType):
+	case t.Kind() == reflect.Interface:
+		converted = interfaces
+	case t.Kind() == reflect.Slice, t.Kind() == reflect.Array:
+		converted = s.converts(t.Elem(), cache, interfaces)
+	case t.Kind() == reflect.Map:
+		converted = t.Key().Kind() == reflect.String && s.converts(t.Elem(), cache, interfaces)
+	case t.Kind() == reflect.Struct:
+		for _, field := range structFields(t) {
+			if s.converts(field.typ, cache, interfaces) {
+				converted = true
+				break
+			}
+		}
+	}
+	cache[t] = converted
+	return converted
+}
+
+// encodeVariables returns vars with the values of registered types encoded.
+func (c *Client) encodeVariables(vars map[string]interface{}) (map[string]interface{}, error) {
+	if c.scalars == nil || len(vars) == 0 {
+		return vars, nil
+	}
+	encoded := make(map[string]interface{}, len(vars))
+	for name, value := range vars {
+		v, err := c.scalars.encode(reflect.ValueOf(value))
+		if err != nil {
+			return nil, errors.Wrapf(err, "variable %s", name)
+		}
+		encoded[name] = v
+	}
+	return encoded, nil
+}
+
+func (s *Scalars) encode(v reflect.Value) (interface{}, error) {
+	if !v.IsValid() {
+		return nil, nil
+	}
+	if !s.encodes(v.Type()) {
+		return v.Interface(), nil
+	}
+	if name, codec, ok := s.lookup(v.Type()); ok {
+		encoded, err := codec.Encode(v.Interface())
+		if err != nil {
+			return nil, errors.Wrapf(err, "encode %s", name)
+		}
+		return encoded, nil
+	}
+	switch v.Kind() {
+	case reflect.Ptr, reflect.Interface:
+		if v.IsNil() {
+			return nil, nil
+		}
+		return s.encode(v.Elem())
+	case reflect.Slice, reflect.Array:
+		if v.Kind() == reflect.Slice && v.IsNil() {
+			return nil, nil
+		}
+		list := make([]interface{}, v.Len())
+		for i := range list {
+			item, err := s.encode(v.Index(i))
+			if err != nil {
+				return nil, err
+			}
+			list[i] = item
+		}
+		return list, nil
+	case reflect.Map:
+		if v.IsNil() {
+			return nil, nil
+		}
+		object := make(map[string]interface{}, v.Len())
+		iter := v.MapRange()
+		for iter.Next() {
+			item, err := s.encode(iter.Value())
+			if err != nil {
+				return nil, err
+			}
+			object[iter.Key().String()] = item
+		}
+		return object, nil
+	case reflect.Struct:
+		object := make(map[string]interface{})
+		for _, field := range structFields(v.Type()) {
+			fv, ok := fieldByIndex(v, field.index, false)
+			if !ok || field.omitEmpty && isEmptyValue(fv) {
+				continue
+			}
+			item, err := s.encode(fv)
+			if err != nil {
+				return nil, err
+			}
+			object[field.name] = item
+		}
+		return object, nil
+	}
+	return v.Interface(), nil
+}
+
+// jsonField is a field of a struct as encoding/json sees it.
+type jsonField struct {
+	name      string
+	index     []int
+	typ       reflect.Type
+	omitEmpty bool
+}
+
+// structFields returns the fields encoding/json encodes for t, following
+// its rules for tags and embedded structs.
+func structFields(t reflect.Type) []jsonField {
+	var fields []jsonField
+	seen := make(map[string]int)
+	dominant := make(map[string]bool)
+	var walk func(t reflect.Type, index []int)
+	walk = func(t reflect.Type, index []int) {
+		for i := 0; i < t.NumField(); i++ {
+			field := t.Field(i)
+			tag := field.Tag.Get("json")
+			if tag == "-" {
+				continue
+			}
+			name, options, _ := strings.Cut(tag, ",")
+			fieldIndex := append(append([]int(nil), index...), i)
+			fieldType := field.Type
+			if field.Anonymous && name == "" {
+				if fieldType.Kind() == reflect.Ptr {
+					fieldType = fieldType.Elem()
+				}
+				if fieldType.Kind() == reflect.Struct {
+					walk(fieldType, fieldIndex)
+					continue
+				}
+			}
+			if !field.IsExported() {
+				continue
+			}
+			tagged := name != ""
+			if !tagged {
+				name = field.Name
+			}
+			current := jsonField{
+				name:      name,
+				index:     fieldIndex,
+				typ:       field.Type,
+				omitEmpty: strings.Contains(","+options+",", ",omitempty,"),
+			}
+			// shallower fields, then tagged ones, hide the others
+			if i, ok := seen[name]; ok {
+				previous := fields[i]
+				if len(previous.index) < len(current.index) || len(previous.index) == len(current.index) && (dominant[name] || !tagged) {
+					continue
+				}
+				fields[i] = current
+				dominant[name] = tagged
+				continue
+			}
+			seen[name] = len(fields)
+			dominant[name] = tagged
+			fields = append(fields, current)
+		}
+	}
+	walk(t, nil)
+	return fields
+}
+
+// matchField returns the field of a key of a JSON object, preferring an
+// exact match to a case-insensitive one like encoding/json.
+func matchField(fields []jsonField, key string) (jsonField, bool) {
+	for _, field := range fields {
+		if field.name == key {
+			return field, true
+		}
+	}
+	for _, field := range fields {
+		if strings.EqualFold(field.name, key) {
+			return field, true
+		}
+	}
+	return jsonField{}, false
+}
+
+// fieldByIndex returns the field of v at index, allocating nil embedded
+// pointers when alloc is set and reporting false if it cannot reach it.
+func fieldByIndex(v reflect.Value, index []int, alloc bool) (reflect.Value, bool) {
+	for i, x := range index {
+		if i > 0 && v.Kind() == reflect.Ptr {
+			if v.IsNil() {
+				if !alloc {
+					return reflect.Value{}, false
+				}
+				v.Set(reflect.New(v.Type().Elem()))
+			}
+			v = v.Elem()
+		}
+		v = v.Field(x)
+	}
+	return v, true
+}
+
+// isEmptyValue reports whether omitempty leaves v out.
+func isEmptyValue(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Array, reflect.Map, reflect.Slice, reflect.String:
+		return v.Len() == 0
+	case reflect.Bool:
+		return !v.Bool()
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return v.Int() == 0
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return v.Uint() == 0
+	case reflect.Float32, reflect.Float64:
+		return v.Float() == 0
+	case reflect.Interface, reflect.Ptr:
+		return v.IsNil()
+	}
+	return false
+}
@@ -0,0 +1,187 @@
+package graphql
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func dateScalars() *Scalars {
+	scalars := NewScalars()
+	scalars.Register("Date", TimeScalar("2006-01-02"))
+	return scalars
+}
+
+func TestScalarsEncodeVariables(t *testing.T) {
+	first := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	second := first.AddDate(0, 0, 1)
+	tests := []struct {
+		name  string
+		value interface{}
+		want  string
+	}{
+		{name: "value", value: first, want: `"2024-03-01"`},
+		{name: "pointer", value: &first, want: `"2024-03-01"`},
+		{name: "nil pointer", value: (*time.Time)(nil), want: `null`},
+		{name: "slice", value: []time.Time{first, second}, want: `["2024-03-01","2024-03-02"]`},
+		{name: "map", value: map[string]time.Time{"from": first}, want: `{"from":"2024-03-01"}`},
+		{
+			name: "struct",
+			value: struct {
+				From time.Time  `json:"from"`
+				To   *time.Time `json:"to,omitempty"`
+				Note string
+			}{From: first},
+			want: `{"Note":"","from":"2024-03-01"}`,
+		},
+		{name: "interfaces", value: []interface{}{second, 1}, want: `["2024-03-02",1]`},
+		{name: "unregistered type", value: "today", want: `"today"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := newPayloadServer(t, `{}`)
+			client := NewClient(srv.URL, WithScalars(dateScalars()))
+			req := NewGraphqlRequest("query ($v: Date) { events(on: $v) { id } }")
+			req.Var("v", tt.value)
+			if _, err := client.Run(context.Background(), req, nil); err != nil {
+				t.Fatal(err)
+			}
+			got, err := json.Marshal(srv.last().Variables["v"])
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(got) != tt.want {
+				t.Fatalf("sent %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+type eventData struct {
+	Event struct {
+		On     time.Time
+		Until  *time.Time
+		Dates  []time.Time
+		ByName map[string]time.Time
+	} `json:"event"`
+}
+
+func TestScalarsDecodeResponses(t *testing.T) {
+	first := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	second := first.AddDate(0, 0, 1)
+	var full eventData
+	full.Event.On = first
+	full.Event.Until = &second
+	full.Event.Dates = []time.Time{first, second}
+	full.Event.ByName = map[string]time.Time{"start": first}
+	var onlyOn eventData
+	onlyOn.Event.On = first
+	tests := []struct {
+		name    string
+		data    string
+		strict  bool
+		want    eventData
+		wantErr string
+	}{
+		{
+			name: "fields",
+			data: `{"event":{"on":"2024-03-01","until":"2024-03-02","dates":["2024-03-01","2024-03-02"],"byName":{"start":"2024-03-01"}}}`,
+			want: full,
+		},
+		{
+			name: "nulls",
+			data: `{"event":{"on":null,"until":null,"dates":null,"byName":null}}`,
+		},
+		{
+			name:    "invalid value",
+			data:    `{"event":{"on":"03/01/2024"}}`,
+			wantErr: "decode Date",
+		},
+		{
+			name: "unknown field",
+			data: `{"event":{"on":"2024-03-01","venue":"Hall"}}`,
+			want: onlyOn,
+		},
+		{
+			name:    "unknown field with strict decoding",
+			data:    `{"event":{"on":"2024-03-01","venue":"Hall"}}`,
+			strict:  true,
+			wantErr: `unknown field "venue"`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := newPayloadServer(t, tt.data)
+			opts := []ClientOption{WithScalars(dateScalars())}
+			if tt.strict {
+				opts = append(opts, WithStrictDecoding())
+			}
+			var got eventData
+			_, err := NewClient(srv.URL, opts...).Run(context.Background(), NewGraphqlRequest("{ event { on until dates byName } }"), &got)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("got error %v, want one containing %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("decoded %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+type cents int64
+
+func TestScalarCodecErrors(t *testing.T) {
+	money := ScalarCodec{
+		Type: reflect.TypeOf(cents(0)),
+		Encode: func(value interface{}) (interface{}, error) {
+			return nil, errors.New("negative amount")
+		},
+		Decode: func(data json.RawMessage) (interface{}, error) {
+			return string(data), nil
+		},
+	}
+	scalars := NewScalars()
+	scalars.Register("Money", money)
+	srv := newPayloadServer(t, `{"price":"1.50"}`)
+	client := NewClient(srv.URL, WithScalars(scalars))
+
+	req := NewGraphqlRequest("query ($max: Money) { price(max: $max) }")
+	req.Var("max", cents(-1))
+	if _, err := client.Run(context.Background(), req, nil); err == nil || !strings.Contains(err.Error(), "variable max: encode Money: negative amount") {
+		t.Fatalf("got error %v", err)
+	}
+
+	var data struct{ Price cents }
+	if _, err := client.Run(context.Background(), NewGraphqlRequest("{ price }"), &data); err == nil || !strings.Contains(err.Error(), "decode Money: got string") {
+		t.Fatalf("got error %v", err)
+	}
+}
+
+func TestScalarsRegister(t *testing.T) {
+	scalars := dateScalars()
+	scalars.Register("Day", TimeScalar("02/01/2006"))
+	if _, ok := scalars.Codec("Date"); ok {
+		t.Fatal("the codec of Date wasn't replaced by the one of Day")
+	}
+	if _, ok := scalars.Codec("Day"); !ok {
+		t.Fatal("Day isn't registered")
+	}
+	srv := newPayloadServer(t, `{"on":"01/03/2024"}`)
+	var data struct{ On time.Time }
+	if _, err := NewClient(srv.URL, WithScalars(scalars)).Run(context.Background(), NewGraphqlRequest("{ on }"), &data); err != nil {
+		t.Fatal(err)
+	}
+	if want := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC); !data.On.Equal(want) {
+		t.Fatalf("decoded %v, want %v", data.On, want)
+	}
+}
@@ -0,0 +1,693 @@
+package graphql
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+
+	"github.com/pkg/errors"
+)
+
+// Schema is a GraphQL schema, used to validate queries on the client side.
+// Create one with ParseSchema or SchemaFromIntrospection.
+type Schema struct {
+	types            map[string]*schemaType
+	directives       map[string]*schemaDirective
+	queryType        string
+	mutationType     string
+	subscriptionType string
+}
+
+type typeKind string
+
+const (
+	kindScalar      typeKind = "SCALAR"
+	kindObject      typeKind = "OBJECT"
+	kindInterface   typeKind = "INTERFACE"
+	kindUnion       typeKind = "UNION"
+	kindEnum        typeKind = "ENUM"
+	kindInputObject typeKind = "INPUT_OBJECT"
+)
+
+type schemaType struct {
+	kind        typeKind
+	name        string
+	fields      map[string]*schemaField
+	inputFields map[string]*schemaInputValue
+	enumValues  map[string]*schemaEnumValue
+	interfaces  []string
+	// possibleTypes are the object types of a union or implementing an
+	// interface.
+	possibleTypes map[string]bool
+	// oneOf is set on input objects declared with @oneOf.
+	oneOf bool
+}
+
+type schemaField struct {
+	name              string
+	args              map[string]*schemaInputValue
+	typ               *typeRef
+	deprecated        bool
+	deprecationReason string
+}
+
+type schemaInputValue struct {
+	name              string
+	typ               *typeRef
+	hasDefault        bool
+	deprecated        bool
+	deprecationReason string
+}
+
+type schemaEnumValue struct {
+	name              string
+	deprecated        bool
+	deprecationReason string
+}
+
+type schemaDirective struct {
+	name string
+	args map[string]*schemaInputValue
+}
+
+func newSchema() *Schema {
+	s := &Schema{
+		types:      make(map[string]*schemaType),
+		directives: make(map[string]*schemaDirective),
+	}
+	for _, name := range []string{"Int", "Float", "String", "Boolean", "ID"} {
+		s.types[name] = &schemaType{kind: kindScalar, name: name}
+	}
+	boolean := &typeRef{name: "Boolean", nonNull: true}
+	s.directives["skip"] = &schemaDirective{name: "skip", args: map[string]*schemaInputValue{
+		"if": {name: "if", typ: boolean},
+	}}
+	s.directives["include"] = &schemaDirective{name: "include", args: map[string]*schemaInputValue{
+		"if": {name: "if", typ: boolean},
+	}}
+	return s
+}
+
+// isComposite reports whether t has fields to select.
+func (t *schemaType) isComposite() bool {
+	return t.kind == kindObject || t.kind == kindInterface || t.kind == kindUnion
+}
+
+func (t *schemaType) isInput() bool {
+	return t.kind == kindScalar || t.kind == kindEnum || t.kind == kindInputObject
+}
+
+// rootType returns the root type of operations of type operationType.
+func (s *Schema) rootType(operationType string) *schemaType {
+	switch operationType {
+	case "query":
+		return s.types[s.queryType]
+	case "mutation":
+		return s.types[s.mutationType]
+	case "subscription":
+		return s.types[s.subscriptionType]
+	}
+	return nil
+}
+
+// finish sets the root types and possible types once every type is known.
+func (s *Schema) finish() error {
+	for operationType, name := range map[string]*string{
+		"Query": &s.queryType, "Mutation": &s.mutationType, "Subscription": &s.subscriptionType,
+	} {
+		if *name == "" {
+			if _, ok := s.types[operationType]; ok {
+				*name = operationType
+			}
+		}
+	}
+	if s.types[s.queryType] == nil {
+		return errors.New("graphql: schema has no query type")
+	}
+	for _, t := range s.types {
+		if t.kind == kindObject {
+			for _, name := range t.interfaces {
+				if iface := s.types[name]; iface != nil && iface.kind == kindInterface {
+					if iface.possibleTypes == nil {
+						iface.possibleTypes = make(map[string]bool)
+					}
+					iface.possibleTypes[t.name] = true
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// ParseSchema parses a schema written in the GraphQL schema definition
+// language.
+func ParseSchema(sdl string) (*Schema, error) {
+	p, err := newParser(sdl)
+	if err != nil {
+		return nil, err
+	}
+	s := newSchema()
+	for p.tok.kind != tokenEOF {
+		if err := p.skipDescription(); err != nil {
+			return nil, err
+		}
+		extend := p.peekName("extend")
+		if extend {
+			if err := p.advance(); err != nil {
+				return nil, err
+			}
+		}
+		if err := p.typeSystemDefinition(s, extend); err != nil {
+			return nil, err
+		}
+	}
+	if err := s.finish(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+func (p *parser) skipDescription() error {
+	if p.tok.kind == tokenString || p.tok.kind == tokenBlockString {
+		return p.advance()
+	}
+	return nil
+}
+
+func (p *parser) typeSystemDefinition(s *Schema, extend bool) error {
+	if p.tok.kind != tokenName {
+		return p.unexpected("expected a definition")
+	}
+	keyword := p.tok.text
+	pos := p.tok.pos
+	if err := p.advance(); err != nil {
+		return err
+	}
+	if keyword == "schema" {
+		return p.schemaDefinition(s)
+	}
+	if keyword == "directive" {
+		return p.directiveDefinition(s)
+	}
+	kinds := map[string]typeKind{
+		"scalar": kindScalar, "type": kindObject, "interface": kindInterface,
+		"union": kindUnion, "enum": kindEnum, "input": kindInputObject,
+	}
+	kind, ok := kinds[keyword]
+	if !ok {
+		return p.lex.errorf(pos, "unexpected %q, expected a type system definition", keyword)
+	}
+	name, err := p.name()
+	if err != nil {
+		return err
+	}
+	t := s.types[name]
+	switch {
+	case t == nil && extend:
+		return p.lex.errorf(pos, "cannot extend unknown type %q", name)
+	case t == nil:
+		t = &schemaType{kind: kind, name: name}
+		s.types[name] = t
+	case !extend && !isBuiltinScalar(name):
+		return p.lex.errorf(pos, "type %q is defined twice", name)
+	case t.kind != kind:
+		return p.lex.errorf(pos, "cannot extend %q with a different kind", name)
+	}
+	if kind == kindObject || kind == kindInterface {
+		if err := p.implements(t); err != nil {
+			return err
+		}
+	}
+	directives, err := p.directives(true)
+	if err != nil {
+		return err
+	}
+	for _, directive := range directives {
+		if directive.name == "oneOf" {
+			t.oneOf = true
+		}
+	}
+	switch kind {
+	case kindObject, kindInterface:
+		return p.fieldsDefinition(t)
+	case kindUnion:
+		return p.unionMembers(t)
+	case kindEnum:
+		return p.enumValuesDefinition(t)
+	case kindInputObject:
+		if !p.peek("{") {
+			return nil
+		}
+		values, err := p.inputValuesDefinition("{", "}")
+		if err != nil {
+			return err
+		}
+		if t.inputFields == nil {
+			t.inputFields = make(map[string]*schemaInputValue)
+		}
+		for name, value := range values {
+			t.inputFields[name] = value
+		}
+	}
+	return nil
+}
+
+func isBuiltinScalar(name string) bool {
+	switch name {
+	case "Int", "Float", "String", "Boolean", "ID":
+		return true
+	}
+	return false
+}
+
+func (p *parser) schemaDefinition(s *Schema) error {
+	if _, err := p.directives(true); err != nil {
+		return err
+	}
+	if err := p.expect("{"); err != nil {
+		return err
+	}
+	for {
+		if ok, err := p.skip("}"); ok || err != nil {
+			return err
+		}
+		operationType, err := p.name()
+		if err != nil {
+			return err
+		}
+		if err := p.expect(":"); err != nil {
+			return err
+		}
+		name, err := p.name()
+		if err != nil {
+			return err
+		}
+		switch operationType {
+		case "query":
+			s.queryType = name
+		case "mutation":
+			s.mutationType = name
+		case "subscription":
+			s.subscriptionType = name
+		default:
+			return fmt.Errorf("graphql: unknown operation type %q in schema definition", operationType)
+		}
+	}
+}
+
+func (p *parser) directiveDefinition(s *Schema) error {
+	if err := p.expect("@"); err != nil {
+		return err
+	}
+	name, err := p.name()
+	if err != nil {
+		return err
+	}
+	directive := &schemaDirective{name: name, args: map[string]*schemaInputValue{}}
+	if p.peek("(") {
+		if directive.args, err = p.inputValuesDefinition("(", ")"); err != nil {
+			return err
+		}
+	}
+	if p.peekName("repeatable") {
+		if err := p.advance(); err != nil {
+			return err
+		}
+	}
+	if err := p.expectName("on"); err != nil {
+		return err
+	}
+	if _, err := p.skip("|"); err != nil {
+		return err
+	}
+	for {
+		if _, err := p.name(); err != nil {
+			return err
+		}
+		if ok, err := p.skip("|"); !ok || err != nil {
+			break
+		}
+	}
+	s.directives[name] = directive
+	return nil
+}
+
+func (p *parser) implements(t *schemaType) error {
+	if !p.peekName("implements") {
+		return nil
+	}
+	if err := p.advance(); err != nil {
+		return err
+	}
+	if _, err := p.skip("&"); err != nil {
+		return err
+	}
+	for {
+		name, err := p.name()
+		if err != nil {
+			return err
+		}
+		t.interfaces = append(t.interfaces, name)
+		if ok, err := p.skip("&"); !ok || err != nil {
+			return err
+		}
+	}
+}
+
+func (p *parser) fieldsDefinition(t *schemaType) error {
+	if ok, err := p.skip("{"); !ok || err != nil {
+		return err
+	}
+	if t.fields == nil {
+		t.fields = make(map[string]*schemaField)
+	}
+	for {
+		if ok, err := p.skip("}"); ok || err != nil {
+			return err
+		}
+		if err := p.skipDescription(); err != nil {
+			return err
+		}
+		field := &schemaField{args: map[string]*schemaInputValue{}}
+		var err error
+		if field.name, err = p.name(); err != nil {
+			return err
+		}
+		if p.peek("(") {
+			if field.args, err = p.inputValuesDefinition("(", ")"); err != nil {
+				return err
+			}
+		}
+		if err := p.expect(":"); err != nil {
+			return err
+		}
+		if field.typ, err = p.typeRef(); err != nil {
+			return err
+		}
+		directives, err := p.directives(true)
+		if err != nil {
+			return err
+		}
+		field.deprecated, field.deprecationReason = deprecation(directives)
+		t.fields[field.name] = field
+	}
+}
+
+func (p *parser) inputValuesDefinition(open, close string) (map[string]*schemaInputValue, error) {
+	if err := p.expect(open); err != nil {
+		return nil, err
+	}
+	values := make(map[string]*schemaInputValue)
+	for {
+		if ok, err := p.skip(close); ok || err != nil {
+			return values, err
+		}
+		if err := p.skipDescription(); err != nil {
+			return nil, err
+		}
+		value := &schemaInputValue{}
+		var err error
+		if value.name, err = p.name(); err != nil {
+			return nil, err
+		}
+		if err := p.expect(":"); err != nil {
+			return nil, err
+		}
+		if value.typ, err = p.typeRef(); err != nil {
+			return nil, err
+		}
+		if ok, err := p.skip("="); err != nil {
+			return nil, err
+		} else if ok {
+			if _, err := p.value(true); err != nil {
+				return nil, err
+			}
+			value.hasDefault = true
+		}
+		directives, err := p.directives(true)
+		if err != nil {
+			return nil, err
+		}
+		value.deprecated, value.deprecationReason = deprecation(directives)
+		values[value.name] = value
+	}
+}
+
+func (p *parser) unionMembers(t *schemaType) error {
+	if ok, err := p.skip("="); !ok || err != nil {
+		return err
+	}
+	if _, err := p.skip("|"); err != nil {
+		return err
+	}
+	if t.possibleTypes == nil {
+		t.possibleTypes = make(map[string]bool)
+	}
+	for {
+		name, err := p.name()
+		if err != nil {
+			return err
+		}
+		t.possibleTypes[name] = true
+		if ok, err := p.skip("|"); !ok || err != nil {
+			return err
+		}
+	}
+}
+
+func (p *parser) enumValuesDefinition(t *schemaType) error {
+	if ok, err := p.skip("{"); !ok || err != nil {
+		return err
+	}
+	if t.enumValues == nil {
+		t.enumValues = make(map[string]*schemaEnumValue)
+	}
+	for {
+		if ok, err := p.skip("}"); ok || err != nil {
+			return err
+		}
+		if err := p.skipDescription(); err != nil {
+			return err
+		}
+		name, err := p.name()
+		if err != nil {
+			return err
+		}
+		directives, err := p.directives(true)
+		if err != nil {
+			return err
+		}
+		value := &schemaEnumValue{name: name}
+		value.deprecated, value.deprecationReason = deprecation(directives)
+		t.enumValues[name] = value
+	}
+}
+
+// deprecation returns whether directives deprecate an element, and why.
+func deprecation(directives []*directiveNode) (bool, string) {
+	for _, directive := range directives {
+		if directive.name != "deprecated" {
+			continue
+		}
+		for _, argument := range directive.arguments {
+			if argument.name == "reason" && argument.value.kind == valueString {
+				return true, argument.value.text
+			}
+		}
+		return true, "No longer supported"
+	}
+	return false, ""
+}
+
+// introspectionTypeRef is a type reference in an introspection result.
+type introspectionTypeRef struct {
+	Kind   string                `json:"kind"`
+	Name   string                `json:"name"`
+	OfType *introspectionTypeRef `json:"ofType"`
+}
+
+func (r *introspectionTypeRef) typeRef() (*typeRef, error) {
+	if r == nil {
+		return nil, errors.New("graphql: incomplete type reference in introspection result")
+	}
+	switch r.Kind {
+	case "NON_NULL":
+		t, err := r.OfType.typeRef()
+		if err != nil {
+			return nil, err
+		}
+		t.nonNull = true
+		return t, nil
+	case "LIST":
+		elem, err := r.OfType.typeRef()
+		if err != nil {
+			return nil, err
+		}
+		return &typeRef{elem: elem}, nil
+	}
+	return &typeRef{name: r.Name}, nil
+}
+
+type introspectionInputValue struct {
+	Name              string                `json:"name"`
+	Type              *introspectionTypeRef `json:"type"`
+	DefaultValue      *string               `json:"defaultValue"`
+	IsDeprecated      bool                  `json:"isDeprecated"`
+	DeprecationReason *string               `json:"deprecationReason"`
+}
+
+func inputValuesFromIntrospection(values []introspectionInputValue) (map[string]*schemaInputValue, error) {
+	result := make(map[string]*schemaInputValue, len(values))
+	for _, value := range values {
+		typ, err := value.Type.typeRef()
+		if err != nil {
+			return nil, err
+		}
+		result[value.Name] = &schemaInputValue{
+			name:       value.Name,
+			typ:        typ,
+			hasDefault: value.DefaultValue != nil,
+			deprecated: value.IsDeprecated,
+		}
+		if value.DeprecationReason != nil {
+			result[value.Name].deprecationReason = *value.DeprecationReason
+		}
+	}
+	return result, nil
+}
+
+// SchemaFromIntrospection builds a schema from the result of the standard
+// introspection query, with or without its data envelope.
+func SchemaFromIntrospection(result []byte) (*Schema, error) {
+	type named struct {
+		Name string `json:"name"`
+	}
+	var payload struct {
+		Data *struct {
+			Schema json.RawMessage `json:"__schema"`
+		} `json:"data"`
+		Schema json.RawMessage `json:"__schema"`
+	}
+	if err := json.Unmarshal(result, &payload); err != nil {
+		return nil, errors.Wrap(err, "decode introspection result")
+	}
+	raw := payload.Schema
+	if payload.Data != nil {
+		raw = payload.Data.Schema
+	}
+	if raw == nil {
+		return nil, errors.New("graphql: introspection result has no __schema")
+	}
+	var introspection struct {
+		QueryType        *named `json:"queryType"`
+		MutationType     *named `json:"mutationType"`
+		SubscriptionType *named `json:"subscriptionType"`
+		Types            []struct {
+			Kind   string `json:"kind"`
+			Name   string `json:"name"`
+			Fields []struct {
+				Name              string                    `json:"name"`
+				Args              []introspectionInputValue `json:"args"`
+				Type              *introspectionTypeRef     `json:"type"`
+				IsDeprecated      bool                      `json:"isDeprecated"`
+				DeprecationReason *string                   `json:"deprecationReason"`
+			} `json:"fields"`
+			InputFields []introspectionInputValue `json:"inputFields"`
+			Interfaces  []named                   `json:"interfaces"`
+			EnumValues  []struct {
+				Name              string  `json:"name"`
+				IsDeprecated      bool    `json:"isDeprecated"`
+				DeprecationReason *string `json:"deprecationReason"`
+			} `json:"enumValues"`
+			PossibleTypes []named `json:"possibleTypes"`
+			IsOneOf       bool    `json:"isOneOf"`
+		} `json:"types"`
+		Directives []struct {
+			Name string                    `json:"name"`
+			Args []introspectionInputValue `json:"args"`
+		} `json:"directives"`
+	}
+	if err := json.Unmarshal(raw, &introspection); err != nil {
+		return nil, errors.Wrap(err, "decode introspection result")
+	}
+	s := newSchema()
+	for _, root := range []struct {
+		t    *named
+		name *string
+	}{
+		{introspection.QueryType, &s.queryType},
+		{introspection.MutationType, &s.mutationType},
+		{introspection.SubscriptionType, &s.subscriptionType},
+	} {
+		if root.t != nil {
+			*root.name = root.t.Name
+		}
+	}
+	for _, it := range introspection.Types {
+		t := &schemaType{kind: typeKind(it.Kind), name: it.Name, oneOf: it.IsOneOf}
+		if it.Fields != nil {
+			t.fields = make(map[string]*schemaField, len(it.Fields))
+		}
+		for _, f := range it.Fields {
+			field := &schemaField{name: f.Name, deprecated: f.IsDeprecated}
+			if f.DeprecationReason != nil {
+				field.deprecationReason = *f.DeprecationReason
+			}
+			var err error
+			if field.typ, err = f.Type.typeRef(); err != nil {
+				return nil, err
+			}
+			if field.args, err = inputValuesFromIntrospection(f.Args); err != nil {
+				return nil, err
+			}
+			t.fields[f.Name] = field
+		}
+		if it.InputFields != nil {
+			var err error
+			if t.inputFields, err = inputValuesFromIntrospection(it.InputFields); err != nil {
+				return nil, err
+			}
+		}
+		for _, iface := range it.Interfaces {
+			t.interfaces = append(t.interfaces, iface.Name)
+		}
+		if it.EnumValues != nil {
+			t.enumValues = make(map[string]*schemaEnumValue, len(it.EnumValues))
+		}
+		for _, v := range it.EnumValues {
+			value := &schemaEnumValue{name: v.Name, deprecated: v.IsDeprecated}
+			if v.DeprecationReason != nil {
+				value.deprecationReason = *v.DeprecationReason
+			}
+			t.enumValues[v.Name] = value
+		}
+		if t.kind == kindUnion {
+			t.possibleTypes = make(map[string]bool, len(it.PossibleTypes))
+			for _, possible := range it.PossibleTypes {
+				t.possibleTypes[possible.Name] = true
+			}
+		}
+		s.types[it.Name] = t
+	}
+	for _, d := range introspection.Directives {
+		args, err := inputValuesFromIntrospection(d.Args)
+		if err != nil {
+			return nil, err
+		}
+		s.directives[d.Name] = &schemaDirective{name: d.Name, args: args}
+	}
+	if err := s.finish(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// TypeNames returns the names of the types of the schema, sorted.
+func (s *Schema) TypeNames() []string {
+	names := make([]string, 0, len(s.types))
+	for name := range s.types {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,40 @@
+package graphql
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// RequestSigner is called with every outgoing request, after all headers
+// have been set, and the exact body that will be sent. It can add
+// signature headers to r.
+type RequestSigner func(r *http.Request, body []byte) error
+
+// WithRequestSigner signs every request with signer. Multipart bodies are
+// buffered in memory so they can be signed.
+func WithRequestSigner(signer RequestSigner) ClientOption {
+	return func(client *Client) {
+		client.signer = signer
+	}
+}
+
+// HMACSigner returns a RequestSigner that sets timestampHeader to the
+// current Unix time and signatureHeader to the hex encoded HMAC-SHA256,
+// keyed with secret, of the timestamp, a dot and the body.
+//
+//	NewClient(url, WithRequestSigner(HMACSigner(secret, "X-Signature", "X-Timestamp")))
+func HMACSigner(secret []byte, signatureHeader, timestampHeader string) RequestSigner {
+	return func(r *http.Request, body []byte) error {
+		timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+		mac := hmac.New(sha256.New, secret)
+		mac.Write([]byte(timestamp + "."))
+		mac.Write(body)
+		r.Header.Set(timestampHeader, timestamp)
+		r.Header.Set(signatureHeader, hex.EncodeToString(mac.Sum(nil)))
+		return nil
+	}
+}
@@ -0,0 +1,119 @@
+package graphql
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"sort"
+	"sync"
+	"sync/atomic"
+
+	"github.com/pkg/errors"
+)
+
+// WithSingleFlight coalesces identical queries run concurrently into a
+// single request, whose response is shared by all of them, e.g. to spare
+// the server a burst of identical queries when a cached response expires.
+// Queries are identical when their bodies and the headers set on their
+// requests are. Mutations, subscriptions, uploads, streamed responses,
+// revalidations of cached responses and requests authenticated by an
+// AuthProvider, whose credentials may depend on the context, are always
+// sent.
+func WithSingleFlight() ClientOption {
+	return func(client *Client) {
+		client.flights = &flightGroup{calls: make(map[string]*flight)}
+	}
+}
+
+// flightGroup holds the requests in flight of a client.
+type flightGroup struct {
+	mu    sync.Mutex
+	calls map[string]*flight
+}
+
+// flight is a request in flight and, once done is closed, its outcome.
+type flight struct {
+	done chan struct{}
+	res  *http.Response
+	body []byte
+	err  error
+	// the measurements of the request, reported by every operation sharing
+	// it
+	attempts      int
+	statusCode    int
+	requestBytes  int64
+	responseBytes int
+}
+
+// doShared sends requestBody like do, or waits for the response to an
+// identical request in flight when single flight is enabled.
+func (c *Client) doShared(ctx context.Context, op *operation, contentType string, requestBody []byte) (*http.Response, *bytes.Buffer, error) {
+	body := func() (io.Reader, error) {
+		return bytes.NewReader(requestBody), nil
+	}
+	if c.flights == nil || op.hasSideEffects() || op.stream != nil || op.revalidate != nil || c.authProvider(op.req) != nil {
+		return c.do(ctx, op, contentType, body, true)
+	}
+	key := flightKey(op.req, requestBody)
+	c.flights.mu.Lock()
+	if call, ok := c.flights.calls[key]; ok {
+		c.flights.mu.Unlock()
+		select {
+		case <-ctx.Done():
+			return nil, nil, ctx.Err()
+		case <-call.done:
+		}
+		if call.err != nil && ctx.Err() == nil && (errors.Is(call.err, context.Canceled) || errors.Is(call.err, context.DeadlineExceeded)) {
+			// the context of the request in flight ended, not this one
+			return c.do(ctx, op, contentType, body, true)
+		}
+		op.attempts, op.statusCode, op.responseBytes = call.attempts, call.statusCode, call.responseBytes
+		atomic.StoreInt64(&op.requestBytes, call.requestBytes)
+		if call.err != nil {
+			return nil, nil, call.err
+		}
+		c.logf(op, LogLevelDebug, LogWire, "<< shared response")
+		res := *call.res
+		res.Header = call.res.Header.Clone()
+		return &res, bytes.NewBuffer(append([]byte(nil), call.body...)), nil
+	}
+	call := &flight{done: make(chan struct{})}
+	c.flights.calls[key] = call
+	c.flights.mu.Unlock()
+
+	res, buf, err := c.do(ctx, op, contentType, body, true)
+	call.res, call.err = res, err
+	call.attempts, call.statusCode, call.responseBytes = op.attempts, op.statusCode, op.responseBytes
+	call.requestBytes = atomic.LoadInt64(&op.requestBytes)
+	if buf != nil {
+		call.body = append([]byte(nil), buf.Bytes()...)
+	}
+	c.flights.mu.Lock()
+	delete(c.flights.calls, key)
+	c.flights.mu.Unlock()
+	close(call.done)
+	return res, buf, err
+}
+
+// flightKey identifies requests sending body with the headers of req.
+func flightKey(req *GraphRequest, body []byte) string {
+	h := sha256.New()
+	h.Write(body)
+	names := make([]string, 0, len(req.Header))
+	for name := range req.Header {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		h.Write([]byte{0})
+		h.Write([]byte(http.CanonicalHeaderKey(name)))
+		for _, value := range req.Header[name] {
+			h.Write([]byte{0})
+			h.Write([]byte(value))
+		}
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
@@ -0,0 +1,94 @@
+package graphql
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestSingleFlightSendsRevalidationsApart(t *testing.T) {
+	var calls int32
+	revalidating := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := atomic.AddInt32(&calls, 1)
+		w.Header().Set("ETag", `"v1"`)
+		if r.Header.Get("If-None-Match") == `"v1"` {
+			close(revalidating)
+			// held so an identical request can join it while in flight
+			time.Sleep(100 * time.Millisecond)
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		fmt.Fprintf(w, `{"data":{"viewer":"v%d"}}`, n)
+	}))
+	defer srv.Close()
+	client := NewClient(srv.URL, WithSingleFlight(), WithResponseCache(CacheConfig{TTL: time.Millisecond, Revalidate: time.Minute}))
+	if _, err := client.Run(context.Background(), NewGraphqlRequest("{ viewer }"), &viewerData{}); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(5 * time.Millisecond)
+
+	leader := make(chan error, 1)
+	go func() {
+		_, err := client.Run(context.Background(), NewGraphqlRequest("{ viewer }"), &viewerData{})
+		leader <- err
+	}()
+	<-revalidating
+	req := NewGraphqlRequest("{ viewer }")
+	req.SetCacheControl(CacheControl{NoCache: true})
+	var data viewerData
+	if _, err := client.Run(context.Background(), req, &data); err != nil {
+		t.Fatalf("request without the cached response: %v", err)
+	}
+	if data.Viewer == "" {
+		t.Fatal("request without the cached response got no data")
+	}
+	if err := <-leader; err != nil {
+		t.Fatalf("revalidation: %v", err)
+	}
+	if calls != 3 {
+		t.Fatalf("server got %d requests, want 3", calls)
+	}
+}
+
+func TestSingleFlightReportsSharedRequests(t *testing.T) {
+	var calls int32
+	arrived, release := make(chan struct{}), make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&calls, 1) == 1 {
+			close(arrived)
+		}
+		<-release
+		fmt.Fprint(w, `{"data":{"viewer":"alice"}}`)
+	}))
+	defer srv.Close()
+	entries := make(chan LogEntry, 3)
+	client := NewClient(srv.URL, WithSingleFlight(), WithLogger(LoggerFunc(func(ctx context.Context, entry LogEntry) {
+		entries <- entry
+	})))
+	run := func() {
+		if _, err := client.Run(context.Background(), NewGraphqlRequest("{ viewer }"), &viewerData{}); err != nil {
+			t.Error(err)
+		}
+	}
+	go run()
+	<-arrived
+	go run()
+	go run()
+	// lets the followers join the request in flight
+	time.Sleep(50 * time.Millisecond)
+	close(release)
+	for i := 0; i < 3; i++ {
+		entry := <-entries
+		if entry.Attempts != 1 || entry.StatusCode != http.StatusOK || entry.RequestBytes == 0 || entry.ResponseBytes == 0 {
+			t.Errorf("entry %d: got %d attempts, status %d, %d bytes sent and %d received", i, entry.Attempts, entry.StatusCode, entry.RequestBytes, entry.ResponseBytes)
+		}
+	}
+	if calls != 1 {
+		t.Fatalf("server got %d requests, want 1", calls)
+	}
+}
@@ -0,0 +1,149 @@
+package graphql
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// latencySamples is the number of recent operations the latency
+// percentiles are computed from.
+const latencySamples = 1024
+
+// Stats is a snapshot of the activity of a Client.
+type Stats struct {
+	// Requests is the number of operations run.
+	Requests int64
+	// Failures is the number of operations that returned an error.
+	Failures int64
+	// Retries is the number of HTTP requests sent again after a
+	// transient failure.
+	Retries int64
+	// InFlight is the number of operations currently running.
+	InFlight int64
+	// LatencyP50 and LatencyP95 are computed over the most recent
+	// operations.
+	LatencyP50 time.Duration
+	LatencyP95 time.Duration
+	// Cache counts the lookups of the response cache set with
+	// WithResponseCache, and CacheByOperation breaks them down by
+	// operation name when CacheConfig.OperationStats is set.
+	Cache            CacheStats
+	CacheByOperation map[string]CacheStats
+}
+
+// CacheStats counts the lookups of a response cache.
+type CacheStats struct {
+	Hits   int64
+	Misses int64
+	// Evictions is the number of responses evicted to make room, when
+	// the backend is an EvictionCounter.
+	Evictions int64
+}
+
+// HitRate returns the fraction of the lookups answered from cache.
+func (s CacheStats) HitRate() float64 {
+	if s.Hits+s.Misses == 0 {
+		return 0
+	}
+	return float64(s.Hits) / float64(s.Hits+s.Misses)
+}
+
+// Stats returns a snapshot of the activity of the client, e.g. for health
+// endpoints.
+func (c *Client) Stats() Stats {
+	stats := c.stats.snapshot()
+	if c.cache != nil {
+		if counter, ok := c.cache.Backend.(EvictionCounter); ok {
+			stats.Cache.Evictions = counter.Evictions()
+		}
+	}
+	return stats
+}
+
+type clientStats struct {
+	mu               sync.Mutex
+	stats            Stats
+	latencies        []time.Duration
+	next             int
+	cacheByOperation map[string]*CacheStats
+}
+
+func (s *clientStats) begin() {
+	s.mu.Lock()
+	s.stats.InFlight++
+	s.mu.Unlock()
+}
+
+func (s *clientStats) end(op *operation, err error) {
+	latency := time.Since(op.start)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.stats.InFlight--
+	s.stats.Requests++
+	if err != nil {
+		s.stats.Failures++
+	}
+	if op.attempts > 1 {
+		s.stats.Retries += int64(op.attempts - 1)
+	}
+	if len(s.latencies) < latencySamples {
+		s.latencies = append(s.latencies, latency)
+		return
+	}
+	s.latencies[s.next] = latency
+	s.next = (s.next + 1) % latencySamples
+}
+
+// cacheLookup counts a lookup of the response cache by the operation
+// named name, broken down by operation if byOperation is set.
+func (s *clientStats) cacheLookup(name string, hit, byOperation bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	counts := []*CacheStats{&s.stats.Cache}
+	if byOperation {
+		if s.cacheByOperation == nil {
+			s.cacheByOperation = make(map[string]*CacheStats)
+		}
+		if s.cacheByOperation[name] == nil {
+			s.cacheByOperation[name] = &CacheStats{}
+		}
+		counts = append(counts, s.cacheByOperation[name])
+	}
+	for _, count := range counts {
+		if hit {
+			count.Hits++
+		} else {
+			count.Misses++
+		}
+	}
+}
+
+func (s *clientStats) snapshot() Stats {
+	s.mu.Lock()
+	stats := s.stats
+	latencies := append([]time.Duration(nil), s.latencies...)
+	if s.cacheByOperation != nil {
+		stats.CacheByOperation = make(map[string]CacheStats, len(s.cacheByOperation))
+		for name, count := range s.cacheByOperation {
+			stats.CacheByOperation[name] = *count
+		}
+	}
+	s.mu.Unlock()
+	if len(latencies) == 0 {
+		return stats
+	}
+	sort.Slice(latencies, func(i, j int) bool { return latencies[i] < latencies[j] })
+	stats.LatencyP50 = percentile(latencies, 50)
+	stats.LatencyP95 = percentile(latencies, 95)
+	return stats
+}
+
+// percentile returns the p-th percentile of sorted, using the nearest rank.
+func percentile(sorted []time.Duration, p int) time.Duration {
+	rank := (p*len(sorted) + 99) / 100
+	if rank < 1 {
+		rank = 1
+	}
+	return sorted[rank-1]
+}
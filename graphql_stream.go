@@ -0,0 +1,285 @@
+package graphql
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"github.com/pkg/errors"
+)
+
+// RunRaw runs req and streams the body of a 200 OK response into w as it
+// is read, without holding it in memory, e.g. for export-style queries
+// returning hundreds of megabytes. The errors and extensions of the
+// response are still decoded into the returned GraphResponse. Attempts are
+// not retried nor hedged once writing to w started, and
+// WithMaxResponseBytes does not apply.
+func (c *Client) RunRaw(ctx context.Context, req *GraphRequest, w io.Writer) (*GraphResponse, error) {
+	return c.runStream(ctx, req, &responseStream{w: w})
+}
+
+// RunRawData is like RunRaw, but only writes the data member of the
+// response into w.
+func (c *Client) RunRawData(ctx context.Context, req *GraphRequest, w io.Writer) (*GraphResponse, error) {
+	return c.runStream(ctx, req, &responseStream{w: w, dataOnly: true})
+}
+
+func (c *Client) runStream(ctx context.Context, req *GraphRequest, stream *responseStream) (*GraphResponse, error) {
+	graphResponse, err := c.execute(ctx, req, nil, stream)
+	if graphResponse != nil {
+		graphResponse.NoData = !stream.hasData
+	}
+	return graphResponse, err
+}
+
+// responseStream copies the body of a response into w while it is read.
+type responseStream struct {
+	w        io.Writer
+	dataOnly bool
+	// written is set once anything was written to w.
+	written bool
+	// hasData is set when the data of the response is not null.
+	hasData bool
+}
+
+// copy copies body into the writer of the stream, and returns the members
+// of the response other than data as a JSON object, for them to be
+// decoded like a buffered response.
+func (s *responseStream) copy(body io.Reader) (*bytes.Buffer, error) {
+	out := bufio.NewWriter(&streamWriter{stream: s})
+	data := io.Writer(out)
+	if !s.dataOnly {
+		body = io.TeeReader(body, out)
+		data = io.Discard
+	}
+	r := bufio.NewReader(body)
+	envelope := &bytes.Buffer{}
+	if err := s.copyMembers(r, data, envelope); err != nil {
+		return nil, err
+	}
+	// read what follows the object for all of the body to be written
+	if _, err := io.Copy(io.Discard, r); err != nil {
+		return nil, err
+	}
+	if err := out.Flush(); err != nil {
+		return nil, errors.Wrap(err, "writing body")
+	}
+	return envelope, nil
+}
+
+// copyMembers reads the response object from r, copying its data into data
+// and its other members into envelope.
+func (s *responseStream) copyMembers(r *bufio.Reader, data io.Writer, envelope *bytes.Buffer) error {
+	if err := expectByte(r, '{'); err != nil {
+		return err
+	}
+	envelope.WriteByte('{')
+	for first := true; ; first = false {
+		b, err := nextByte(r)
+		if err != nil {
+			return err
+		}
+		if b == '}' {
+			envelope.WriteByte('}')
+			return nil
+		}
+		if !first {
+			if b != ',' {
+				return fmt.Errorf("graphql: invalid response: unexpected %q", b)
+			}
+			if b, err = nextByte(r); err != nil {
+				return err
+			}
+		}
+		if b != '"' {
+			return fmt.Errorf("graphql: invalid response: unexpected %q", b)
+		}
+		var rawKey bytes.Buffer
+		rawKey.WriteByte('"')
+		if err := copyString(r, &rawKey); err != nil {
+			return err
+		}
+		var key string
+		if err := json.Unmarshal(rawKey.Bytes(), &key); err != nil {
+			return errors.Wrap(err, "invalid response")
+		}
+		if err := expectByte(r, ':'); err != nil {
+			return err
+		}
+		if key == "data" {
+			b, err := nextByte(r)
+			if err != nil {
+				return err
+			}
+			s.hasData = b != 'n'
+			if err := r.UnreadByte(); err != nil {
+				return err
+			}
+			if err := copyValue(r, data); err != nil {
+				return err
+			}
+			continue
+		}
+		if envelope.Len() > 1 {
+			envelope.WriteByte(',')
+		}
+		envelope.Write(rawKey.Bytes())
+		envelope.WriteByte(':')
+		if err := copyValue(r, envelope); err != nil {
+			return err
+		}
+	}
+}
+
+// streamWriter records that the stream started writing.
+type streamWriter struct {
+	stream *responseStream
+}
+
+func (w *streamWriter) Write(p []byte) (int, error) {
+	if len(p) > 0 {
+		w.stream.written = true
+	}
+	return w.stream.w.Write(p)
+}
+
+// nextByte returns the next byte of r that isn't whitespace.
+func nextByte(r *bufio.Reader) (byte, error) {
+	for {
+		b, err := r.ReadByte()
+		if err == io.EOF {
+			return 0, io.ErrUnexpectedEOF
+		}
+		if err != nil {
+			return 0, err
+		}
+		switch b {
+		case ' ', '\t', '\n', '\r':
+			continue
+		}
+		return b, nil
+	}
+}
+
+func expectByte(r *bufio.Reader, expected byte) error {
+	b, err := nextByte(r)
+	if err != nil {
+		return err
+	}
+	if b != expected {
+		return fmt.Errorf("graphql: invalid response: expected %q, got %q", expected, b)
+	}
+	return nil
+}
+
+type byteWriter interface {
+	io.Writer
+	WriteByte(c byte) error
+}
+
+// copyString copies the rest of a string, after its opening quote.
+func copyString(r *bufio.Reader, w io.Writer) error {
+	for escaped := false; ; {
+		chunk, err := r.ReadSlice('"')
+		if err == bufio.ErrBufferFull {
+			if _, err := w.Write(chunk); err != nil {
+				return err
+			}
+			escaped = endsEscaped(chunk, escaped)
+			continue
+		}
+		if err == io.EOF {
+			return io.ErrUnexpectedEOF
+		}
+		if err != nil {
+			return err
+		}
+		if _, err := w.Write(chunk); err != nil {
+			return err
+		}
+		// the quote ends the string unless an odd number of backslashes
+		// precede it
+		if !endsEscaped(chunk[:len(chunk)-1], escaped) {
+			return nil
+		}
+		escaped = false
+	}
+}
+
+// endsEscaped reports whether the byte following chunk is escaped, given
+// whether the first byte of chunk is.
+func endsEscaped(chunk []byte, escaped bool) bool {
+	backslashes := 0
+	for i := len(chunk) - 1; i >= 0 && chunk[i] == '\\'; i-- {
+		backslashes++
+	}
+	if backslashes == len(chunk) && escaped {
+		backslashes++
+	}
+	return backslashes%2 == 1
+}
+
+// copyValue copies a JSON value from r to w.
+func copyValue(r *bufio.Reader, w io.Writer) error {
+	b, err := nextByte(r)
+	if err != nil {
+		return err
+	}
+	bw, ok := w.(byteWriter)
+	if !ok {
+		buffered := bufio.NewWriter(w)
+		defer buffered.Flush()
+		bw = buffered
+	}
+	if err := bw.WriteByte(b); err != nil {
+		return err
+	}
+	switch b {
+	case '"':
+		return copyString(r, bw)
+	case '{', '[':
+		for depth := 1; depth > 0; {
+			b, err := r.ReadByte()
+			if err == io.EOF {
+				return io.ErrUnexpectedEOF
+			}
+			if err != nil {
+				return err
+			}
+			if err := bw.WriteByte(b); err != nil {
+				return err
+			}
+			switch b {
+			case '"':
+				if err := copyString(r, bw); err != nil {
+					return err
+				}
+			case '{', '[':
+				depth++
+			case '}', ']':
+				depth--
+			}
+		}
+		return nil
+	}
+	// a number, true, false or null
+	for {
+		b, err := r.ReadByte()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		switch b {
+		case ',', '}', ']', ' ', '\t', '\n', '\r':
+			return r.UnreadByte()
+		}
+		if err := bw.WriteByte(b); err != nil {
+			return err
+		}
+	}
+}
@@ -0,0 +1,182 @@
+package graphql
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+type rawResponse struct {
+	status int
+	body   string
+}
+
+// rawServer answers the requests it receives with responses in turn,
+// repeating the last one, and counts them in calls.
+func rawServer(t *testing.T, calls *int32, responses ...rawResponse) *httptest.Server {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := int(atomic.AddInt32(calls, 1))
+		if n > len(responses) {
+			n = len(responses)
+		}
+		w.WriteHeader(responses[n-1].status)
+		w.Write([]byte(responses[n-1].body))
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestRunRaw(t *testing.T) {
+	longString := strings.Repeat(`\\`, 3000) + `\"` + strings.Repeat("a", 5000)
+	tests := []struct {
+		name      string
+		dataOnly  bool
+		responses []rawResponse
+		retry     bool
+		want      string
+		// wantErr is a part of the expected error, empty for none.
+		wantErr    string
+		wantErrors int
+		wantNoData bool
+		wantCalls  int32
+	}{
+		{
+			name:       "body",
+			responses:  []rawResponse{{200, `{"data":{"a":"x"},"errors":[{"message":"partial"}]}`}},
+			want:       `{"data":{"a":"x"},"errors":[{"message":"partial"}]}`,
+			wantErrors: 1,
+			wantCalls:  1,
+		},
+		{
+			name:      "data",
+			dataOnly:  true,
+			responses: []rawResponse{{200, `{ "extensions" : {"cost":1}, "data" : {"s":"q\"}","list":[1, 2,{"b":null}]} }`}},
+			want:      `{"s":"q\"}","list":[1, 2,{"b":null}]}`,
+			wantCalls: 1,
+		},
+		{
+			name:      "long escaped string",
+			dataOnly:  true,
+			responses: []rawResponse{{200, `{"data":{"s":"` + longString + `"}}`}},
+			want:      `{"s":"` + longString + `"}`,
+			wantCalls: 1,
+		},
+		{
+			name:       "null data",
+			dataOnly:   true,
+			responses:  []rawResponse{{200, `{"errors":[{"message":"denied"}],"data":null}`}},
+			want:       `null`,
+			wantErrors: 1,
+			wantNoData: true,
+			wantCalls:  1,
+		},
+		{
+			name:       "missing data",
+			dataOnly:   true,
+			responses:  []rawResponse{{200, `{"errors":[{"message":"invalid"}]}`}},
+			wantErrors: 1,
+			wantNoData: true,
+			wantCalls:  1,
+		},
+		{
+			name:      "truncated body",
+			dataOnly:  true,
+			responses: []rawResponse{{200, `{"data":{"a":[1,2`}},
+			wantErr:   "streaming body: unexpected EOF",
+			wantCalls: 1,
+		},
+		{
+			name:      "not an object",
+			responses: []rawResponse{{200, `[]`}},
+			wantErr:   `expected '{', got '['`,
+			wantCalls: 1,
+		},
+		{
+			name:      "error status",
+			responses: []rawResponse{{500, `{"data":{"a":1}}`}},
+			wantErr:   "500",
+			wantCalls: 1,
+		},
+		{
+			name:      "retried before writing",
+			dataOnly:  true,
+			retry:     true,
+			responses: []rawResponse{{503, `unavailable`}, {200, `{"data":{"a":1}}`}},
+			want:      `{"a":1}`,
+			wantCalls: 2,
+		},
+		{
+			name:      "retried when truncated before writing",
+			dataOnly:  true,
+			retry:     true,
+			responses: []rawResponse{{200, `{"data":{"a":[1,2`}, {200, `{"data":{"a":[1,2]}}`}},
+			want:      `{"a":[1,2]}`,
+			wantCalls: 2,
+		},
+		{
+			name:      "not retried after writing",
+			dataOnly:  true,
+			retry:     true,
+			responses: []rawResponse{{200, `{"data":{"s":"` + strings.Repeat("a", 10000)}, {200, `{"data":{"a":1}}`}},
+			wantErr:   "unexpected EOF",
+			wantCalls: 1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var calls int32
+			srv := rawServer(t, &calls, tt.responses...)
+			var opts []ClientOption
+			if tt.retry {
+				opts = append(opts, WithRetry(RetryPolicy{MaxAttempts: 3, InitialBackoff: time.Millisecond}))
+			}
+			client := NewClient(srv.URL, opts...)
+			run := client.RunRaw
+			if tt.dataOnly {
+				run = client.RunRawData
+			}
+			var w bytes.Buffer
+			graphResponse, err := run(context.Background(), NewGraphqlRequest("{ a }"), &w)
+			if got := atomic.LoadInt32(&calls); got != tt.wantCalls {
+				t.Fatalf("sent %d requests, want %d", got, tt.wantCalls)
+			}
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("got error %v, want one containing %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if w.String() != tt.want {
+				t.Fatalf("wrote %q, want %q", w.String(), tt.want)
+			}
+			if len(graphResponse.Errors) != tt.wantErrors || graphResponse.NoData != tt.wantNoData {
+				t.Fatalf("got errors %v and NoData %t", graphResponse.Errors, graphResponse.NoData)
+			}
+		})
+	}
+}
+
+func TestRunRawDecodesExtensions(t *testing.T) {
+	var calls int32
+	srv := rawServer(t, &calls, rawResponse{200, `{"data":{"a":1},"extensions":{"cost":{"requested":3}}}`})
+	var w bytes.Buffer
+	graphResponse, err := NewClient(srv.URL).RunRawData(context.Background(), NewGraphqlRequest("{ a }"), &w)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cost, _ := graphResponse.Extensions["cost"].(map[string]interface{})
+	if cost["requested"] != float64(3) || string(graphResponse.RawExtensions) != `{"cost":{"requested":3}}` {
+		t.Fatalf("got extensions %v", graphResponse.Extensions)
+	}
+	if graphResponse.RawData != nil {
+		t.Fatalf("kept the streamed data %s", graphResponse.RawData)
+	}
+}
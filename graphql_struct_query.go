@@ -0,0 +1,318 @@
+package graphql
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/pkg/errors"
+)
+
+// Query runs a query generated from q, a pointer to a struct describing
+// the selections, and decodes the response data into q.
+//
+// Fields are selected by the lower camel case form of their names, or by
+// their graphql tag, which is written into the query as is and may hold
+// arguments, an alias or an inline fragment:
+//
+//	var q struct {
+//		User struct {
+//			Name      string
+//			Thumbnail string `graphql:"thumbnail: avatar(size: 64)"`
+//			Admin     struct {
+//				Level int
+//			} `graphql:"... on Admin"`
+//		} `graphql:"user(id: $id)"`
+//	}
+//	_, err := client.Query(ctx, &q, map[string]interface{}{"id": ID("1")})
+//
+// The types of the variables are derived from their Go types, see
+// GraphQLTyper.
+func (c *Client) Query(ctx context.Context, q interface{}, variables map[string]interface{}) (*GraphResponse, error) {
+	return c.runStruct(ctx, "query", q, variables)
+}
+
+// Mutate runs a mutation generated from m like Query does.
+func (c *Client) Mutate(ctx context.Context, m interface{}, variables map[string]interface{}) (*GraphResponse, error) {
+	return c.runStruct(ctx, "mutation", m, variables)
+}
+
+func (c *Client) runStruct(ctx context.Context, operationType string, v interface{}, variables map[string]interface{}) (*GraphResponse, error) {
+	query, err := structDocument(operationType, v, variables)
+	if err != nil {
+		return nil, err
+	}
+	req := NewGraphqlRequest(query)
+	for name, value := range variables {
+		req.Var(name, value)
+	}
+	graphResponse, err := c.Run(ctx, req, &structData{v: v})
+	if graphResponse != nil {
+		graphResponse.Data = v
+	}
+	return graphResponse, err
+}
+
+// QueryFromStruct returns the query Client.Query sends for q.
+func QueryFromStruct(q interface{}, variables map[string]interface{}) (string, error) {
+	return structDocument("query", q, variables)
+}
+
+// MutationFromStruct returns the mutation Client.Mutate sends for m.
+func MutationFromStruct(m interface{}, variables map[string]interface{}) (string, error) {
+	return structDocument("mutation", m, variables)
+}
+
+// GraphQLTyper is implemented by variable types that know their GraphQL
+// type. It is called on the zero value of the type. Other types are mapped
+// to String, Int, Float, Boolean, lists and, for structs, the name of the
+// Go type. Pointers are nullable, everything else is non-null.
+type GraphQLTyper interface {
+	GraphQLType() string
+}
+
+// ID is a variable of the GraphQL ID type.
+type ID string
+
+// GraphQLType returns "ID!".
+func (ID) GraphQLType() string {
+	return "ID!"
+}
+
+func structDocument(operationType string, v interface{}, variables map[string]interface{}) (string, error) {
+	t := reflect.TypeOf(v)
+	if t == nil || t.Kind() != reflect.Ptr || t.Elem().Kind() != reflect.Struct {
+		return "", fmt.Errorf("graphql: %T is not a pointer to a struct", v)
+	}
+	var b strings.Builder
+	b.WriteString(operationType)
+	if len(variables) > 0 {
+		names := make([]string, 0, len(variables))
+		for name := range variables {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		b.WriteString("(")
+		for i, name := range names {
+			if variables[name] == nil {
+				return "", fmt.Errorf("graphql: can't infer the type of variable %q from nil", name)
+			}
+			typ, err := graphQLType(reflect.TypeOf(variables[name]))
+			if err != nil {
+				return "", errors.Wrapf(err, "variable %q", name)
+			}
+			if i > 0 {
+				b.WriteString(", ")
+			}
+			b.WriteString("$" + name + ": " + typ)
+		}
+		b.WriteString(")")
+	}
+	writeStructSelections(&b, t.Elem())
+	return b.String(), nil
+}
+
+var graphQLTyperType = reflect.TypeOf((*GraphQLTyper)(nil)).Elem()
+
+func graphQLType(t reflect.Type) (string, error) {
+	if t.Kind() == reflect.Ptr {
+		typ, err := graphQLType(t.Elem())
+		return strings.TrimSuffix(typ, "!"), err
+	}
+	if t.Implements(graphQLTyperType) {
+		return reflect.Zero(t).Interface().(GraphQLTyper).GraphQLType(), nil
+	}
+	switch t.Kind() {
+	case reflect.String:
+		return "String!", nil
+	case reflect.Bool:
+		return "Boolean!", nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return "Int!", nil
+	case reflect.Float32, reflect.Float64:
+		return "Float!", nil
+	case reflect.Slice, reflect.Array:
+		elem, err := graphQLType(t.Elem())
+		if err != nil {
+			return "", err
+		}
+		return "[" + elem + "]!", nil
+	case reflect.Struct:
+		if t.Name() != "" {
+			return t.Name() + "!", nil
+		}
+	}
+	return "", fmt.Errorf("graphql: can't infer the GraphQL type of %s, implement GraphQLTyper", t)
+}
+
+// writeStructSelections writes the selection set of t, if it is an object.
+func writeStructSelections(b *strings.Builder, t reflect.Type) {
+	t = selectionType(t)
+	if isLeafType(t) {
+		return
+	}
+	b.WriteString(" {")
+	writeStructFields(b, t)
+	b.WriteString(" }")
+}
+
+func writeStructFields(b *strings.Builder, t reflect.Type) {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		tag, tagged := field.Tag.Lookup("graphql")
+		if field.PkgPath != "" || tag == "-" {
+			continue
+		}
+		if field.Anonymous && !tagged && !isLeafType(selectionType(field.Type)) {
+			writeStructFields(b, selectionType(field.Type))
+			continue
+		}
+		if !tagged {
+			tag = lowerCamelCase(field.Name)
+		}
+		b.WriteString(" " + tag)
+		writeStructSelections(b, field.Type)
+	}
+}
+
+// selectionType returns the type selected by a field of type t, without
+// pointers and lists.
+func selectionType(t reflect.Type) reflect.Type {
+	for {
+		switch t.Kind() {
+		case reflect.Ptr, reflect.Slice, reflect.Array:
+			if t.Kind() == reflect.Slice && t.Elem().Kind() == reflect.Uint8 {
+				return t
+			}
+			t = t.Elem()
+		default:
+			return t
+		}
+	}
+}
+
+var jsonUnmarshalerType = reflect.TypeOf((*json.Unmarshaler)(nil)).Elem()
+
+// isLeafType reports whether t is decoded from a scalar, or an enum,
+// rather than from an object with selections.
+func isLeafType(t reflect.Type) bool {
+	return t.Kind() != reflect.Struct || reflect.PtrTo(t).Implements(jsonUnmarshalerType)
+}
+
+// responseKey returns the key of the field selected by tag in the
+// response.
+func responseKey(tag string) string {
+	if i := strings.IndexAny(tag, "(@{"); i >= 0 {
+		tag = tag[:i]
+	}
+	if i := strings.Index(tag, ":"); i >= 0 {
+		tag = tag[:i]
+	}
+	return strings.TrimSpace(tag)
+}
+
+// lowerCamelCase converts a Go field name to the usual GraphQL field name,
+// e.g. "ID" to "id" and "URLPath" to "urlPath".
+func lowerCamelCase(name string) string {
+	runes := []rune(name)
+	for i := range runes {
+		if !unicode.IsUpper(runes[i]) {
+			break
+		}
+		if i > 0 && i+1 < len(runes) && unicode.IsLower(runes[i+1]) {
+			break
+		}
+		runes[i] = unicode.ToLower(runes[i])
+	}
+	return string(runes)
+}
+
+// structData decodes response data into a struct following the
+// selections generated from it.
+type structData struct {
+	v interface{}
+}
+
+func (d *structData) UnmarshalJSON(data []byte) error {
+	return decodeSelection(data, reflect.ValueOf(d.v).Elem())
+}
+
+func decodeSelection(data []byte, v reflect.Value) error {
+	if isLeafType(selectionType(v.Type())) {
+		return json.Unmarshal(data, v.Addr().Interface())
+	}
+	if bytes.Equal(bytes.TrimSpace(data), []byte("null")) {
+		v.Set(reflect.Zero(v.Type()))
+		return nil
+	}
+	switch v.Kind() {
+	case reflect.Ptr:
+		if v.IsNil() {
+			v.Set(reflect.New(v.Type().Elem()))
+		}
+		return decodeSelection(data, v.Elem())
+	case reflect.Slice, reflect.Array:
+		var items []json.RawMessage
+		if err := json.Unmarshal(data, &items); err != nil {
+			return err
+		}
+		if v.Kind() == reflect.Slice {
+			v.Set(reflect.MakeSlice(v.Type(), len(items), len(items)))
+		}
+		for i := 0; i < len(items) && i < v.Len(); i++ {
+			if err := decodeSelection(items[i], v.Index(i)); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return err
+	}
+	return decodeStructFields(fields, v)
+}
+
+func decodeStructFields(fields map[string]json.RawMessage, v reflect.Value) error {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		tag, tagged := field.Tag.Lookup("graphql")
+		if field.PkgPath != "" || tag == "-" {
+			continue
+		}
+		inline := strings.HasPrefix(strings.TrimSpace(tag), "...") ||
+			field.Anonymous && !tagged && !isLeafType(selectionType(field.Type))
+		if inline {
+			target := v.Field(i)
+			if target.Kind() == reflect.Ptr {
+				if target.IsNil() {
+					target.Set(reflect.New(target.Type().Elem()))
+				}
+				target = target.Elem()
+			}
+			if err := decodeStructFields(fields, target); err != nil {
+				return err
+			}
+			continue
+		}
+		key := lowerCamelCase(field.Name)
+		if tagged {
+			key = responseKey(tag)
+		}
+		data, ok := fields[key]
+		if !ok {
+			continue
+		}
+		if err := decodeSelection(data, v.Field(i)); err != nil {
+			return errors.Wrapf(err, "decode %s", key)
+		}
+	}
+	return nil
+}
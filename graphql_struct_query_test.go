@@ -0,0 +1,206 @@
+package graphql
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+)
+
+type ReviewInput struct {
+	Stars int
+}
+
+type Node struct {
+	ID string
+}
+
+func TestQueryFromStruct(t *testing.T) {
+	tests := []struct {
+		name      string
+		mutation  bool
+		v         interface{}
+		variables map[string]interface{}
+		want      string
+	}{
+		{
+			name: "field names",
+			v: &struct {
+				Viewer struct {
+					Login     string
+					URLPath   string
+					CreatedAt time.Time
+				}
+			}{},
+			want: "query { viewer { login urlPath createdAt } }",
+		},
+		{
+			name: "tags",
+			v: &struct {
+				User struct {
+					Name      string
+					Thumbnail string `graphql:"thumbnail: avatar(size: 64)"`
+					Admin     struct {
+						Level int
+					} `graphql:"... on Admin"`
+				} `graphql:"user(id: $id)"`
+			}{},
+			variables: map[string]interface{}{"id": ID("1")},
+			want:      "query($id: ID!) { user(id: $id) { name thumbnail: avatar(size: 64) ... on Admin { level } } }",
+		},
+		{
+			name: "lists and pointers",
+			v: &struct {
+				Repos []*struct {
+					ID    string
+					Owner *struct{ Login string }
+				}
+			}{},
+			want: "query { repos { id owner { login } } }",
+		},
+		{
+			name: "embedded structs and skipped fields",
+			v: &struct {
+				Viewer struct {
+					Node
+					Secret string `graphql:"-"`
+					hidden string
+				}
+			}{},
+			want: "query { viewer { id } }",
+		},
+		{
+			name: "variable types",
+			v:    &struct{ Search []struct{ Title string } }{},
+			variables: map[string]interface{}{
+				"after": (*string)(nil),
+				"draft": true,
+				"first": 10,
+				"ratio": 0.5,
+				"tags":  []string{"go"},
+			},
+			want: "query($after: String, $draft: Boolean!, $first: Int!, $ratio: Float!, $tags: [String!]!) { search { title } }",
+		},
+		{
+			name:     "mutation",
+			mutation: true,
+			v: &struct {
+				AddReview struct {
+					Review struct{ Stars int }
+				} `graphql:"addReview(input: $input)"`
+			}{},
+			variables: map[string]interface{}{"input": ReviewInput{Stars: 5}},
+			want:      "mutation($input: ReviewInput!) { addReview(input: $input) { review { stars } } }",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			build := QueryFromStruct
+			if tt.mutation {
+				build = MutationFromStruct
+			}
+			got, err := build(tt.v, tt.variables)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Fatalf("got %q, want %q", got, tt.want)
+			}
+			if _, err := ParseQuery(got); err != nil {
+				t.Fatalf("generated an invalid document: %v", err)
+			}
+		})
+	}
+}
+
+func TestQueryFromStructErrors(t *testing.T) {
+	tests := []struct {
+		name      string
+		v         interface{}
+		variables map[string]interface{}
+		want      string
+	}{
+		{
+			name: "not a pointer",
+			v:    struct{ Viewer struct{ Login string } }{},
+			want: "is not a pointer to a struct",
+		},
+		{
+			name: "pointer to a scalar",
+			v:    new(string),
+			want: "is not a pointer to a struct",
+		},
+		{
+			name:      "nil variable",
+			v:         &struct{ Viewer struct{ Login string } }{},
+			variables: map[string]interface{}{"id": nil},
+			want:      `can't infer the type of variable "id" from nil`,
+		},
+		{
+			name:      "unknown variable type",
+			v:         &struct{ Viewer struct{ Login string } }{},
+			variables: map[string]interface{}{"filter": map[string]int{}},
+			want:      `variable "filter": graphql: can't infer the GraphQL type of map[string]int`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := QueryFromStruct(tt.v, tt.variables)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Fatalf("got error %v, want one containing %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestClientQuery(t *testing.T) {
+	srv := newPayloadServer(t, `{"user":{"name":"Ann","thumbnail":"a.png","level":3,"friends":[{"id":"2"},{"id":"3"}],"manager":null}}`)
+	var q struct {
+		User struct {
+			Name      string
+			Thumbnail string `graphql:"thumbnail: avatar(size: 64)"`
+			Admin     *struct {
+				Level int
+			} `graphql:"... on Admin"`
+			Friends []Node
+			Manager *Node
+		} `graphql:"user(id: $id)"`
+	}
+	graphResponse, err := NewClient(srv.URL).Query(context.Background(), &q, map[string]interface{}{"id": ID("1")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	payload := srv.last()
+	if want := "query($id: ID!) { user(id: $id) { name thumbnail: avatar(size: 64) ... on Admin { level } friends { id } manager { id } } }"; payload.Query != want {
+		t.Fatalf("sent %q, want %q", payload.Query, want)
+	}
+	if payload.Variables["id"] != "1" {
+		t.Fatalf("sent variables %v", payload.Variables)
+	}
+	user := q.User
+	if user.Name != "Ann" || user.Thumbnail != "a.png" || user.Admin == nil || user.Admin.Level != 3 ||
+		len(user.Friends) != 2 || user.Friends[1].ID != "3" || user.Manager != nil {
+		t.Fatalf("decoded %+v", user)
+	}
+	if graphResponse.Data != &q {
+		t.Fatalf("got response data %v, want the query struct", graphResponse.Data)
+	}
+}
+
+func TestClientMutate(t *testing.T) {
+	srv := newPayloadServer(t, `{"addReview":{"review":{"stars":5}}}`)
+	var m struct {
+		AddReview struct {
+			Review struct{ Stars int }
+		} `graphql:"addReview(input: $input)"`
+	}
+	if _, err := NewClient(srv.URL).Mutate(context.Background(), &m, map[string]interface{}{"input": ReviewInput{Stars: 5}}); err != nil {
+		t.Fatal(err)
+	}
+	if payload := srv.last(); !strings.HasPrefix(payload.Query, "mutation($input: ReviewInput!)") {
+		t.Fatalf("sent %q", payload.Query)
+	}
+	if m.AddReview.Review.Stars != 5 {
+		t.Fatalf("decoded %+v", m)
+	}
+}
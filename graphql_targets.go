@@ -0,0 +1,52 @@
+package graphql
+
+import "encoding/json"
+
+// Targets decodes the top-level fields of the data of a response, usually
+// aliased, into separate values, so one round trip can fill several
+// independent structs:
+//
+//	req := NewGraphqlRequest(`{ user: user(id: 1) { name } orders: orders(first: 10) { id } }`)
+//	targets := NewTargets().Decode("user", &user).Decode("orders", &orders)
+//	_, err := client.Run(ctx, req, targets)
+//
+// Fields without a target are ignored, and targets of fields missing from
+// the response are left unchanged.
+type Targets struct {
+	targets map[string]interface{}
+}
+
+// NewTargets makes a new Targets without targets.
+func NewTargets() *Targets {
+	return &Targets{targets: make(map[string]interface{})}
+}
+
+// Decode sets v as the target of the field with the response key key. v
+// must be a pointer.
+func (t *Targets) Decode(key string, v interface{}) *Targets {
+	t.targets[key] = v
+	return t
+}
+
+func (t *Targets) UnmarshalJSON(data []byte) error {
+	return t.decode(data, json.Unmarshal, json.Unmarshal)
+}
+
+// decode splits data into fields with unmarshal, and decodes the fields
+// into their targets with decodeField.
+func (t *Targets) decode(data []byte, unmarshal, decodeField func(data []byte, v interface{}) error) error {
+	var fields map[string]json.RawMessage
+	if err := unmarshal(data, &fields); err != nil {
+		return err
+	}
+	for key, field := range fields {
+		target, ok := t.targets[key]
+		if !ok {
+			continue
+		}
+		if err := decodeField(field, target); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,126 @@
+package graphql
+
+import (
+	"context"
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+type targetUser struct {
+	Name  string
+	State ticketState
+}
+
+type targetOrder struct {
+	ID     string
+	Placed time.Time
+}
+
+func TestTargets(t *testing.T) {
+	placed := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	unchanged := []targetOrder{{ID: "0"}}
+	tests := []struct {
+		name       string
+		opts       []ClientOption
+		data       string
+		wantUser   targetUser
+		wantOrders []targetOrder
+		// wantErr is a part of the expected error, empty for none.
+		wantErr string
+	}{
+		{
+			name:       "fields",
+			data:       `{"user":{"name":"Ann"},"orders":[{"id":"1"},{"id":"2"}],"viewer":{"login":"ann"}}`,
+			wantUser:   targetUser{Name: "Ann"},
+			wantOrders: []targetOrder{{ID: "1"}, {ID: "2"}},
+		},
+		{
+			name:       "missing field",
+			data:       `{"user":{"name":"Ann"}}`,
+			wantUser:   targetUser{Name: "Ann"},
+			wantOrders: unchanged,
+		},
+		{
+			name:       "null field",
+			data:       `{"user":null,"orders":null}`,
+			wantOrders: nil,
+		},
+		{
+			name:    "wrong type",
+			data:    `{"user":"Ann"}`,
+			wantErr: "cannot unmarshal string",
+		},
+		{
+			name:       "strict decoding ignores fields without targets",
+			opts:       []ClientOption{WithStrictDecoding()},
+			data:       `{"user":{"name":"Ann"},"viewer":{"login":"ann"}}`,
+			wantUser:   targetUser{Name: "Ann"},
+			wantOrders: unchanged,
+		},
+		{
+			name:    "strict decoding checks targets",
+			opts:    []ClientOption{WithStrictDecoding()},
+			data:    `{"user":{"name":"Ann","email":"ann@example.com"}}`,
+			wantErr: `unknown field "email"`,
+		},
+		{
+			name:       "scalars",
+			opts:       []ClientOption{WithScalars(dateScalars())},
+			data:       `{"orders":[{"id":"1","placed":"2024-03-01"}]}`,
+			wantOrders: []targetOrder{{ID: "1", Placed: placed}},
+		},
+		{
+			name:       "decode hooks",
+			opts:       []ClientOption{WithDecodeHooks(upperStateHook)},
+			data:       `{"user":{"name":"Ann","state":"open"}}`,
+			wantUser:   targetUser{Name: "Ann", State: "OPEN"},
+			wantOrders: unchanged,
+		},
+		{
+			name:    "strict decoding with scalars",
+			opts:    []ClientOption{WithStrictDecoding(), WithScalars(dateScalars())},
+			data:    `{"orders":[{"id":"1","placed":"2024-03-01","total":3}]}`,
+			wantErr: `unknown field "total"`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := newPayloadServer(t, tt.data)
+			var user targetUser
+			orders := append([]targetOrder(nil), unchanged...)
+			targets := NewTargets().Decode("user", &user).Decode("orders", &orders)
+			req := NewGraphqlRequest(`{ user: user(id: 1) { name state } orders: orders(first: 10) { id placed } viewer { login } }`)
+			_, err := NewClient(srv.URL, tt.opts...).Run(context.Background(), req, targets)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("got error %v, want one containing %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if user != tt.wantUser {
+				t.Fatalf("decoded user %+v, want %+v", user, tt.wantUser)
+			}
+			if !reflect.DeepEqual(orders, tt.wantOrders) {
+				t.Fatalf("decoded orders %+v, want %+v", orders, tt.wantOrders)
+			}
+		})
+	}
+}
+
+func TestTargetsUnmarshalJSON(t *testing.T) {
+	var user targetUser
+	var count int
+	targets := NewTargets().Decode("user", &user).Decode("count", &count)
+	if err := json.Unmarshal([]byte(`{"user":{"name":"Ann"},"count":3,"other":true}`), targets); err != nil {
+		t.Fatal(err)
+	}
+	if user.Name != "Ann" || count != 3 {
+		t.Fatalf("decoded %+v and %d", user, count)
+	}
+}
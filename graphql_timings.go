@@ -0,0 +1,107 @@
+package graphql
+
+import (
+	"context"
+	"crypto/tls"
+	"net/http/httptrace"
+	"sync"
+	"time"
+)
+
+// RequestTimings is the timing breakdown of a single HTTP request. Phases
+// that didn't happen, e.g. DNS and Connect on a reused connection, are zero.
+type RequestTimings struct {
+	DNS          time.Duration
+	Connect      time.Duration
+	TLSHandshake time.Duration
+	// TimeToFirstByte is measured from the start of the request to the
+	// first byte of the response.
+	TimeToFirstByte time.Duration
+	BodyRead        time.Duration
+	Total           time.Duration
+	ReusedConn      bool
+}
+
+// RequestTimingsFunc receives the timing breakdown of an HTTP request.
+type RequestTimingsFunc func(ctx context.Context, info OperationInfo, timings RequestTimings)
+
+// WithRequestTimings calls fn with the timing breakdown of every HTTP
+// request, including retries, so network latency can be told apart from
+// server latency. The breakdown is also passed to Client.Log.
+func WithRequestTimings(fn RequestTimingsFunc) ClientOption {
+	return func(client *Client) {
+		client.onTimings = fn
+	}
+}
+
+type timingsTracker struct {
+	mu        sync.Mutex
+	start     time.Time
+	dnsStart  time.Time
+	connStart time.Time
+	tlsStart  time.Time
+	bodyStart time.Time
+	timings   RequestTimings
+}
+
+func newTimingsTracker() *timingsTracker {
+	return &timingsTracker{start: time.Now()}
+}
+
+func (t *timingsTracker) clientTrace() *httptrace.ClientTrace {
+	return &httptrace.ClientTrace{
+		DNSStart: func(httptrace.DNSStartInfo) { t.mark(&t.dnsStart) },
+		DNSDone:  func(httptrace.DNSDoneInfo) { t.since(&t.timings.DNS, t.dnsStart) },
+		ConnectStart: func(string, string) {
+			t.mark(&t.connStart)
+		},
+		ConnectDone: func(string, string, error) { t.since(&t.timings.Connect, t.connStart) },
+		TLSHandshakeStart: func() {
+			t.mark(&t.tlsStart)
+		},
+		TLSHandshakeDone: func(tls.ConnectionState, error) { t.since(&t.timings.TLSHandshake, t.tlsStart) },
+		GotConn: func(info httptrace.GotConnInfo) {
+			t.mu.Lock()
+			t.timings.ReusedConn = info.Reused
+			t.mu.Unlock()
+		},
+		GotFirstResponseByte: func() { t.since(&t.timings.TimeToFirstByte, t.start) },
+	}
+}
+
+func (t *timingsTracker) mark(at *time.Time) {
+	t.mu.Lock()
+	*at = time.Now()
+	t.mu.Unlock()
+}
+
+func (t *timingsTracker) since(d *time.Duration, start time.Time) {
+	t.mu.Lock()
+	*d = time.Since(start)
+	t.mu.Unlock()
+}
+
+// readingBody marks the start of reading the response body.
+func (t *timingsTracker) readingBody() {
+	if t != nil {
+		t.mark(&t.bodyStart)
+	}
+}
+
+// reportTimings passes the timings of a finished request to the client's callback.
+func (c *Client) reportTimings(ctx context.Context, op *operation, t *timingsTracker) {
+	if t == nil {
+		return
+	}
+	t.mu.Lock()
+	timings := t.timings
+	if !t.bodyStart.IsZero() {
+		timings.BodyRead = time.Since(t.bodyStart)
+	}
+	timings.Total = time.Since(t.start)
+	t.mu.Unlock()
+	c.logf(op, LogLevelDebug, LogWire, "<< timings: dns=%s connect=%s tls=%s ttfb=%s body=%s total=%s reused=%t",
+		timings.DNS, timings.Connect, timings.TLSHandshake, timings.TimeToFirstByte,
+		timings.BodyRead, timings.Total, timings.ReusedConn)
+	c.onTimings(ctx, c.operationInfo(op), timings)
+}
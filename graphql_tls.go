@@ -0,0 +1,105 @@
+package graphql
+
+import (
+	"crypto/tls"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+)
+
+// CertificateLoader returns the client certificate to present in a TLS
+// handshake. It is called for every new connection, so it can return a
+// rotated certificate without recreating the Client.
+type CertificateLoader func() (*tls.Certificate, error)
+
+// WithClientCertificate presents the certificate returned by load to
+// servers that ask for one (mTLS). New connections pick up rotated
+// certificates while in-flight requests keep their connection. It only
+// applies to an *http.Client whose Transport is an *http.Transport, which
+// includes the default one.
+//
+//	NewClient(url, WithClientCertificate(CertificateFromFiles("client.crt", "client.key")))
+func WithClientCertificate(load CertificateLoader) ClientOption {
+	return func(client *Client) {
+		client.clientCertificate = load
+	}
+}
+
+// CertificateFromFiles returns a CertificateLoader that reads a PEM encoded
+// certificate and key pair, and reads them again whenever one of the files
+// changes on disk. If a reload fails, e.g. because the files are being
+// rewritten, the previous certificate keeps being used.
+func CertificateFromFiles(certFile, keyFile string) CertificateLoader {
+	loader := &fileCertificate{certFile: certFile, keyFile: keyFile}
+	return loader.load
+}
+
+type fileCertificate struct {
+	certFile string
+	keyFile  string
+
+	mu          sync.Mutex
+	cert        *tls.Certificate
+	certModTime time.Time
+	keyModTime  time.Time
+}
+
+func (f *fileCertificate) load() (*tls.Certificate, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	certInfo, err := os.Stat(f.certFile)
+	if err != nil {
+		return f.fallback(err)
+	}
+	keyInfo, err := os.Stat(f.keyFile)
+	if err != nil {
+		return f.fallback(err)
+	}
+	if f.cert != nil && certInfo.ModTime().Equal(f.certModTime) && keyInfo.ModTime().Equal(f.keyModTime) {
+		return f.cert, nil
+	}
+	cert, err := tls.LoadX509KeyPair(f.certFile, f.keyFile)
+	if err != nil {
+		return f.fallback(err)
+	}
+	f.cert, f.certModTime, f.keyModTime = &cert, certInfo.ModTime(), keyInfo.ModTime()
+	return f.cert, nil
+}
+
+// fallback returns the last certificate that was loaded, or err if there
+// is none.
+func (f *fileCertificate) fallback(err error) (*tls.Certificate, error) {
+	if f.cert != nil {
+		return f.cert, nil
+	}
+	return nil, err
+}
+
+// withClientCertificate returns a copy of doer whose transport presents the
+// certificate returned by load, or doer itself if its transport can't be
+// configured.
+func withClientCertificate(doer HTTPDoer, load CertificateLoader) HTTPDoer {
+	httpClient, ok := doer.(*http.Client)
+	if !ok {
+		return doer
+	}
+	var transport *http.Transport
+	switch t := httpClient.Transport.(type) {
+	case nil:
+		transport = http.DefaultTransport.(*http.Transport).Clone()
+	case *http.Transport:
+		transport = t.Clone()
+	default:
+		return doer
+	}
+	if transport.TLSClientConfig == nil {
+		transport.TLSClientConfig = &tls.Config{MinVersion: tls.VersionTLS12}
+	}
+	transport.TLSClientConfig.GetClientCertificate = func(*tls.CertificateRequestInfo) (*tls.Certificate, error) {
+		return load()
+	}
+	configured := *httpClient
+	configured.Transport = transport
+	return &configured
+}
@@ -0,0 +1,72 @@
+package graphql
+
+import (
+	"context"
+	"net/http"
+)
+
+// OperationInfo describes an operation the Client is about to run.
+type OperationInfo struct {
+	// Name is the name of the operation, if the query names it.
+	Name string
+	// Type is query, mutation or subscription.
+	Type     string
+	Endpoint string
+	// RequestID is set when request IDs are enabled with WithRequestID.
+	RequestID string
+	// Label is the label set with GraphRequest.SetLabel.
+	Label string
+}
+
+// OperationResult describes the outcome of an operation.
+type OperationResult struct {
+	// StatusCode is the HTTP status of the last response, or zero if none
+	// was received.
+	StatusCode int
+	// GraphQLErrors is the number of errors in the response.
+	GraphQLErrors int
+	Err           error
+}
+
+// Tracer instruments the operations run by the Client. See the otelgraphql
+// package for an OpenTelemetry implementation.
+type Tracer interface {
+	// StartOperation is called when an operation starts. The returned
+	// context is used for the rest of the operation, and the returned
+	// function is called with its outcome.
+	StartOperation(ctx context.Context, info OperationInfo) (context.Context, func(OperationResult))
+	// InjectHeaders adds the trace context of ctx to the headers of an
+	// outgoing request.
+	InjectHeaders(ctx context.Context, header http.Header)
+}
+
+// WithTracer instruments every operation with tracer.
+func WithTracer(tracer Tracer) ClientOption {
+	return func(client *Client) {
+		client.tracer = tracer
+	}
+}
+
+func (c *Client) operationInfo(op *operation) OperationInfo {
+	info := OperationInfo{Name: op.name, Type: "query", Endpoint: c.url, RequestID: op.requestID, Label: op.req.label}
+	if len(op.definitions) > 0 {
+		info.Type = op.definitions[0].Type
+	}
+	return info
+}
+
+// startTrace starts tracing op, returning the context of the operation and
+// the function to call with its outcome.
+func (c *Client) startTrace(ctx context.Context, op *operation) (context.Context, func(*GraphResponse, error)) {
+	if c.tracer == nil {
+		return ctx, func(*GraphResponse, error) {}
+	}
+	ctx, finish := c.tracer.StartOperation(ctx, c.operationInfo(op))
+	return ctx, func(graphResponse *GraphResponse, err error) {
+		result := OperationResult{StatusCode: op.statusCode, Err: err}
+		if graphResponse != nil {
+			result.GraphQLErrors = len(graphResponse.Errors)
+		}
+		finish(result)
+	}
+}
@@ -0,0 +1,30 @@
+package graphql
+
+import "context"
+
+// Run runs req with c and decodes the data of the response into a new T,
+// so the type of the data is checked at compile time:
+//
+//	type userData struct {
+//		User struct{ Name string }
+//	}
+//	data, res, err := graphql.Run[userData](ctx, client, req)
+//
+// The data is returned along with an error when the error policy turns
+// GraphQL errors into one. It is nil when no response was decoded, or when
+// the response has no data: the error is then ErrNoData, unless Run failed
+// with another one.
+func Run[T any](ctx context.Context, c *Client, req *GraphRequest) (*T, *GraphResponse, error) {
+	data := new(T)
+	res, err := c.Run(ctx, req, data)
+	if res == nil {
+		return nil, nil, err
+	}
+	if res.NoData {
+		if err == nil {
+			err = ErrNoData
+		}
+		return nil, res, err
+	}
+	return data, res, err
+}
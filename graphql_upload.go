@@ -0,0 +1,130 @@
+package graphql
+
+import (
+	"encoding/json"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// Upload sets the Upload variable to file, following the GraphQL multipart
+// request spec. The variable may be a path into an input object, e.g.
+// "input.avatar". Requests with uploads are sent as the spec describes,
+// with operations and map fields, and require a Client created with the
+// UseMultipartForm option.
+//
+//	req := NewGraphqlRequest(`mutation($file: Upload!) { upload(file: $file) { id } }`)
+//	req.Upload("file", File{Name: "a.png", R: f})
+func (req *GraphRequest) Upload(variable string, file File) {
+	req.setVariable(variable, nil)
+	file.variablePath = "variables." + variable
+	req.files = append(req.files, file)
+}
+
+// UploadList sets the list-typed Upload variable to files, with the null
+// placeholders and map entries the GraphQL multipart request spec expects.
+//
+//	req := NewGraphqlRequest(`mutation($docs: [Upload!]!) { attach(docs: $docs) }`)
+//	req.UploadList("docs", []File{{Name: "a.pdf", R: a}, {Name: "b.pdf", R: b}})
+func (req *GraphRequest) UploadList(variable string, files []File) {
+	req.setVariable(variable, make([]interface{}, len(files)))
+	for i, file := range files {
+		file.variablePath = "variables." + variable + "." + strconv.Itoa(i)
+		req.files = append(req.files, file)
+	}
+}
+
+// setVariable sets the variable at the dotted path, creating the input
+// objects along the way.
+func (req *GraphRequest) setVariable(path string, value interface{}) {
+	keys := strings.Split(path, ".")
+	if req.vars == nil {
+		req.vars = make(map[string]interface{})
+	}
+	object := req.vars
+	for _, key := range keys[:len(keys)-1] {
+		next, ok := object[key].(map[string]interface{})
+		if !ok {
+			next = make(map[string]interface{})
+			object[key] = next
+		}
+		object = next
+	}
+	object[keys[len(keys)-1]] = value
+}
+
+// multipartField is a form field of a multipart request, other than files.
+type multipartField struct {
+	name  string
+	value string
+}
+
+// multipartFields returns the form fields of the multipart body of req, in
+// the layout of the GraphQL multipart request spec if req has uploads.
+func multipartFields(req *GraphRequest, variables []byte) ([]multipartField, error) {
+	if !hasUploads(req.files) {
+		fields := []multipartField{{name: "query", value: req.query}}
+		if req.operationName != "" {
+			fields = append(fields, multipartField{name: "operationName", value: req.operationName})
+		}
+		if len(variables) > 0 {
+			fields = append(fields, multipartField{name: "variables", value: string(variables)})
+		}
+		if len(req.extensions) > 0 {
+			extensions, err := json.Marshal(req.extensions)
+			if err != nil {
+				return nil, errors.Wrap(err, "encode extensions")
+			}
+			fields = append(fields, multipartField{name: "extensions", value: string(extensions)})
+		}
+		return fields, nil
+	}
+	operations, err := json.Marshal(struct {
+		Query         string                 `json:"query"`
+		OperationName string                 `json:"operationName,omitempty"`
+		Variables     json.RawMessage        `json:"variables"`
+		Extensions    map[string]interface{} `json:"extensions,omitempty"`
+	}{req.query, req.operationName, variablesOrNull(variables), req.extensions})
+	if err != nil {
+		return nil, errors.Wrap(err, "encode operations")
+	}
+	paths := make(map[string][]string)
+	for i := range req.files {
+		if req.files[i].variablePath != "" {
+			paths[strconv.Itoa(i)] = []string{req.files[i].variablePath}
+		}
+	}
+	fileMap, err := json.Marshal(paths)
+	if err != nil {
+		return nil, errors.Wrap(err, "encode map")
+	}
+	return []multipartField{
+		{name: "operations", value: string(operations)},
+		{name: "map", value: string(fileMap)},
+	}, nil
+}
+
+// fileFieldName returns the name of the form field of the i-th file.
+func fileFieldName(i int, file File) string {
+	if file.variablePath != "" {
+		return strconv.Itoa(i)
+	}
+	return file.Field
+}
+
+func hasUploads(files []File) bool {
+	for i := range files {
+		if files[i].variablePath != "" {
+			return true
+		}
+	}
+	return false
+}
+
+func variablesOrNull(variables []byte) []byte {
+	if len(variables) == 0 {
+		return []byte("null")
+	}
+	return variables
+}
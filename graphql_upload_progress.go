@@ -0,0 +1,173 @@
+package graphql
+
+import (
+	"io"
+	"mime/multipart"
+	"os"
+	"strings"
+)
+
+// UploadProgress is the progress of a multipart upload.
+type UploadProgress struct {
+	// Field and Name are the form field and the name of the file, and
+	// are empty for the progress of the whole request body.
+	Field string
+	Name  string
+	Sent  int64
+	// Total is the number of bytes to send, or -1 if it is unknown.
+	Total int64
+}
+
+// UploadProgressFunc receives the progress of uploads. It is called from
+// the goroutine writing the request body, each time a chunk is written.
+// Progress starts over when a request is retried.
+type UploadProgressFunc func(progress UploadProgress)
+
+// WithUploadProgress reports the progress of every file, and of the whole
+// request body, of multipart requests to fn.
+func WithUploadProgress(fn UploadProgressFunc) ClientOption {
+	return func(client *Client) {
+		client.uploadProgress = fn
+	}
+}
+
+// OnUploadProgress reports the progress of the files of this request, and
+// of the whole request body, to fn instead of the Client's
+// UploadProgressFunc.
+func (req *GraphRequest) OnUploadProgress(fn UploadProgressFunc) {
+	req.uploadProgress = fn
+}
+
+// uploadTracker reports the progress of a multipart body.
+type uploadTracker struct {
+	fn    UploadProgressFunc
+	sent  int64
+	total int64
+	sizes []int64
+}
+
+// newUploadTracker returns a tracker of the body of req, or nil if
+// progress isn't reported. It must be called once the files are rewound.
+func (c *Client) newUploadTracker(req *GraphRequest, boundary string, variables []byte) *uploadTracker {
+	fn := req.uploadProgress
+	if fn == nil {
+		fn = c.uploadProgress
+	}
+	if fn == nil {
+		return nil
+	}
+	t := &uploadTracker{fn: fn, total: -1, sizes: make([]int64, len(req.files))}
+	known := true
+	for i := range req.files {
+		t.sizes[i] = readerSize(req.files[i].R)
+		known = known && t.sizes[i] >= 0
+	}
+	if known {
+		t.total = multipartSize(req, boundary, variables, t.sizes)
+	}
+	return t
+}
+
+// body returns w counting the bytes of the whole body.
+func (t *uploadTracker) body(w io.Writer) io.Writer {
+	if t == nil {
+		return w
+	}
+	return writerFunc(func(p []byte) (int, error) {
+		n, err := w.Write(p)
+		t.sent += int64(n)
+		t.fn(UploadProgress{Sent: t.sent, Total: t.total})
+		return n, err
+	})
+}
+
+// file returns the reader of the i-th file, counting the bytes read.
+func (t *uploadTracker) file(i int, file File) io.Reader {
+	if t == nil {
+		return file.R
+	}
+	return &progressReader{r: file.R, fn: t.fn, progress: UploadProgress{
+		Field: fileFieldName(i, file), Name: file.Name, Total: t.sizes[i],
+	}}
+}
+
+type progressReader struct {
+	r        io.Reader
+	fn       UploadProgressFunc
+	progress UploadProgress
+}
+
+func (r *progressReader) Read(p []byte) (int, error) {
+	n, err := r.r.Read(p)
+	if n > 0 {
+		r.progress.Sent += int64(n)
+		r.fn(r.progress)
+	}
+	return n, err
+}
+
+type writerFunc func(p []byte) (int, error)
+
+func (f writerFunc) Write(p []byte) (int, error) {
+	return f(p)
+}
+
+// readerSize returns the number of bytes left to read from r, or -1 if it
+// can't be known without reading.
+func readerSize(r io.Reader) int64 {
+	switch r := r.(type) {
+	case *pathFile:
+		info, err := os.Stat(r.path)
+		if err != nil {
+			return -1
+		}
+		return info.Size()
+	case interface{ Len() int }:
+		return int64(r.Len())
+	case io.Seeker:
+		current, err := r.Seek(0, io.SeekCurrent)
+		if err != nil {
+			return -1
+		}
+		end, err := r.Seek(0, io.SeekEnd)
+		if err != nil {
+			return -1
+		}
+		if _, err := r.Seek(current, io.SeekStart); err != nil {
+			return -1
+		}
+		return end - current
+	}
+	return -1
+}
+
+// multipartSize returns the size of the multipart body of req, given the
+// sizes of its files, or -1 if it can't be computed.
+func multipartSize(req *GraphRequest, boundary string, variables []byte, sizes []int64) int64 {
+	placeholder := req.Clone()
+	size := int64(0)
+	for i := range placeholder.files {
+		file := &placeholder.files[i]
+		if f, ok := file.R.(*pathFile); ok && file.ContentType == "" {
+			contentType, err := f.contentType()
+			if err != nil {
+				return -1
+			}
+			file.ContentType = contentType
+		}
+		file.R = strings.NewReader("")
+		size += sizes[i]
+	}
+	var written int64
+	writer := multipart.NewWriter(writerFunc(func(p []byte) (int, error) {
+		written += int64(len(p))
+		return len(p), nil
+	}))
+	if err := writer.SetBoundary(boundary); err != nil {
+		return -1
+	}
+	if err := writeMultipartBody(writer, placeholder, variables, nil); err != nil {
+		return -1
+	}
+	return size + written
+}
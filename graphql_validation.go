@@ -0,0 +1,660 @@
+package graphql
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// ValidationError is a problem found in a query validated against a
+// Schema.
+type ValidationError struct {
+	Message   string
+	Locations []Location
+}
+
+func (e ValidationError) Error() string {
+	if len(e.Locations) == 0 {
+		return e.Message
+	}
+	return fmt.Sprintf("%s (%d:%d)", e.Message, e.Locations[0].Line, e.Locations[0].Column)
+}
+
+// ValidationErrors are the problems found in a query validated against a
+// Schema. Run returns them, before sending anything, when the Client has a
+// schema and the query is invalid.
+type ValidationErrors []ValidationError
+
+func (e ValidationErrors) Error() string {
+	messages := make([]string, len(e))
+	for i := range e {
+		messages[i] = e[i].Error()
+	}
+	return "graphql: invalid query: " + strings.Join(messages, "; ")
+}
+
+// WithSchema validates every query against schema before it is sent: the
+// fields and arguments it uses must exist, argument and variable values
+// must have the right types, and required variables must be set.
+func WithSchema(schema *Schema) ClientOption {
+	return func(client *Client) {
+		client.schema = schema
+	}
+}
+
+// validate validates req against the schema of the client, if any, and
+// returns the deprecated elements of the schema it uses.
+func (c *Client) validate(req *GraphRequest) ([]Deprecation, error) {
+	if c.schema == nil {
+		return nil, nil
+	}
+	return c.schema.validate(req)
+}
+
+// Validate validates the query and variables of req against s. Syntax
+// errors are returned as they are, and other problems as ValidationErrors.
+func (s *Schema) Validate(req *GraphRequest) error {
+	_, err := s.validate(req)
+	return err
+}
+
+// validate validates req against s, and returns the deprecated elements of
+// the schema req uses if it is valid.
+func (s *Schema) validate(req *GraphRequest) ([]Deprecation, error) {
+	doc, err := parseQuery(req.query)
+	if err != nil {
+		return nil, err
+	}
+	v := &validator{
+		schema:     s,
+		doc:        doc,
+		fragments:  make(map[string]*fragmentNode),
+		reported:   make(map[string]bool),
+		deprecated: make(map[string]bool),
+	}
+	v.validateDocument(req)
+	if len(v.errs) > 0 {
+		return nil, v.errs
+	}
+	return v.deprecations, nil
+}
+
+type validator struct {
+	schema    *Schema
+	doc       *document
+	fragments map[string]*fragmentNode
+	errs      ValidationErrors
+	// reported dedupes errors found in fragments spread more than once.
+	reported map[string]bool
+	// deprecations are reported once per schema coordinate.
+	deprecations []Deprecation
+	deprecated   map[string]bool
+
+	// The state of the operation being validated.
+	variables map[string]*variableNode
+	used      map[string]bool
+	spread    map[string]bool
+	visiting  map[string]bool
+}
+
+func (v *validator) errorf(pos int, format string, args ...interface{}) {
+	message := fmt.Sprintf(format, args...)
+	key := strconv.Itoa(pos) + message
+	if v.reported[key] {
+		return
+	}
+	v.reported[key] = true
+	v.errs = append(v.errs, ValidationError{Message: message, Locations: []Location{locate(v.doc.src, pos)}})
+}
+
+func (v *validator) validateDocument(req *GraphRequest) {
+	for _, fragment := range v.doc.fragments {
+		if _, ok := v.fragments[fragment.name]; ok {
+			v.errorf(fragment.pos, "There can be only one fragment named %q.", fragment.name)
+			continue
+		}
+		v.fragments[fragment.name] = fragment
+	}
+	spread := make(map[string]bool)
+	for _, operation := range v.doc.operations {
+		if operation.name == "" && len(v.doc.operations) > 1 {
+			v.errorf(operation.pos, "This anonymous operation must be the only defined operation.")
+		}
+		v.validateOperation(operation)
+		for name := range v.spread {
+			spread[name] = true
+		}
+	}
+	for _, fragment := range v.doc.fragments {
+		if !spread[fragment.name] {
+			v.errorf(fragment.pos, "Fragment %q is never used.", fragment.name)
+		}
+	}
+	if len(v.errs) > 0 {
+		return
+	}
+	if operation := v.executedOperation(req); operation != nil {
+		v.validateVariableValues(req, operation)
+	}
+}
+
+// executedOperation returns the operation of the document req runs.
+func (v *validator) executedOperation(req *GraphRequest) *operationNode {
+	if req.operationName == "" {
+		if len(v.doc.operations) == 1 {
+			return v.doc.operations[0]
+		}
+		v.errs = append(v.errs, ValidationError{Message: "Must provide operation name if query contains multiple operations."})
+		return nil
+	}
+	for _, operation := range v.doc.operations {
+		if operation.name == req.operationName {
+			return operation
+		}
+	}
+	v.errs = append(v.errs, ValidationError{Message: fmt.Sprintf("Unknown operation named %q.", req.operationName)})
+	return nil
+}
+
+func (v *validator) validateOperation(operation *operationNode) {
+	v.variables = make(map[string]*variableNode)
+	v.used = make(map[string]bool)
+	v.spread = make(map[string]bool)
+	v.visiting = make(map[string]bool)
+	for _, variable := range operation.variables {
+		if _, ok := v.variables[variable.name]; ok {
+			v.errorf(variable.pos, "There can be only one variable named \"$%s\".", variable.name)
+			continue
+		}
+		v.variables[variable.name] = variable
+		t := v.schema.types[variable.typ.namedType()]
+		switch {
+		case t == nil:
+			v.errorf(variable.pos, "Unknown type %q.", variable.typ.namedType())
+		case !t.isInput():
+			v.errorf(variable.pos, "Variable \"$%s\" cannot be non-input type %q.", variable.name, variable.typ)
+		case variable.defaultValue != nil:
+			v.validateValue(variable.defaultValue, variable.typ)
+		}
+	}
+	v.validateDirectives(operation.directives)
+	root := v.schema.rootType(operation.typ)
+	if root == nil {
+		v.errorf(operation.pos, "Schema is not configured for %ss.", operation.typ)
+	} else {
+		v.validateSelections(operation.selections, root)
+	}
+	for _, variable := range operation.variables {
+		if !v.used[variable.name] {
+			if operation.name != "" {
+				v.errorf(variable.pos, "Variable \"$%s\" is never used in operation %q.", variable.name, operation.name)
+			} else {
+				v.errorf(variable.pos, "Variable \"$%s\" is never used.", variable.name)
+			}
+		}
+	}
+}
+
+func (v *validator) validateSelections(selections []*selectionNode, parent *schemaType) {
+	for _, selection := range selections {
+		v.validateDirectives(selection.directives)
+		switch selection.kind {
+		case selectionField:
+			v.validateField(selection, parent)
+		case selectionInlineFragment:
+			t := parent
+			if selection.typeCondition != "" {
+				if t = v.typeCondition(selection.typeCondition, selection.pos, parent); t == nil {
+					continue
+				}
+			}
+			v.validateSelections(selection.selections, t)
+		case selectionFragmentSpread:
+			fragment := v.fragments[selection.name]
+			if fragment == nil {
+				v.errorf(selection.pos, "Unknown fragment %q.", selection.name)
+				continue
+			}
+			v.spread[fragment.name] = true
+			if v.visiting[fragment.name] {
+				v.errorf(selection.pos, "Cannot spread fragment %q within itself.", fragment.name)
+				continue
+			}
+			t := v.typeCondition(fragment.typeCondition, fragment.pos, parent)
+			if t == nil {
+				continue
+			}
+			v.visiting[fragment.name] = true
+			v.validateDirectives(fragment.directives)
+			v.validateSelections(fragment.selections, t)
+			v.visiting[fragment.name] = false
+		}
+	}
+}
+
+// typeCondition returns the type of a fragment on name, spread in parent.
+func (v *validator) typeCondition(name string, pos int, parent *schemaType) *schemaType {
+	t := v.schema.types[name]
+	switch {
+	case t == nil:
+		v.errorf(pos, "Unknown type %q.", name)
+		return nil
+	case !t.isComposite():
+		v.errorf(pos, "Fragment cannot condition on non composite type %q.", name)
+		return nil
+	}
+	for possible := range v.possibleTypes(t) {
+		if v.possibleTypes(parent)[possible] {
+			return t
+		}
+	}
+	v.errorf(pos, "Fragment on %q cannot be spread here as objects of type %q can never be of type %q.", name, parent.name, name)
+	return nil
+}
+
+// possibleTypes returns the object types a value of type t can have.
+func (v *validator) possibleTypes(t *schemaType) map[string]bool {
+	if t.kind == kindObject {
+		return map[string]bool{t.name: true}
+	}
+	return t.possibleTypes
+}
+
+func (v *validator) validateField(selection *selectionNode, parent *schemaType) {
+	if selection.name == "__typename" {
+		if len(selection.selections) > 0 {
+			v.errorf(selection.pos, "Field \"__typename\" must not have a selection since type \"String!\" has no subfields.")
+		}
+		return
+	}
+	if (selection.name == "__schema" || selection.name == "__type") && parent == v.schema.rootType("query") {
+		// Introspection types aren't part of the schema.
+		return
+	}
+	field := parent.fields[selection.name]
+	if field == nil {
+		v.errorf(selection.pos, "Cannot query field %q on type %q.", selection.name, parent.name)
+		return
+	}
+	if field.deprecated {
+		v.deprecate(selection.pos, parent.name+"."+field.name, field.deprecationReason)
+	}
+	v.validateArguments(selection.arguments, field.args, selection.pos, parent.name+"."+field.name)
+	t := v.schema.types[field.typ.namedType()]
+	if t == nil {
+		return
+	}
+	switch {
+	case t.isComposite() && len(selection.selections) == 0:
+		v.errorf(selection.pos, "Field %q of type %q must have a selection of subfields.", selection.name, field.typ)
+	case !t.isComposite() && len(selection.selections) > 0:
+		v.errorf(selection.pos, "Field %q must not have a selection since type %q has no subfields.", selection.name, field.typ)
+	case t.isComposite():
+		v.validateSelections(selection.selections, t)
+	}
+}
+
+func (v *validator) validateDirectives(directives []*directiveNode) {
+	for _, directive := range directives {
+		definition := v.schema.directives[directive.name]
+		if definition == nil {
+			v.errorf(directive.pos, "Unknown directive \"@%s\".", directive.name)
+			continue
+		}
+		v.validateArguments(directive.arguments, definition.args, directive.pos, "@"+directive.name)
+	}
+}
+
+// validateArguments validates the arguments given to the field or
+// directive at coordinate, such as "Query.user" or "@include", against
+// their definitions.
+func (v *validator) validateArguments(arguments []*argumentNode, definitions map[string]*schemaInputValue, pos int, coordinate string) {
+	owner := fmt.Sprintf("field %q", coordinate)
+	if strings.HasPrefix(coordinate, "@") {
+		owner = fmt.Sprintf("directive %q", coordinate)
+	}
+	given := make(map[string]bool, len(arguments))
+	for _, argument := range arguments {
+		given[argument.name] = true
+		definition := definitions[argument.name]
+		if definition == nil {
+			v.errorf(argument.pos, "Unknown argument %q on %s.", argument.name, owner)
+			continue
+		}
+		if definition.deprecated {
+			v.deprecate(argument.pos, coordinate+"("+argument.name+":)", definition.deprecationReason)
+		}
+		v.validateValueAt(argument.value, definition.typ, definition.hasDefault)
+	}
+	for name, definition := range definitions {
+		if definition.typ.nonNull && !definition.hasDefault && !given[name] {
+			v.errorf(pos, "Argument %q of type %q is required on %s, but it was not provided.", name, definition.typ, owner)
+		}
+	}
+}
+
+func (v *validator) validateValue(value *valueNode, expected *typeRef) {
+	v.validateValueAt(value, expected, false)
+}
+
+// validateValueAt validates a literal value used where a value of type
+// expected is, with a default value if hasDefault is set.
+func (v *validator) validateValueAt(value *valueNode, expected *typeRef, hasDefault bool) {
+	if value.kind == valueVariable {
+		v.used[value.text] = true
+		variable := v.variables[value.text]
+		if variable == nil {
+			v.errorf(value.pos, "Variable \"$%s\" is not defined.", value.text)
+			return
+		}
+		variableType := variable.typ
+		if !variableType.nonNull && expected.nonNull && (hasDefault || variable.defaultValue != nil && variable.defaultValue.kind != valueNull) {
+			variableType = &typeRef{name: variableType.name, elem: variableType.elem, nonNull: true}
+		}
+		if !typeCompatible(variableType, expected) {
+			v.errorf(value.pos, "Variable \"$%s\" of type %q used in position expecting type %q.", value.text, variable.typ, expected)
+		}
+		return
+	}
+	if value.kind == valueNull {
+		if expected.nonNull {
+			v.errorf(value.pos, "Expected value of type %q, found null.", expected)
+		}
+		return
+	}
+	if expected.elem != nil {
+		if value.kind != valueList {
+			v.validateValueAt(value, expected.elem, false)
+			return
+		}
+		for _, item := range value.list {
+			v.validateValueAt(item, expected.elem, false)
+		}
+		return
+	}
+	t := v.schema.types[expected.name]
+	if t == nil {
+		return
+	}
+	switch t.kind {
+	case kindScalar:
+		if !literalIsScalar(value, t.name) {
+			v.errorf(value.pos, "%s cannot represent %s.", t.name, formatValue(value))
+		}
+	case kindEnum:
+		enumValue := t.enumValues[value.text]
+		if value.kind != valueEnum || enumValue == nil {
+			v.errorf(value.pos, "Value %s does not exist in %q enum.", formatValue(value), t.name)
+		} else if enumValue.deprecated {
+			v.deprecate(value.pos, t.name+"."+enumValue.name, enumValue.deprecationReason)
+		}
+	case kindInputObject:
+		if value.kind != valueObject {
+			v.errorf(value.pos, "Expected value of type %q, found %s.", expected, formatValue(value))
+			return
+		}
+		given := make(map[string]bool, len(value.fields))
+		for _, field := range value.fields {
+			given[field.name] = true
+			definition := t.inputFields[field.name]
+			if definition == nil {
+				v.errorf(field.pos, "Field %q is not defined by type %q.", field.name, t.name)
+				continue
+			}
+			if definition.deprecated {
+				v.deprecate(field.pos, t.name+"."+field.name, definition.deprecationReason)
+			}
+			v.validateValueAt(field.value, definition.typ, definition.hasDefault)
+		}
+		for name, definition := range t.inputFields {
+			if definition.typ.nonNull && !definition.hasDefault && !given[name] {
+				v.errorf(value.pos, "Field \"%s.%s\" of required type %q was not provided.", t.name, name, definition.typ)
+			}
+		}
+		if t.oneOf {
+			v.validateOneOf(value, t)
+		}
+	}
+}
+
+// validateOneOf checks that the literal value of the @oneOf input object t
+// sets exactly one field, to a non-null value.
+func (v *validator) validateOneOf(value *valueNode, t *schemaType) {
+	if len(value.fields) != 1 {
+		v.errorf(value.pos, "OneOf input object %q must specify exactly one field, but %d were set.", t.name, len(value.fields))
+		return
+	}
+	field := value.fields[0]
+	switch field.value.kind {
+	case valueNull:
+		v.errorf(field.pos, "Field \"%s.%s\" must be non-null.", t.name, field.name)
+	case valueVariable:
+		if variable := v.variables[field.value.text]; variable != nil && !variable.typ.nonNull {
+			v.errorf(field.pos, "Variable \"$%s\" is of type %q but must be non-nullable to be used for OneOf input object %q.",
+				variable.name, variable.typ, t.name)
+		}
+	}
+}
+
+// typeCompatible reports whether a variable of type variable can be used
+// where a value of type expected is.
+func typeCompatible(variable, expected *typeRef) bool {
+	if expected.nonNull && !variable.nonNull {
+		return false
+	}
+	if variable.elem != nil || expected.elem != nil {
+		return variable.elem != nil && expected.elem != nil && typeCompatible(variable.elem, expected.elem)
+	}
+	return variable.name == expected.name
+}
+
+// literalIsScalar reports whether value can be coerced to the scalar name.
+// Custom scalars accept any value.
+func literalIsScalar(value *valueNode, name string) bool {
+	switch name {
+	case "Int":
+		_, err := strconv.ParseInt(value.text, 10, 32)
+		return value.kind == valueInt && err == nil
+	case "Float":
+		return value.kind == valueInt || value.kind == valueFloat
+	case "String":
+		return value.kind == valueString
+	case "Boolean":
+		return value.kind == valueBoolean
+	case "ID":
+		return value.kind == valueString || value.kind == valueInt
+	}
+	return true
+}
+
+// formatValue formats value for error messages.
+func formatValue(value *valueNode) string {
+	switch value.kind {
+	case valueVariable:
+		return "$" + value.text
+	case valueString:
+		return strconv.Quote(value.text)
+	case valueList:
+		items := make([]string, len(value.list))
+		for i, item := range value.list {
+			items[i] = formatValue(item)
+		}
+		return "[" + strings.Join(items, ", ") + "]"
+	case valueObject:
+		fields := make([]string, len(value.fields))
+		for i, field := range value.fields {
+			fields[i] = field.name + ": " + formatValue(field.value)
+		}
+		return "{" + strings.Join(fields, ", ") + "}"
+	}
+	return value.text
+}
+
+// validateVariableValues checks that the variables of req can be coerced
+// to the types operation declares.
+func (v *validator) validateVariableValues(req *GraphRequest, operation *operationNode) {
+	values := map[string]interface{}{}
+	if len(req.vars) > 0 {
+		encoded, err := json.Marshal(req.vars)
+		if err != nil {
+			v.errs = append(v.errs, ValidationError{Message: errors.Wrap(err, "encode variables").Error()})
+			return
+		}
+		decoder := json.NewDecoder(bytes.NewReader(encoded))
+		decoder.UseNumber()
+		if err := decoder.Decode(&values); err != nil {
+			v.errs = append(v.errs, ValidationError{Message: errors.Wrap(err, "decode variables").Error()})
+			return
+		}
+	}
+	// Upload variables are null until the server maps the files to them.
+	uploads := make(map[string]bool)
+	for _, file := range req.files {
+		if file.variablePath != "" {
+			uploads[strings.TrimPrefix(file.variablePath, "variables.")] = true
+		}
+	}
+	for _, variable := range operation.variables {
+		value, ok := values[variable.name]
+		if !ok {
+			if variable.typ.nonNull && variable.defaultValue == nil {
+				v.errorf(variable.pos, "Variable \"$%s\" of required type %q was not provided.", variable.name, variable.typ)
+			}
+			continue
+		}
+		if problem := v.coerceVariable(value, variable.typ, variable.name, variable.pos, uploads); problem != "" {
+			v.errorf(variable.pos, "Variable \"$%s\" got invalid value: %s", variable.name, problem)
+		}
+	}
+}
+
+// coerceVariable returns why value, found at path in the variables, can't
+// be coerced to t, or an empty string if it can. pos is the position of the
+// definition of the variable.
+func (v *validator) coerceVariable(value interface{}, t *typeRef, path string, pos int, uploads map[string]bool) string {
+	if value == nil {
+		if t.nonNull && !uploads[path] {
+			return fmt.Sprintf("expected non-null value of type %q at %q", t, path)
+		}
+		return ""
+	}
+	if t.elem != nil {
+		list, ok := value.([]interface{})
+		if !ok {
+			return v.coerceVariable(value, t.elem, path, pos, uploads)
+		}
+		for i, item := range list {
+			if problem := v.coerceVariable(item, t.elem, path+"."+strconv.Itoa(i), pos, uploads); problem != "" {
+				return problem
+			}
+		}
+		return ""
+	}
+	named := v.schema.types[t.name]
+	if named == nil {
+		return ""
+	}
+	invalid := fmt.Sprintf("expected value of type %q at %q", t, path)
+	switch named.kind {
+	case kindScalar:
+		if !jsonIsScalar(value, named.name) {
+			return invalid
+		}
+	case kindEnum:
+		name, ok := value.(string)
+		if !ok || named.enumValues[name] == nil {
+			return invalid
+		}
+		if enumValue := named.enumValues[name]; enumValue.deprecated {
+			v.deprecate(pos, named.name+"."+name, enumValue.deprecationReason)
+		}
+	case kindInputObject:
+		object, ok := value.(map[string]interface{})
+		if !ok {
+			return invalid
+		}
+		for name := range object {
+			if named.inputFields[name] == nil {
+				return fmt.Sprintf("field %q is not defined by type %q at %q", name, named.name, path)
+			}
+		}
+		for name, field := range named.inputFields {
+			fieldValue, ok := object[name]
+			if !ok {
+				if field.typ.nonNull && !field.hasDefault {
+					return fmt.Sprintf("field %q of required type %q was not provided at %q", name, field.typ, path)
+				}
+				continue
+			}
+			if field.deprecated {
+				v.deprecate(pos, named.name+"."+name, field.deprecationReason)
+			}
+			if problem := v.coerceVariable(fieldValue, field.typ, path+"."+name, pos, uploads); problem != "" {
+				return problem
+			}
+		}
+		if named.oneOf {
+			return oneOfProblem(named.name, object, path)
+		}
+	}
+	return ""
+}
+
+// oneOfProblem returns why object, found at path in the variables, isn't a
+// valid value of the @oneOf input object name, or an empty string if it is.
+func oneOfProblem(name string, object map[string]interface{}, path string) string {
+	fields := make([]string, 0, len(object))
+	for field := range object {
+		fields = append(fields, field)
+	}
+	sort.Strings(fields)
+	switch {
+	case len(fields) == 0:
+		return fmt.Sprintf("OneOf input object %q must specify exactly one field at %q, but none was set", name, path)
+	case len(fields) > 1:
+		return fmt.Sprintf("OneOf input object %q must specify exactly one field at %q, but %d were set: %s",
+			name, path, len(fields), strings.Join(fields, ", "))
+	case object[fields[0]] == nil:
+		return fmt.Sprintf("OneOf input object %q field %q must be non-null at %q", name, fields[0], path)
+	}
+	return ""
+}
+
+// jsonIsScalar reports whether a decoded JSON value can be coerced to the
+// scalar name. Custom scalars accept any value.
+func jsonIsScalar(value interface{}, name string) bool {
+	switch name {
+	case "Int":
+		n, ok := value.(json.Number)
+		if !ok {
+			return false
+		}
+		i, err := n.Int64()
+		return err == nil && i >= math.MinInt32 && i <= math.MaxInt32
+	case "Float":
+		_, ok := value.(json.Number)
+		return ok
+	case "String":
+		_, ok := value.(string)
+		return ok
+	case "Boolean":
+		_, ok := value.(bool)
+		return ok
+	case "ID":
+		switch value := value.(type) {
+		case string:
+			return true
+		case json.Number:
+			_, err := value.Int64()
+			return err == nil
+		}
+		return false
+	}
+	return true
+}
@@ -0,0 +1,137 @@
+package graphql
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/pkg/errors"
+)
+
+const testSchema = `
+type Query {
+	user(id: ID!): User
+	users(first: Int = 10, role: Role): [User!]!
+	search(filter: UserFilter): [User!]!
+}
+
+type Mutation {
+	rename(id: ID!, name: String!): User
+}
+
+type User {
+	id: ID!
+	name: String
+	role: Role
+	friends: [User!]!
+}
+
+enum Role { ADMIN MEMBER }
+
+input UserFilter {
+	name: String
+	role: Role!
+}
+`
+
+func mustParseSchema(t *testing.T) *Schema {
+	t.Helper()
+	schema, err := ParseSchema(testSchema)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return schema
+}
+
+func TestSchemaValidate(t *testing.T) {
+	schema := mustParseSchema(t)
+	tests := []struct {
+		name  string
+		query string
+		vars  map[string]interface{}
+		// err is a part of the expected error, empty for valid queries.
+		err string
+	}{
+		{name: "valid", query: `{ user(id: 1) { id name friends { id } } }`},
+		{name: "fragment", query: `query { users { ...F } } fragment F on User { id role }`},
+		{name: "variables", query: `query($id: ID!) { user(id: $id) { id } }`, vars: map[string]interface{}{"id": "1"}},
+		{name: "input object", query: `{ search(filter: {role: ADMIN}) { id } }`},
+		{name: "mutation", query: `mutation { rename(id: 1, name: "a") { id } }`},
+		{name: "syntax error", query: `{ user(id: 1) { id }`, err: "syntax error"},
+		{name: "unknown field", query: `{ user(id: 1) { email } }`, err: `Cannot query field "email" on type "User".`},
+		{name: "missing argument", query: `{ user { id } }`, err: `Argument "id" of type "ID!" is required`},
+		{name: "unknown argument", query: `{ user(id: 1, name: "a") { id } }`, err: `Unknown argument "name"`},
+		{name: "missing subfields", query: `{ user(id: 1) }`, err: `must have a selection of subfields`},
+		{name: "leaf subfields", query: `{ user(id: 1) { name { x } } }`, err: `must not have a selection`},
+		{name: "bad enum", query: `{ users(role: OWNER) { id } }`, err: `Value OWNER does not exist in "Role" enum.`},
+		{name: "bad scalar", query: `{ users(first: "ten") { id } }`, err: `Int cannot represent`},
+		{name: "missing input field", query: `{ search(filter: {name: "a"}) { id } }`, err: `Field "UserFilter.role" of required type "Role!" was not provided.`},
+		{name: "undefined variable", query: `{ user(id: $id) { id } }`, err: `Variable "$id" is not defined.`},
+		{name: "unused variable", query: `query($id: ID) { users { id } }`, err: `Variable "$id" is never used.`},
+		{name: "missing variable", query: `query($id: ID!) { user(id: $id) { id } }`, err: `Variable "$id" of required type "ID!" was not provided.`},
+		{name: "unknown fragment", query: `{ users { ...F } }`, err: `Unknown fragment "F".`},
+		{name: "unused fragment", query: `{ users { id } } fragment F on User { id }`, err: `Fragment "F" is never used.`},
+		{name: "no subscriptions", query: `subscription { users { id } }`, err: `Schema is not configured for subscriptions.`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := NewGraphqlRequest(tt.query)
+			for name, value := range tt.vars {
+				req.Var(name, value)
+			}
+			err := schema.Validate(req)
+			switch {
+			case tt.err == "" && err != nil:
+				t.Fatalf("got %v, want no error", err)
+			case tt.err != "" && (err == nil || !strings.Contains(err.Error(), tt.err)):
+				t.Fatalf("got %v, want an error containing %q", err, tt.err)
+			}
+		})
+	}
+}
+
+func TestSchemaValidateLocations(t *testing.T) {
+	err := mustParseSchema(t).Validate(NewGraphqlRequest("{\n  user(id: 1) {\n    email\n  }\n}"))
+	var errs ValidationErrors
+	if !errors.As(err, &errs) || len(errs) != 1 {
+		t.Fatalf("got %v, want a single ValidationError", err)
+	}
+	if want := (Location{Line: 3, Column: 5}); len(errs[0].Locations) != 1 || errs[0].Locations[0] != want {
+		t.Fatalf("got locations %v, want %v", errs[0].Locations, want)
+	}
+}
+
+func TestWithSchemaValidatesBeforeSending(t *testing.T) {
+	var calls int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		fmt.Fprint(w, `{"data":{"user":{"id":"1"}}}`)
+	}))
+	defer srv.Close()
+	client := NewClient(srv.URL, WithSchema(mustParseSchema(t)))
+	tests := map[string]struct {
+		query string
+		valid bool
+	}{
+		"valid":   {query: `{ user(id: 1) { id } }`, valid: true},
+		"invalid": {query: `{ user(id: 1) { email } }`},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			before := atomic.LoadInt32(&calls)
+			_, err := client.Run(context.Background(), NewGraphqlRequest(tt.query), nil)
+			var errs ValidationErrors
+			if tt.valid != (err == nil) || !tt.valid && !errors.As(err, &errs) {
+				t.Fatalf("got %v", err)
+			}
+			sent := atomic.LoadInt32(&calls) - before
+			if want := map[bool]int32{true: 1, false: 0}[tt.valid]; sent != want {
+				t.Fatalf("server got %d requests, want %d", sent, want)
+			}
+		})
+	}
+}
@@ -0,0 +1,141 @@
+// Package graphqltest helps testing code running GraphQL requests: Client
+// is a fake graphql.Runner for unit tests that don't need a server, and
+// Server a GraphQL server answering with fixtures for tests going through
+// a real graphql.Client.
+//
+//	client := graphqltest.NewClient()
+//	client.QueueData("GetUser", map[string]interface{}{"user": map[string]interface{}{"name": "Ada"}})
+//	user, err := users.Get(ctx, client, "42") // takes a graphql.Runner
+//	client.AssertVariables(t, "GetUser", map[string]interface{}{"id": "42"})
+package graphqltest
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"testing"
+
+	graphql "github.com/pzentenoe/graphql-client"
+)
+
+// Client is a fake graphql.Runner answering requests with the responses
+// queued for their operation names, and recording the requests it
+// receives. It is safe for concurrent use.
+type Client struct {
+	recorder
+
+	mu        sync.Mutex
+	responses map[string][]response
+}
+
+// response is a queued response, or error.
+type response struct {
+	res *graphql.GraphResponse
+	err error
+}
+
+var _ graphql.Runner = (*Client)(nil)
+
+// NewClient returns a Client without queued responses.
+func NewClient() *Client {
+	return &Client{responses: make(map[string][]response)}
+}
+
+// Queue queues res as the response to the next request of the operation
+// named operationName, "" for anonymous operations. Its Data is encoded to
+// JSON and decoded into the value passed to Run, so it can be a map or a
+// json.RawMessage as well as a value of the type Run decodes into.
+func (c *Client) Queue(operationName string, res *graphql.GraphResponse) {
+	c.push(operationName, response{res: res})
+}
+
+// QueueData queues a response with data and no errors, see Queue.
+func (c *Client) QueueData(operationName string, data interface{}) {
+	c.Queue(operationName, &graphql.GraphResponse{Data: data})
+}
+
+// QueueError makes the next request of the operation named operationName
+// fail with err, e.g. a *graphql.HTTPError or a network error.
+func (c *Client) QueueError(operationName string, err error) {
+	c.push(operationName, response{err: err})
+}
+
+func (c *Client) push(operationName string, r response) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.responses[operationName] = append(c.responses[operationName], r)
+}
+
+// Run records req and answers it with the next response queued for its
+// operation. It fails if there is none.
+func (c *Client) Run(ctx context.Context, req *graphql.GraphRequest, graphqlResponse interface{}) (*graphql.GraphResponse, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	name := OperationName(req)
+	c.record(req.Clone())
+	c.mu.Lock()
+	queue := c.responses[name]
+	if len(queue) == 0 {
+		c.mu.Unlock()
+		return nil, fmt.Errorf("graphqltest: no response queued for operation %q", name)
+	}
+	next := queue[0]
+	c.responses[name] = queue[1:]
+	c.mu.Unlock()
+	if next.err != nil {
+		return nil, next.err
+	}
+	res := *next.res
+	if res.StatusCode == 0 {
+		res.StatusCode = http.StatusOK
+	}
+	res.Data = nil
+	if next.res.Data != nil {
+		data, err := json.Marshal(next.res.Data)
+		if err != nil {
+			return nil, fmt.Errorf("graphqltest: encoding data of %q: %v", name, err)
+		}
+		if graphqlResponse == nil {
+			res.RawData = data
+		} else if err := json.Unmarshal(data, graphqlResponse); err != nil {
+			return nil, fmt.Errorf("graphqltest: decoding data of %q: %v", name, err)
+		}
+	}
+	res.NoData = next.res.Data == nil
+	if graphqlResponse != nil {
+		res.Data = graphqlResponse
+	}
+	return &res, nil
+}
+
+// OperationName returns the name of the operation req runs: its operation
+// name if set, or the name of the only operation of its query.
+func OperationName(req *graphql.GraphRequest) string {
+	if name := req.OperationName(); name != "" {
+		return name
+	}
+	doc, err := req.Parse()
+	if err != nil {
+		return ""
+	}
+	operation, err := doc.Operation("")
+	if err != nil {
+		return ""
+	}
+	return operation.Name
+}
+
+// AssertAllConsumed checks that every queued response was returned.
+func (c *Client) AssertAllConsumed(t testing.TB) {
+	t.Helper()
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for name, queue := range c.responses {
+		if len(queue) > 0 {
+			t.Errorf("graphqltest: %d responses queued for operation %q were not used", len(queue), name)
+		}
+	}
+}
@@ -0,0 +1,213 @@
+package graphqltest
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	graphql "github.com/pzentenoe/graphql-client"
+)
+
+// recordingT records the failures of the assertions instead of failing
+// the test.
+type recordingT struct {
+	testing.TB
+	failures []string
+}
+
+func (t *recordingT) Errorf(format string, args ...interface{}) {
+	t.failures = append(t.failures, fmt.Sprintf(format, args...))
+}
+
+type userData struct {
+	User struct {
+		Name string `json:"name"`
+	} `json:"user"`
+}
+
+func TestClient(t *testing.T) {
+	errNetwork := errors.New("connection refused")
+	tests := []struct {
+		name          string
+		queue         func(c *Client)
+		query         string
+		operationName string
+		// decode is false to run the request without a value to decode
+		// the data into.
+		decode bool
+		ctx    func() context.Context
+		want   string
+		// wantErr is a part of the expected error, empty for none.
+		wantErr    string
+		wantErrors int
+		wantNoData bool
+	}{
+		{
+			name: "named operation",
+			queue: func(c *Client) {
+				c.QueueData("GetUser", map[string]interface{}{"user": map[string]interface{}{"name": "Ada"}})
+			},
+			query:  `query GetUser { user { name } }`,
+			decode: true,
+			want:   `{"user":{"name":"Ada"}}`,
+		},
+		{
+			name:          "operation name of the request",
+			queue:         func(c *Client) { c.QueueData("B", json.RawMessage(`{"user":{"name":"Bob"}}`)) },
+			query:         `query A { user { id } } query B { user { name } }`,
+			operationName: "B",
+			decode:        true,
+			want:          `{"user":{"name":"Bob"}}`,
+		},
+		{
+			name:   "anonymous operation",
+			queue:  func(c *Client) { c.QueueData("", userData{}) },
+			query:  `{ user { name } }`,
+			decode: true,
+			want:   `{"user":{"name":""}}`,
+		},
+		{
+			name:  "raw data",
+			queue: func(c *Client) { c.QueueData("", map[string]interface{}{"user": nil}) },
+			query: `{ user { name } }`,
+			want:  `{"user":null}`,
+		},
+		{
+			name: "graphql errors",
+			queue: func(c *Client) {
+				c.Queue("GetUser", &graphql.GraphResponse{Errors: []graphql.GraphErr{{Message: "not found"}}})
+			},
+			query:      `query GetUser { user { name } }`,
+			decode:     true,
+			want:       `{"user":{"name":""}}`,
+			wantErrors: 1,
+			wantNoData: true,
+		},
+		{
+			name:    "error",
+			queue:   func(c *Client) { c.QueueError("GetUser", errNetwork) },
+			query:   `query GetUser { user { name } }`,
+			wantErr: "connection refused",
+		},
+		{
+			name:    "nothing queued",
+			queue:   func(c *Client) { c.QueueData("Other", nil) },
+			query:   `query GetUser { user { name } }`,
+			wantErr: `no response queued for operation "GetUser"`,
+		},
+		{
+			name:    "data of another type",
+			queue:   func(c *Client) { c.QueueData("", map[string]interface{}{"user": "Ada"}) },
+			query:   `{ user { name } }`,
+			decode:  true,
+			wantErr: "decoding data",
+		},
+		{
+			name:  "canceled context",
+			queue: func(c *Client) { c.QueueData("", userData{}) },
+			query: `{ user { name } }`,
+			ctx: func() context.Context {
+				ctx, cancel := context.WithCancel(context.Background())
+				cancel()
+				return ctx
+			},
+			wantErr: "context canceled",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := NewClient()
+			tt.queue(client)
+			req := graphql.NewGraphqlRequest(tt.query)
+			req.SetOperationName(tt.operationName)
+			ctx := context.Background()
+			if tt.ctx != nil {
+				ctx = tt.ctx()
+			}
+			var data userData
+			var target interface{}
+			if tt.decode {
+				target = &data
+			}
+			res, err := client.Run(ctx, req, target)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("got error %v, want one containing %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := []byte(res.RawData)
+			if tt.decode {
+				got, _ = json.Marshal(data)
+			}
+			if string(got) != tt.want {
+				t.Fatalf("got data %s, want %s", got, tt.want)
+			}
+			if len(res.Errors) != tt.wantErrors || res.NoData != tt.wantNoData || res.StatusCode != 200 {
+				t.Fatalf("got errors %v, NoData %t and status %d", res.Errors, res.NoData, res.StatusCode)
+			}
+		})
+	}
+}
+
+func TestClientAnswersInOrder(t *testing.T) {
+	client := NewClient()
+	client.QueueData("", map[string]interface{}{"user": map[string]interface{}{"name": "Ada"}})
+	client.QueueData("", map[string]interface{}{"user": map[string]interface{}{"name": "Bob"}})
+	for _, want := range []string{"Ada", "Bob"} {
+		var data userData
+		if _, err := client.Run(context.Background(), graphql.NewGraphqlRequest(`{ user { name } }`), &data); err != nil {
+			t.Fatal(err)
+		}
+		if data.User.Name != want {
+			t.Fatalf("got %q, want %q", data.User.Name, want)
+		}
+	}
+	client.AssertAllConsumed(t)
+}
+
+func TestClientAssertions(t *testing.T) {
+	client := NewClient()
+	client.QueueData("GetUser", userData{})
+	client.QueueData("GetUser", userData{})
+	req := graphql.NewGraphqlRequest(`query GetUser($id: ID!) { user(id: $id) { name } }`)
+	req.Var("id", 42)
+	if _, err := client.Run(context.Background(), req, nil); err != nil {
+		t.Fatal(err)
+	}
+	// the recorded request is a copy
+	req.Var("id", 43)
+	tests := []struct {
+		name   string
+		assert func(t testing.TB)
+		// want is a part of the expected failure, empty for none.
+		want string
+	}{
+		{name: "called", assert: func(t testing.TB) { client.AssertCalled(t, "GetUser", 1) }},
+		{name: "called too few times", assert: func(t testing.TB) { client.AssertCalled(t, "GetUser", 2) }, want: `operation "GetUser" was run 1 times, want 2`},
+		{name: "variables", assert: func(t testing.TB) { client.AssertVariables(t, "GetUser", map[string]interface{}{"id": 42.0}) }},
+		{name: "other variables", assert: func(t testing.TB) { client.AssertVariables(t, "GetUser", map[string]interface{}{"id": "42"}) }, want: `variables of "GetUser" are {"id":42}, want {"id":"42"}`},
+		{name: "query", assert: func(t testing.TB) { client.AssertQueryContains(t, "GetUser", "user(id: $id)") }},
+		{name: "other query", assert: func(t testing.TB) { client.AssertQueryContains(t, "GetUser", "email") }, want: `query of "GetUser" does not contain "email"`},
+		{name: "operation not run", assert: func(t testing.TB) { client.AssertVariables(t, "Other", nil) }, want: `operation "Other" was not run`},
+		{name: "queued responses left", assert: client.AssertAllConsumed, want: `1 responses queued for operation "GetUser" were not used`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rt := &recordingT{TB: t}
+			tt.assert(rt)
+			switch {
+			case tt.want == "" && len(rt.failures) > 0:
+				t.Fatalf("got failures %q, want none", rt.failures)
+			case tt.want != "" && (len(rt.failures) != 1 || !strings.Contains(rt.failures[0], tt.want)):
+				t.Fatalf("got failures %q, want one containing %q", rt.failures, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,113 @@
+package graphqltest
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+
+	graphql "github.com/pzentenoe/graphql-client"
+)
+
+// recorder records the requests received by a Client or a Server.
+type recorder struct {
+	mu       sync.Mutex
+	requests []*graphql.GraphRequest
+}
+
+func (r *recorder) record(req *graphql.GraphRequest) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.requests = append(r.requests, req)
+}
+
+// Requests returns the requests received, in order.
+func (r *recorder) Requests() []*graphql.GraphRequest {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]*graphql.GraphRequest(nil), r.requests...)
+}
+
+// RequestsFor returns the requests received for the operation named
+// operationName, in order.
+func (r *recorder) RequestsFor(operationName string) []*graphql.GraphRequest {
+	var requests []*graphql.GraphRequest
+	for _, req := range r.Requests() {
+		if OperationName(req) == operationName {
+			requests = append(requests, req)
+		}
+	}
+	return requests
+}
+
+// AssertCalled checks that the operation named operationName was run n
+// times.
+func (r *recorder) AssertCalled(t testing.TB, operationName string, n int) {
+	t.Helper()
+	if got := len(r.RequestsFor(operationName)); got != n {
+		t.Errorf("graphqltest: operation %q was run %d times, want %d", operationName, got, n)
+	}
+}
+
+// AssertQueryContains checks that the last request of the operation named
+// operationName has a query containing substr.
+func (r *recorder) AssertQueryContains(t testing.TB, operationName, substr string) {
+	t.Helper()
+	req := r.last(t, operationName)
+	if req != nil && !strings.Contains(req.Query(), substr) {
+		t.Errorf("graphqltest: query of %q does not contain %q:\n%s", operationName, substr, req.Query())
+	}
+}
+
+// AssertVariables checks that the last request of the operation named
+// operationName has the variables vars, compared as JSON.
+func (r *recorder) AssertVariables(t testing.TB, operationName string, vars map[string]interface{}) {
+	t.Helper()
+	req := r.last(t, operationName)
+	if req == nil {
+		return
+	}
+	got, err := normalize(req.Vars())
+	if err != nil {
+		t.Errorf("graphqltest: encoding variables of %q: %v", operationName, err)
+		return
+	}
+	want, err := normalize(vars)
+	if err != nil {
+		t.Errorf("graphqltest: encoding expected variables: %v", err)
+		return
+	}
+	if !reflect.DeepEqual(got, want) {
+		gotJSON, _ := json.Marshal(got)
+		wantJSON, _ := json.Marshal(want)
+		t.Errorf("graphqltest: variables of %q are %s, want %s", operationName, gotJSON, wantJSON)
+	}
+}
+
+// last returns the last request of the operation named operationName, and
+// fails t if there is none.
+func (r *recorder) last(t testing.TB, operationName string) *graphql.GraphRequest {
+	t.Helper()
+	requests := r.RequestsFor(operationName)
+	if len(requests) == 0 {
+		t.Errorf("graphqltest: operation %q was not run", operationName)
+		return nil
+	}
+	return requests[len(requests)-1]
+}
+
+// normalize returns the JSON form of vars, for values of different Go
+// types encoding alike to compare equal.
+func normalize(vars map[string]interface{}) (interface{}, error) {
+	if len(vars) == 0 {
+		return nil, nil
+	}
+	encoded, err := json.Marshal(vars)
+	if err != nil {
+		return nil, err
+	}
+	var normalized interface{}
+	err = json.Unmarshal(encoded, &normalized)
+	return normalized, err
+}
@@ -0,0 +1,247 @@
+package graphqltest
+
+import (
+	"encoding/json"
+	"io"
+	"mime"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"sync"
+	"testing"
+
+	graphql "github.com/pzentenoe/graphql-client"
+)
+
+// Server is a GraphQL server answering requests with the fixtures
+// registered for their operation names or queries, for tests going through
+// a real graphql.Client and HTTP stack.
+//
+//	server := graphqltest.NewServer(t, graphqltest.Strict())
+//	server.On("GetUser").Data(map[string]interface{}{"user": nil})
+//	server.On("CreateUser").Status(http.StatusServiceUnavailable, "")
+//	client := graphql.NewClient(server.URL)
+type Server struct {
+	*httptest.Server
+	recorder
+
+	t      testing.TB
+	strict bool
+
+	mu       sync.Mutex
+	fixtures []*Fixture
+}
+
+// ServerOption configures a Server.
+type ServerOption func(*Server)
+
+// Strict fails the test when the server receives a request no fixture
+// matches. Such requests are answered with a GraphQL error either way.
+func Strict() ServerOption {
+	return func(s *Server) {
+		s.strict = true
+	}
+}
+
+// NewServer starts a Server, closed when the test ends.
+func NewServer(t testing.TB, opts ...ServerOption) *Server {
+	s := &Server{t: t}
+	for _, opt := range opts {
+		opt(s)
+	}
+	s.Server = httptest.NewServer(http.HandlerFunc(s.serveHTTP))
+	t.Cleanup(s.Close)
+	return s
+}
+
+// On registers a fixture answering the requests of the operation named
+// operationName, "" for anonymous operations. When several fixtures match
+// a request, the last one registered answers it.
+func (s *Server) On(operationName string) *Fixture {
+	return s.add(&Fixture{operationName: operationName, byName: true})
+}
+
+// OnQuery registers a fixture answering the requests whose query matches
+// the regular expression pattern.
+func (s *Server) OnQuery(pattern string) *Fixture {
+	return s.add(&Fixture{query: regexp.MustCompile(pattern)})
+}
+
+func (s *Server) add(f *Fixture) *Fixture {
+	f.status = http.StatusOK
+	f.header = make(http.Header)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.fixtures = append(s.fixtures, f)
+	return f
+}
+
+// Fixture is the response of a Server to the requests it matches. It
+// answers with null data until set otherwise.
+type Fixture struct {
+	operationName string
+	byName        bool
+	query         *regexp.Regexp
+
+	mu     sync.Mutex
+	status int
+	header http.Header
+	data   interface{}
+	errors []graphql.GraphErr
+	body   []byte
+	raw    bool
+}
+
+// Data sets the data of the response, encoded to JSON.
+func (f *Fixture) Data(data interface{}) *Fixture {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.data = data
+	return f
+}
+
+// Errors sets the GraphQL errors of the response.
+func (f *Fixture) Errors(errs ...graphql.GraphErr) *Fixture {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.errors = errs
+	return f
+}
+
+// JSON makes the fixture answer with body as is, e.g. to return extensions
+// or malformed responses.
+func (f *Fixture) JSON(body string) *Fixture {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.body, f.raw = []byte(body), true
+	return f
+}
+
+// Status makes the fixture answer with the HTTP status code and body, e.g.
+// a 503 or a 429 with a Retry-After header set with Header.
+func (f *Fixture) Status(code int, body string) *Fixture {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.status, f.body, f.raw = code, []byte(body), true
+	return f
+}
+
+// Header sets a header of the response.
+func (f *Fixture) Header(key, value string) *Fixture {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.header.Set(key, value)
+	return f
+}
+
+func (f *Fixture) matches(req *graphql.GraphRequest) bool {
+	if f.byName {
+		return OperationName(req) == f.operationName
+	}
+	return f.query.MatchString(req.Query())
+}
+
+func (f *Fixture) write(w http.ResponseWriter) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for key, values := range f.header {
+		w.Header()[key] = values
+	}
+	body := f.body
+	if !f.raw {
+		body = encodeResponse(f.data, f.errors)
+	}
+	if w.Header().Get("Content-Type") == "" {
+		w.Header().Set("Content-Type", "application/json")
+	}
+	w.WriteHeader(f.status)
+	_, _ = w.Write(body)
+}
+
+func encodeResponse(data interface{}, errs []graphql.GraphErr) []byte {
+	body, err := json.Marshal(struct {
+		Data   interface{}        `json:"data"`
+		Errors []graphql.GraphErr `json:"errors,omitempty"`
+	}{data, errs})
+	if err != nil {
+		body, _ = json.Marshal(struct {
+			Errors []graphql.GraphErr `json:"errors"`
+		}{[]graphql.GraphErr{{Message: "graphqltest: encoding fixture: " + err.Error()}}})
+	}
+	return body
+}
+
+func (s *Server) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	req, err := readRequest(r)
+	if err != nil {
+		s.t.Errorf("graphqltest: reading request: %v", err)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	s.record(req)
+	s.mu.Lock()
+	var fixture *Fixture
+	for i := len(s.fixtures) - 1; i >= 0; i-- {
+		if s.fixtures[i].matches(req) {
+			fixture = s.fixtures[i]
+			break
+		}
+	}
+	s.mu.Unlock()
+	if fixture != nil {
+		fixture.write(w)
+		return
+	}
+	name := OperationName(req)
+	if s.strict {
+		s.t.Errorf("graphqltest: unexpected operation %q:\n%s", name, req.Query())
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_, _ = w.Write(encodeResponse(nil, []graphql.GraphErr{{Message: "graphqltest: no fixture for operation " + name}}))
+}
+
+// payload is the body of a GraphQL request.
+type payload struct {
+	Query         string                 `json:"query"`
+	OperationName string                 `json:"operationName"`
+	Variables     map[string]interface{} `json:"variables"`
+}
+
+// readRequest reads the GraphQL request sent in r, as JSON or as a
+// multipart form.
+func readRequest(r *http.Request) (*graphql.GraphRequest, error) {
+	var p payload
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if mediaType == "multipart/form-data" {
+		if err := r.ParseMultipartForm(32 << 20); err != nil {
+			return nil, err
+		}
+		if operations := r.FormValue("operations"); operations != "" {
+			if err := json.Unmarshal([]byte(operations), &p); err != nil {
+				return nil, err
+			}
+		} else {
+			p.Query, p.OperationName = r.FormValue("query"), r.FormValue("operationName")
+			if variables := r.FormValue("variables"); variables != "" {
+				if err := json.Unmarshal([]byte(variables), &p.Variables); err != nil {
+					return nil, err
+				}
+			}
+		}
+	} else {
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal(body, &p); err != nil {
+			return nil, err
+		}
+	}
+	req := graphql.NewGraphqlRequest(p.Query)
+	req.SetOperationName(p.OperationName)
+	for name, value := range p.Variables {
+		req.Var(name, value)
+	}
+	req.Header = r.Header.Clone()
+	return req, nil
+}
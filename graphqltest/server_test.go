@@ -0,0 +1,157 @@
+package graphqltest
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+
+	graphql "github.com/pzentenoe/graphql-client"
+)
+
+func TestServer(t *testing.T) {
+	ada := map[string]interface{}{"user": map[string]interface{}{"name": "Ada"}}
+	tests := []struct {
+		name   string
+		strict bool
+		setup  func(s *Server)
+		query  string
+		opts   []graphql.ClientOption
+		file   bool
+		want   string
+		// wantErr is a part of the expected error, empty for none.
+		wantErr string
+		// wantGraphErr is a part of the expected GraphQL error, if any.
+		wantGraphErr string
+		wantHeader   string
+		// wantFailures is the number of test failures reported by the
+		// server.
+		wantFailures int
+	}{
+		{
+			name:  "operation name",
+			setup: func(s *Server) { s.On("GetUser").Data(ada) },
+			query: `query GetUser { user { name } }`,
+			want:  "Ada",
+		},
+		{
+			name:  "query pattern",
+			setup: func(s *Server) { s.OnQuery(`user\s*{`).Data(ada) },
+			query: `{ user { name } }`,
+			want:  "Ada",
+		},
+		{
+			name: "last fixture",
+			setup: func(s *Server) {
+				s.On("GetUser").Data(map[string]interface{}{"user": map[string]interface{}{"name": "Bob"}})
+				s.OnQuery(`name`).Data(ada)
+			},
+			query: `query GetUser { user { name } }`,
+			want:  "Ada",
+		},
+		{
+			name:         "errors",
+			setup:        func(s *Server) { s.On("GetUser").Errors(graphql.GraphErr{Message: "not found"}) },
+			query:        `query GetUser { user { name } }`,
+			wantGraphErr: "not found",
+		},
+		{
+			name:    "status",
+			setup:   func(s *Server) { s.On("GetUser").Status(http.StatusServiceUnavailable, "down") },
+			query:   `query GetUser { user { name } }`,
+			wantErr: "503",
+		},
+		{
+			name: "raw body and headers",
+			setup: func(s *Server) {
+				s.On("").JSON(`{"data":{"user":{"name":"Ada"}},"extensions":{"cost":1}}`).Header("X-Cost", "1")
+			},
+			query:      `{ user { name } }`,
+			want:       "Ada",
+			wantHeader: "1",
+		},
+		{
+			name:  "multipart request",
+			setup: func(s *Server) { s.On("Upload").Data(ada) },
+			query: `query Upload { user { name } }`,
+			opts:  []graphql.ClientOption{graphql.UseMultipartForm()},
+			file:  true,
+			want:  "Ada",
+		},
+		{
+			name:         "unexpected operation",
+			setup:        func(s *Server) { s.On("Other").Data(ada) },
+			query:        `query GetUser { user { name } }`,
+			wantGraphErr: "no fixture for operation GetUser",
+		},
+		{
+			name:         "unexpected operation in strict mode",
+			strict:       true,
+			setup:        func(s *Server) { s.On("Other").Data(ada) },
+			query:        `query GetUser { user { name } }`,
+			wantGraphErr: "no fixture for operation GetUser",
+			wantFailures: 1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rt := &recordingT{TB: t}
+			var opts []ServerOption
+			if tt.strict {
+				opts = append(opts, Strict())
+			}
+			server := NewServer(rt, opts...)
+			tt.setup(server)
+			req := graphql.NewGraphqlRequest(tt.query)
+			if tt.file {
+				req.File("file", "a.txt", strings.NewReader("content"))
+			}
+			var data userData
+			res, err := graphql.NewClient(server.URL, tt.opts...).Run(context.Background(), req, &data)
+			if len(rt.failures) != tt.wantFailures {
+				t.Fatalf("got failures %q, want %d", rt.failures, tt.wantFailures)
+			}
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("got error %v, want one containing %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tt.wantGraphErr != "" {
+				if len(res.Errors) != 1 || !strings.Contains(res.Errors[0].Error(), tt.wantGraphErr) {
+					t.Fatalf("got errors %v, want one containing %q", res.Errors, tt.wantGraphErr)
+				}
+				return
+			}
+			if data.User.Name != tt.want {
+				t.Fatalf("got %q, want %q", data.User.Name, tt.want)
+			}
+			if got := res.Header.Get("X-Cost"); got != tt.wantHeader {
+				t.Fatalf("got header %q, want %q", got, tt.wantHeader)
+			}
+		})
+	}
+}
+
+func TestServerRecordsRequests(t *testing.T) {
+	server := NewServer(t, Strict())
+	server.On("GetUser").Data(map[string]interface{}{"user": nil})
+	client := graphql.NewClient(server.URL)
+	for _, id := range []string{"1", "2"} {
+		req := graphql.NewGraphqlRequest(`query GetUser($id: ID!) { user(id: $id) { name } }`)
+		req.Var("id", id)
+		req.Header.Set("X-Request", id)
+		if _, err := client.Run(context.Background(), req, nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+	server.AssertCalled(t, "GetUser", 2)
+	server.AssertVariables(t, "GetUser", map[string]interface{}{"id": "2"})
+	server.AssertQueryContains(t, "GetUser", "user(id: $id)")
+	if requests := server.Requests(); len(requests) != 2 || requests[0].Header.Get("X-Request") != "1" {
+		t.Fatalf("recorded %d requests, the first with header %q", len(requests), requests[0].Header.Get("X-Request"))
+	}
+}
@@ -0,0 +1,19 @@
+module github.com/pzentenoe/graphql-client/otelgraphql
+
+go 1.21
+
+require (
+	github.com/pzentenoe/graphql-client v0.0.0-00010101000000-000000000000
+	go.opentelemetry.io/otel v1.28.0
+	go.opentelemetry.io/otel/trace v1.28.0
+)
+
+require (
+	github.com/go-logr/logr v1.4.2 // indirect
+	github.com/go-logr/stdr v1.2.2 // indirect
+	github.com/pkg/errors v0.9.1 // indirect
+	go.opentelemetry.io/otel/metric v1.28.0 // indirect
+	golang.org/x/oauth2 v0.21.0 // indirect
+)
+
+replace github.com/pzentenoe/graphql-client => ../
@@ -0,0 +1,98 @@
+// Package otelgraphql instruments a graphql.Client with OpenTelemetry.
+//
+//	client := graphql.NewClient(url, otelgraphql.WithTracing())
+//
+// Every Run call gets a client span, and the trace context is injected
+// into the headers of the outgoing requests. The package is a module of
+// its own, so only programs importing it depend on OpenTelemetry.
+package otelgraphql
+
+import (
+	"context"
+	"net/http"
+	"strings"
+
+	graphql "github.com/pzentenoe/graphql-client"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const instrumentationName = "github.com/pzentenoe/graphql-client/otelgraphql"
+
+// Option configures the tracing set up by WithTracing.
+type Option func(*tracer)
+
+// WithTracerProvider sets the TracerProvider spans are created with. The
+// global provider is used by default.
+func WithTracerProvider(provider trace.TracerProvider) Option {
+	return func(t *tracer) {
+		t.provider = provider
+	}
+}
+
+// WithPropagators sets the propagators used to inject the trace context
+// into outgoing requests. The global propagators are used by default.
+func WithPropagators(propagators propagation.TextMapPropagator) Option {
+	return func(t *tracer) {
+		t.propagators = propagators
+	}
+}
+
+// WithTracing returns a ClientOption that emits a span for every operation
+// run by the Client.
+func WithTracing(opts ...Option) graphql.ClientOption {
+	t := &tracer{
+		provider:    otel.GetTracerProvider(),
+		propagators: otel.GetTextMapPropagator(),
+	}
+	for _, opt := range opts {
+		opt(t)
+	}
+	t.tracer = t.provider.Tracer(instrumentationName)
+	return graphql.WithTracer(t)
+}
+
+type tracer struct {
+	provider    trace.TracerProvider
+	propagators propagation.TextMapPropagator
+	tracer      trace.Tracer
+}
+
+func (t *tracer) StartOperation(ctx context.Context, info graphql.OperationInfo) (context.Context, func(graphql.OperationResult)) {
+	name := "graphql." + info.Type
+	if info.Name != "" {
+		name = strings.Join([]string{info.Type, info.Name}, " ")
+	}
+	ctx, span := t.tracer.Start(ctx, name,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			attribute.String("graphql.operation.name", info.Name),
+			attribute.String("graphql.operation.type", info.Type),
+			attribute.String("url.full", info.Endpoint),
+		),
+	)
+	if info.Label != "" {
+		span.SetAttributes(attribute.String("graphql.operation.label", info.Label))
+	}
+	return ctx, func(result graphql.OperationResult) {
+		defer span.End()
+		if result.StatusCode != 0 {
+			span.SetAttributes(attribute.Int("http.response.status_code", result.StatusCode))
+		}
+		span.SetAttributes(attribute.Int("graphql.errors.count", result.GraphQLErrors))
+		switch {
+		case result.Err != nil:
+			span.RecordError(result.Err)
+			span.SetStatus(codes.Error, result.Err.Error())
+		case result.GraphQLErrors > 0:
+			span.SetStatus(codes.Error, "response contains GraphQL errors")
+		}
+	}
+}
+
+func (t *tracer) InjectHeaders(ctx context.Context, header http.Header) {
+	t.propagators.Inject(ctx, propagation.HeaderCarrier(header))
+}